@@ -5,10 +5,12 @@ import (
 	"flag"
 	"fmt"
 	"log/slog"
+	"net/url"
 
 	"github.com/sfomuseum/go-atproto"
 	"github.com/sfomuseum/go-atproto/pds"
 	"github.com/sfomuseum/go-atproto/plc"
+	"github.com/sfomuseum/go-atproto/relay"
 )
 
 func Run(ctx context.Context) error {
@@ -62,6 +64,36 @@ func RunWithOptions(ctx context.Context, opts *RunOptions) error {
 
 	defer operations_db.Close()
 
+	events_db, err := pds.NewEventsDatabase(ctx, opts.EventsDatabaseURI)
+
+	if err != nil {
+		return err
+	}
+
+	defer events_db.Close()
+
+	commits_db, err := pds.NewCommitsDatabase(ctx, opts.CommitsDatabaseURI)
+
+	if err != nil {
+		return err
+	}
+
+	defer commits_db.Close()
+
+	blocks_db, err := pds.NewBlocksDatabase(ctx, opts.BlocksDatabaseURI)
+
+	if err != nil {
+		return err
+	}
+
+	defer blocks_db.Close()
+
+	sequencer, err := pds.NewSequencer(ctx, events_db)
+
+	if err != nil {
+		return err
+	}
+
 	acct, err := pds.GetAccountWithHandle(ctx, accounts_db, opts.Handle)
 
 	if acct != nil {
@@ -108,6 +140,70 @@ func RunWithOptions(ctx context.Context, opts *RunOptions) error {
 		return err
 	}
 
+	// Announce the new account (and its handle) to subscribers of com.atproto.sync.subscribeRepos
+
+	identity_ev, err := pds.NewIdentityEvent(rsp.Account.DID, rsp.Account.Handle)
+
+	if err != nil {
+		logger.Error("Failed to create identity event", "error", err)
+		return err
+	}
+
+	account_ev, err := pds.NewAccountEvent(rsp.Account.DID, true, "")
+
+	if err != nil {
+		logger.Error("Failed to create account event", "error", err)
+		return err
+	}
+
+	for _, e := range []*pds.Event{identity_ev, account_ev} {
+
+		err = sequencer.Emit(ctx, e)
+
+		if err != nil {
+			logger.Error("Failed to add event to database", "type", e.Type, "error", err)
+			return err
+		}
+	}
+
+	// Create the initial (empty) commit for the account's repository. This also emits a "#commit" event.
+
+	repo_opts := &pds.CreateRepoOptions{
+		KeysDatabase:    keys_db,
+		CommitsDatabase: commits_db,
+		BlocksDatabase:  blocks_db,
+		Sequencer:       sequencer,
+	}
+
+	commit, err := pds.CreateRepo(ctx, repo_opts, rsp.Account.DID)
+
+	if err != nil {
+		logger.Error("Failed to create repository", "error", err)
+		return err
+	}
+
+	logger = logger.With("commit", commit.CID)
+
 	logger.Info("New account created")
+
+	// Failing to notify relays does not invalidate the new account so errors are logged rather than returned
+
+	if len(opts.RelayURIs) > 0 {
+
+		service_u, err := url.Parse(opts.Service)
+
+		if err != nil {
+			logger.Error("Failed to parse service URI, unable to request crawl from relays", "error", err)
+			return nil
+		}
+
+		relay_cl := relay.DefaultClient()
+		err = relay_cl.RequestCrawlAll(ctx, opts.RelayURIs, service_u.Host)
+
+		if err != nil {
+			logger.Error("Failed to request crawl from one or more relays", "error", err)
+		}
+	}
+
 	return nil
 }
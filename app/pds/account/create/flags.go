@@ -4,6 +4,7 @@ import (
 	"flag"
 
 	"github.com/sfomuseum/go-flags/flagset"
+	"github.com/sfomuseum/go-flags/multi"
 )
 
 var database_uri string
@@ -11,9 +12,13 @@ var database_uri string
 var accounts_database_uri string
 var keys_database_uri string
 var operations_database_uri string
+var events_database_uri string
+var commits_database_uri string
+var blocks_database_uri string
 
 var handle string
 var service string
+var relay_uris multi.MultiString
 var verbose bool
 
 func DefaultFlagSet() *flag.FlagSet {
@@ -25,9 +30,13 @@ func DefaultFlagSet() *flag.FlagSet {
 	fs.StringVar(&accounts_database_uri, "account-database-uri", "", "A registered sfomuseum/go-atproto/pds.AccountsDatabase URI.")
 	fs.StringVar(&keys_database_uri, "keys-database-uri", "", "A registered sfomuseum/go-atproto/pds.KeysDatabase URI.")
 	fs.StringVar(&operations_database_uri, "operations-database-uri", "", "A registered sfomuseum/go-atproto/pds.OperationsDatabase URI.")
+	fs.StringVar(&events_database_uri, "events-database-uri", "", "A registered sfomuseum/go-atproto/pds.EventsDatabase URI.")
+	fs.StringVar(&commits_database_uri, "commits-database-uri", "", "A registered sfomuseum/go-atproto/pds.CommitsDatabase URI.")
+	fs.StringVar(&blocks_database_uri, "blocks-database-uri", "", "A registered sfomuseum/go-atproto/pds.BlocksDatabase URI.")
 
 	fs.StringVar(&handle, "handle", "", "The handle name for the new account.")
 	fs.StringVar(&service, "service", "", "The service name for the new account.")
+	fs.Var(&relay_uris, "relay-uri", "Zero or more relay URIs (or hostnames) to ask to crawl the PDS at -service (using com.atproto.sync.requestCrawl) once the account has been created.")
 
 	fs.BoolVar(&verbose, "verbose", false, "Enable verbose (debug) logging.")
 	return fs
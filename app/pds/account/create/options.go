@@ -8,12 +8,16 @@ import (
 )
 
 type RunOptions struct {
-	AccountsDatabaseURI   string `json:"accounts_database_uri"`
-	KeysDatabaseURI       string `json:"keys_database_uri"`
-	OperationsDatabaseURI string `json:"operations_database_uri"`
-	Handle                string `json:"handle"`
-	Service               string `json:"service"`
-	Verbose               bool   `json:"verbose"`
+	AccountsDatabaseURI   string   `json:"accounts_database_uri"`
+	KeysDatabaseURI       string   `json:"keys_database_uri"`
+	OperationsDatabaseURI string   `json:"operations_database_uri"`
+	EventsDatabaseURI     string   `json:"events_database_uri"`
+	CommitsDatabaseURI    string   `json:"commits_database_uri"`
+	BlocksDatabaseURI     string   `json:"blocks_database_uri"`
+	Handle                string   `json:"handle"`
+	Service               string   `json:"service"`
+	RelayURIs             []string `json:"relay_uris"`
+	Verbose               bool     `json:"verbose"`
 }
 
 func OptionsFromFlagSet(ctx context.Context, fs *flag.FlagSet) (*RunOptions, error) {
@@ -33,14 +37,30 @@ func OptionsFromFlagSet(ctx context.Context, fs *flag.FlagSet) (*RunOptions, err
 		if operations_database_uri == "" {
 			operations_database_uri = database_uri
 		}
+
+		if events_database_uri == "" {
+			events_database_uri = database_uri
+		}
+
+		if commits_database_uri == "" {
+			commits_database_uri = database_uri
+		}
+
+		if blocks_database_uri == "" {
+			blocks_database_uri = database_uri
+		}
 	}
 
 	opts := &RunOptions{
 		AccountsDatabaseURI:   accounts_database_uri,
 		KeysDatabaseURI:       keys_database_uri,
 		OperationsDatabaseURI: operations_database_uri,
+		EventsDatabaseURI:     events_database_uri,
+		CommitsDatabaseURI:    commits_database_uri,
+		BlocksDatabaseURI:     blocks_database_uri,
 		Handle:                handle,
 		Service:               service,
+		RelayURIs:             relay_uris,
 		Verbose:               verbose,
 	}
 
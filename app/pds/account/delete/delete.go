@@ -63,6 +63,24 @@ func RunWithOptions(ctx context.Context, opts *RunOptions) error {
 
 	defer operations_db.Close()
 
+	events_db, err := pds.NewEventsDatabase(ctx, opts.EventsDatabaseURI)
+
+	if err != nil {
+		logger.Error("Failed to initialize events database", "error", err)
+		return err
+	}
+
+	defer events_db.Close()
+
+	blocks_db, err := pds.NewBlocksDatabase(ctx, opts.BlocksDatabaseURI)
+
+	if err != nil {
+		logger.Error("Failed to initialize blocks database", "error", err)
+		return err
+	}
+
+	defer blocks_db.Close()
+
 	acct, err := accounts_db.GetAccount(ctx, opts.DID)
 
 	if err != nil {
@@ -144,6 +162,31 @@ func RunWithOptions(ctx context.Context, opts *RunOptions) error {
 		return err
 	}
 
+	logger.Debug("Delete account blocks")
+
+	err = pds.DeleteBlocksForDID(ctx, blocks_db, acct.DID)
+
+	if err != nil {
+		logger.Error("Failed to remove blocks", "error", err)
+		return err
+	}
+
+	logger.Debug("Add account event")
+
+	account_ev, err := pds.NewAccountEvent(acct.DID, false, pds.ACCOUNT_STATUS_DELETED)
+
+	if err != nil {
+		logger.Error("Failed to create account event", "error", err)
+		return err
+	}
+
+	err = pds.AddEvent(ctx, events_db, account_ev)
+
+	if err != nil {
+		logger.Error("Failed to add account event", "error", err)
+		return err
+	}
+
 	logger.Info("Account successfully deleted.")
 	return nil
 }
@@ -11,6 +11,8 @@ var database_uri string
 var accounts_database_uri string
 var keys_database_uri string
 var operations_database_uri string
+var events_database_uri string
+var blocks_database_uri string
 
 var did string
 var verbose bool
@@ -24,6 +26,8 @@ func DefaultFlagSet() *flag.FlagSet {
 	fs.StringVar(&accounts_database_uri, "account-database-uri", "", "A registered sfomuseum/go-atproto/pds.AccountsDatabase URI.")
 	fs.StringVar(&keys_database_uri, "keys-database-uri", "", "A registered sfomuseum/go-atproto/pds.KeysDatabase URI.")
 	fs.StringVar(&operations_database_uri, "operations-database-uri", "", "A registered sfomuseum/go-atproto/pds.OperationsDatabase URI.")
+	fs.StringVar(&events_database_uri, "events-database-uri", "", "A registered sfomuseum/go-atproto/pds.EventsDatabase URI.")
+	fs.StringVar(&blocks_database_uri, "blocks-database-uri", "", "A registered sfomuseum/go-atproto/pds.BlocksDatabase URI.")
 
 	fs.StringVar(&did, "did", "", "The DID for the account to delete.")
 
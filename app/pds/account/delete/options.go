@@ -11,6 +11,8 @@ type RunOptions struct {
 	AccountsDatabaseURI   string `json:"accounts_database_uri"`
 	KeysDatabaseURI       string `json:"keys_database_uri"`
 	OperationsDatabaseURI string `json:"operations_database_uri"`
+	EventsDatabaseURI     string `json:"events_database_uri"`
+	BlocksDatabaseURI     string `json:"blocks_database_uri"`
 	DID                   string `json:"did"`
 	Verbose               bool   `json:"verbose"`
 }
@@ -32,12 +34,22 @@ func OptionsFromFlagSet(ctx context.Context, fs *flag.FlagSet) (*RunOptions, err
 		if operations_database_uri == "" {
 			operations_database_uri = database_uri
 		}
+
+		if events_database_uri == "" {
+			events_database_uri = database_uri
+		}
+
+		if blocks_database_uri == "" {
+			blocks_database_uri = database_uri
+		}
 	}
 
 	opts := &RunOptions{
 		AccountsDatabaseURI:   accounts_database_uri,
 		KeysDatabaseURI:       keys_database_uri,
 		OperationsDatabaseURI: operations_database_uri,
+		EventsDatabaseURI:     events_database_uri,
+		BlocksDatabaseURI:     blocks_database_uri,
 		DID:                   did,
 		Verbose:               verbose,
 	}
@@ -40,7 +40,9 @@ func RunWithOptions(ctx context.Context, opts *RunOptions) error {
 
 	defer accounts_db.Close()
 
-	for acct, err := range accounts_db.ListAccounts(ctx) {
+	list_opts := &pds.ListAccountsOptions{}
+
+	for acct, err := range pds.ListAccounts(ctx, accounts_db, list_opts) {
 
 		if err != nil {
 			return err
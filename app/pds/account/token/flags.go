@@ -0,0 +1,35 @@
+package token
+
+import (
+	"flag"
+	"time"
+
+	"github.com/sfomuseum/go-flags/flagset"
+)
+
+var database_uri string
+
+var keys_database_uri string
+
+var did string
+var audience string
+var lexicon_method string
+var ttl time.Duration
+var verbose bool
+
+func DefaultFlagSet() *flag.FlagSet {
+
+	fs := flagset.NewFlagSet("token")
+
+	fs.StringVar(&database_uri, "database-uri", "", "An optional common database URI to apply to all other empty -{SUBJECT}-database-uri flags. This is a convenience flag for things like SQL databases.")
+
+	fs.StringVar(&keys_database_uri, "keys-database-uri", "", "A registered sfomuseum/go-atproto/pds.KeysDatabase URI.")
+
+	fs.StringVar(&did, "did", "", "The DID of the account to issue a token for.")
+	fs.StringVar(&audience, "audience", "", "The DID of the service the token will be sent to. This should match the ?audience= parameter of the service's jwt:// authenticator URI.")
+	fs.StringVar(&lexicon_method, "lxm", "", "The NSID of the XRPC method the token is valid for (for example com.atproto.repo.createRecord).")
+	fs.DurationVar(&ttl, "ttl", 60*time.Second, "The amount of time the token is valid for.")
+
+	fs.BoolVar(&verbose, "verbose", false, "Enable verbose (debug) logging.")
+	return fs
+}
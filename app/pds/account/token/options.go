@@ -0,0 +1,41 @@
+package token
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	"github.com/sfomuseum/go-flags/flagset"
+)
+
+type RunOptions struct {
+	KeysDatabaseURI string        `json:"keys_database_uri"`
+	DID             string        `json:"did"`
+	Audience        string        `json:"audience"`
+	LexiconMethod   string        `json:"lxm"`
+	TTL             time.Duration `json:"ttl"`
+	Verbose         bool          `json:"verbose"`
+}
+
+func OptionsFromFlagSet(ctx context.Context, fs *flag.FlagSet) (*RunOptions, error) {
+
+	flagset.Parse(fs)
+
+	if database_uri != "" {
+
+		if keys_database_uri == "" {
+			keys_database_uri = database_uri
+		}
+	}
+
+	opts := &RunOptions{
+		KeysDatabaseURI: keys_database_uri,
+		DID:             did,
+		Audience:        audience,
+		LexiconMethod:   lexicon_method,
+		TTL:             ttl,
+		Verbose:         verbose,
+	}
+
+	return opts, nil
+}
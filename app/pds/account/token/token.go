@@ -0,0 +1,89 @@
+package token
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/sfomuseum/go-atproto/auth"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+func Run(ctx context.Context) error {
+	fs := DefaultFlagSet()
+	return RunWithFlagSet(ctx, fs)
+}
+
+func RunWithFlagSet(ctx context.Context, fs *flag.FlagSet) error {
+
+	opts, err := OptionsFromFlagSet(ctx, fs)
+
+	if err != nil {
+		return err
+	}
+
+	return RunWithOptions(ctx, opts)
+}
+
+// RunWithOptions issues an atproto inter-service authentication token, signed by the "atproto" key of an account
+// hosted by this PDS, and writes it to STDOUT.
+func RunWithOptions(ctx context.Context, opts *RunOptions) error {
+
+	if opts.Verbose {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+		slog.Debug("Verbose logging enabled")
+	}
+
+	_, err := syntax.ParseDID(opts.DID)
+
+	if err != nil {
+		return fmt.Errorf("Invalid -did, %w", err)
+	}
+
+	if opts.Audience == "" {
+		return fmt.Errorf("Missing -audience")
+	}
+
+	_, err = syntax.ParseNSID(opts.LexiconMethod)
+
+	if err != nil {
+		return fmt.Errorf("Invalid -lxm, %w", err)
+	}
+
+	keys_db, err := pds.NewKeysDatabase(ctx, opts.KeysDatabaseURI)
+
+	if err != nil {
+		return err
+	}
+
+	defer keys_db.Close()
+
+	k, err := keys_db.GetKey(ctx, opts.DID, "atproto")
+
+	if err != nil {
+		return fmt.Errorf("Failed to retrieve atproto key, %w", err)
+	}
+
+	pr_key, err := k.PrivateKeyK256()
+
+	if err != nil {
+		return fmt.Errorf("Failed to derive private key, %w", err)
+	}
+
+	claims := &auth.ServiceTokenClaims{
+		Issuer:        opts.DID,
+		Audience:      opts.Audience,
+		LexiconMethod: opts.LexiconMethod,
+	}
+
+	tk, err := auth.NewServiceToken(pr_key, claims, opts.TTL)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create token, %w", err)
+	}
+
+	fmt.Println(tk)
+	return nil
+}
@@ -0,0 +1,34 @@
+package gc
+
+import (
+	"flag"
+	"time"
+
+	"github.com/sfomuseum/go-atproto/pds"
+	"github.com/sfomuseum/go-flags/flagset"
+)
+
+var database_uri string
+var blobs_database_uri string
+
+var blob_store_uri string
+var did string
+var grace_period time.Duration
+var dry_run bool
+var verbose bool
+
+func DefaultFlagSet() *flag.FlagSet {
+
+	fs := flagset.NewFlagSet("gc")
+
+	fs.StringVar(&database_uri, "database-uri", "", "An optional common database URI to apply to all other empty -{SUBJECT}-database-uri flags. This is a convenience flag for things like SQL databases.")
+	fs.StringVar(&blobs_database_uri, "blobs-database-uri", "", "A registered sfomuseum/go-atproto/pds.BlobsDatabase URI.")
+
+	fs.StringVar(&blob_store_uri, "blob-store-uri", "", "A valid gocloud.dev/blob.Bucket URI where blob data is stored.")
+	fs.StringVar(&did, "did", "", "An optional DID to limit garbage collection to. If empty blobs for all DIDs are garbage collected.")
+	fs.DurationVar(&grace_period, "grace-period", pds.DEFAULT_BLOB_GRACE_PERIOD, "The amount of time that blobs which are not referenced by any records are retained before being garbage collected.")
+	fs.BoolVar(&dry_run, "dry-run", false, "Report the blobs that would be garbage collected without deleting them.")
+
+	fs.BoolVar(&verbose, "verbose", false, "Enable verbose (debug) logging.")
+	return fs
+}
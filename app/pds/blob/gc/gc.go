@@ -0,0 +1,74 @@
+package gc
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+func Run(ctx context.Context) error {
+	fs := DefaultFlagSet()
+	return RunWithFlagSet(ctx, fs)
+}
+
+func RunWithFlagSet(ctx context.Context, fs *flag.FlagSet) error {
+
+	opts, err := OptionsFromFlagSet(ctx, fs)
+
+	if err != nil {
+		return err
+	}
+
+	return RunWithOptions(ctx, opts)
+}
+
+func RunWithOptions(ctx context.Context, opts *RunOptions) error {
+
+	if opts.Verbose {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+		slog.Debug("Verbose logging enabled")
+	}
+
+	blobs_db, err := pds.NewBlobsDatabase(ctx, opts.BlobsDatabaseURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create blobs database, %w", err)
+	}
+
+	defer blobs_db.Close()
+
+	blob_store, err := pds.NewBlobStore(ctx, opts.BlobStoreURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create blob store, %w", err)
+	}
+
+	defer blob_store.Close()
+
+	gc_opts := &pds.GCBlobsOptions{
+		BlobsDatabase: blobs_db,
+		BlobStore:     blob_store,
+		DID:           opts.DID,
+		GracePeriod:   opts.GracePeriod,
+		DryRun:        opts.DryRun,
+	}
+
+	deleted, err := pds.GCBlobs(ctx, gc_opts)
+
+	if err != nil {
+		return fmt.Errorf("Failed to garbage collect blobs, %w", err)
+	}
+
+	var size int64
+
+	for _, b := range deleted {
+		fmt.Printf("%s\t%s\t%d\n", b.DID, b.CID, b.Size)
+		size += b.Size
+	}
+
+	slog.Debug("Garbage collected blobs", "count", len(deleted), "size", size, "dry run", opts.DryRun)
+	return nil
+}
@@ -0,0 +1,50 @@
+package gc
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/sfomuseum/go-flags/flagset"
+)
+
+type RunOptions struct {
+	BlobsDatabaseURI string        `json:"blobs_database_uri"`
+	BlobStoreURI     string        `json:"blob_store_uri"`
+	DID              string        `json:"did"`
+	GracePeriod      time.Duration `json:"grace_period"`
+	DryRun           bool          `json:"dry_run"`
+	Verbose          bool          `json:"verbose"`
+}
+
+func OptionsFromFlagSet(ctx context.Context, fs *flag.FlagSet) (*RunOptions, error) {
+
+	flagset.Parse(fs)
+
+	if database_uri != "" {
+
+		if blobs_database_uri == "" {
+			blobs_database_uri = database_uri
+		}
+	}
+
+	if blob_store_uri == "" {
+		return nil, fmt.Errorf("Missing -blob-store-uri flag")
+	}
+
+	if grace_period < 0 {
+		return nil, fmt.Errorf("Invalid -grace-period flag, must not be negative")
+	}
+
+	opts := &RunOptions{
+		BlobsDatabaseURI: blobs_database_uri,
+		BlobStoreURI:     blob_store_uri,
+		DID:              did,
+		GracePeriod:      grace_period,
+		DryRun:           dry_run,
+		Verbose:          verbose,
+	}
+
+	return opts, nil
+}
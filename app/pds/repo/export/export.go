@@ -0,0 +1,118 @@
+package export
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/aaronland/gocloud/blob/bucket"
+	"github.com/sfomuseum/go-atproto/car"
+	"github.com/sfomuseum/go-atproto/pds"
+	"gocloud.dev/blob"
+)
+
+func Run(ctx context.Context) error {
+	fs := DefaultFlagSet()
+	return RunWithFlagSet(ctx, fs)
+}
+
+func RunWithFlagSet(ctx context.Context, fs *flag.FlagSet) error {
+
+	opts, err := OptionsFromFlagSet(ctx, fs)
+
+	if err != nil {
+		return err
+	}
+
+	return RunWithOptions(ctx, opts)
+}
+
+func RunWithOptions(ctx context.Context, opts *RunOptions) error {
+
+	if opts.Verbose {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+		slog.Debug("Verbose logging enabled")
+	}
+
+	logger := slog.Default()
+	logger = logger.With("did", opts.DID)
+
+	records_db, err := pds.NewRecordsDatabase(ctx, opts.RecordsDatabaseURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create records database, %w", err)
+	}
+
+	defer records_db.Close()
+
+	commits_db, err := pds.NewCommitsDatabase(ctx, opts.CommitsDatabaseURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create commits database, %w", err)
+	}
+
+	defer commits_db.Close()
+
+	var wr io.WriteCloser
+
+	switch {
+	case opts.BucketURI != "":
+
+		b, err := bucket.OpenBucket(ctx, opts.BucketURI)
+
+		if err != nil {
+			return fmt.Errorf("Failed to open bucket, %w", err)
+		}
+
+		defer b.Close()
+
+		wr_opts := &blob.WriterOptions{
+			ContentType: car.CONTENT_TYPE,
+		}
+
+		bucket_wr, err := b.NewWriter(ctx, opts.Filename, wr_opts)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create bucket writer, %w", err)
+		}
+
+		wr = bucket_wr
+
+	case opts.Filename == "-":
+		wr = os.Stdout
+
+	default:
+
+		fh, err := os.Create(opts.Filename)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create %s, %w", opts.Filename, err)
+		}
+
+		wr = fh
+	}
+
+	export_opts := &pds.ExportRepoOptions{
+		RecordsDatabase: records_db,
+		CommitsDatabase: commits_db,
+	}
+
+	commit, err := pds.ExportRepo(ctx, export_opts, opts.DID, wr)
+
+	if err != nil {
+		wr.Close()
+		return fmt.Errorf("Failed to export repo, %w", err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		return fmt.Errorf("Failed to close writer, %w", err)
+	}
+
+	logger.Debug("Exported repo", "commit", commit.CID, "rev", commit.Rev, "filename", opts.Filename)
+	return nil
+}
@@ -0,0 +1,34 @@
+package export
+
+import (
+	"flag"
+
+	"github.com/sfomuseum/go-flags/flagset"
+)
+
+var database_uri string
+
+var records_database_uri string
+var commits_database_uri string
+
+var did string
+var bucket_uri string
+var filename string
+var verbose bool
+
+func DefaultFlagSet() *flag.FlagSet {
+
+	fs := flagset.NewFlagSet("export")
+
+	fs.StringVar(&database_uri, "database-uri", "", "An optional common database URI to apply to all other empty -{SUBJECT}-database-uri flags. This is a convenience flag for things like SQL databases.")
+
+	fs.StringVar(&records_database_uri, "records-database-uri", "", "A registered sfomuseum/go-atproto/pds.RecordsDatabase URI.")
+	fs.StringVar(&commits_database_uri, "commits-database-uri", "", "A registered sfomuseum/go-atproto/pds.CommitsDatabase URI.")
+
+	fs.StringVar(&did, "did", "", "The DID of the repository to export.")
+	fs.StringVar(&bucket_uri, "bucket-uri", "", "An optional gocloud.dev/blob.Bucket URI to write the CAR file to. If empty the CAR file will be written to the local filesystem.")
+	fs.StringVar(&filename, "filename", "", "The name of the CAR file to write. If empty this will be \"{DID}.car\". If \"-\" (and -bucket-uri is empty) the CAR file will be written to STDOUT.")
+
+	fs.BoolVar(&verbose, "verbose", false, "Enable verbose (debug) logging.")
+	return fs
+}
@@ -0,0 +1,53 @@
+package export
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/sfomuseum/go-flags/flagset"
+)
+
+type RunOptions struct {
+	RecordsDatabaseURI string `json:"records_database_uri"`
+	CommitsDatabaseURI string `json:"commits_database_uri"`
+	DID                string `json:"did"`
+	BucketURI          string `json:"bucket_uri"`
+	Filename           string `json:"filename"`
+	Verbose            bool   `json:"verbose"`
+}
+
+func OptionsFromFlagSet(ctx context.Context, fs *flag.FlagSet) (*RunOptions, error) {
+
+	flagset.Parse(fs)
+
+	if database_uri != "" {
+
+		if records_database_uri == "" {
+			records_database_uri = database_uri
+		}
+
+		if commits_database_uri == "" {
+			commits_database_uri = database_uri
+		}
+	}
+
+	if did == "" {
+		return nil, fmt.Errorf("Missing -did flag")
+	}
+
+	if filename == "" {
+		filename = fmt.Sprintf("%s.car", did)
+	}
+
+	opts := &RunOptions{
+		RecordsDatabaseURI: records_database_uri,
+		CommitsDatabaseURI: commits_database_uri,
+		DID:                did,
+		BucketURI:          bucket_uri,
+		Filename:           filename,
+		Verbose:            verbose,
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,44 @@
+package importer
+
+import (
+	"flag"
+
+	"github.com/sfomuseum/go-flags/flagset"
+)
+
+var database_uri string
+
+var accounts_database_uri string
+var records_database_uri string
+var keys_database_uri string
+var commits_database_uri string
+var blocks_database_uri string
+var blobs_database_uri string
+var events_database_uri string
+
+var did string
+var bucket_uri string
+var filename string
+var verbose bool
+
+func DefaultFlagSet() *flag.FlagSet {
+
+	fs := flagset.NewFlagSet("import")
+
+	fs.StringVar(&database_uri, "database-uri", "", "An optional common database URI to apply to all other empty -{SUBJECT}-database-uri flags. This is a convenience flag for things like SQL databases.")
+
+	fs.StringVar(&accounts_database_uri, "accounts-database-uri", "", "A registered sfomuseum/go-atproto/pds.AccountsDatabase URI.")
+	fs.StringVar(&records_database_uri, "records-database-uri", "", "A registered sfomuseum/go-atproto/pds.RecordsDatabase URI.")
+	fs.StringVar(&keys_database_uri, "keys-database-uri", "", "A registered sfomuseum/go-atproto/pds.KeysDatabase URI.")
+	fs.StringVar(&commits_database_uri, "commits-database-uri", "", "A registered sfomuseum/go-atproto/pds.CommitsDatabase URI.")
+	fs.StringVar(&blocks_database_uri, "blocks-database-uri", "", "A registered sfomuseum/go-atproto/pds.BlocksDatabase URI.")
+	fs.StringVar(&blobs_database_uri, "blobs-database-uri", "", "An optional registered sfomuseum/go-atproto/pds.BlobsDatabase URI used to record the blobs referenced by imported records.")
+	fs.StringVar(&events_database_uri, "events-database-uri", "", "An optional registered sfomuseum/go-atproto/pds.EventsDatabase URI used to emit a #sync event for the imported repository.")
+
+	fs.StringVar(&did, "did", "", "The DID of the (existing) account to import the repository in to.")
+	fs.StringVar(&bucket_uri, "bucket-uri", "", "An optional gocloud.dev/blob.Bucket URI to read the CAR file from. If empty the CAR file will be read from the local filesystem.")
+	fs.StringVar(&filename, "filename", "", "The name of the CAR file to read. If empty this will be \"{DID}.car\". If \"-\" (and -bucket-uri is empty) the CAR file will be read from STDIN.")
+
+	fs.BoolVar(&verbose, "verbose", false, "Enable verbose (debug) logging.")
+	return fs
+}
@@ -0,0 +1,175 @@
+package importer
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/aaronland/gocloud/blob/bucket"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+func Run(ctx context.Context) error {
+	fs := DefaultFlagSet()
+	return RunWithFlagSet(ctx, fs)
+}
+
+func RunWithFlagSet(ctx context.Context, fs *flag.FlagSet) error {
+
+	opts, err := OptionsFromFlagSet(ctx, fs)
+
+	if err != nil {
+		return err
+	}
+
+	return RunWithOptions(ctx, opts)
+}
+
+func RunWithOptions(ctx context.Context, opts *RunOptions) error {
+
+	if opts.Verbose {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+		slog.Debug("Verbose logging enabled")
+	}
+
+	logger := slog.Default()
+	logger = logger.With("did", opts.DID)
+
+	accounts_db, err := pds.NewAccountsDatabase(ctx, opts.AccountsDatabaseURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create accounts database, %w", err)
+	}
+
+	defer accounts_db.Close()
+
+	records_db, err := pds.NewRecordsDatabase(ctx, opts.RecordsDatabaseURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create records database, %w", err)
+	}
+
+	defer records_db.Close()
+
+	keys_db, err := pds.NewKeysDatabase(ctx, opts.KeysDatabaseURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create keys database, %w", err)
+	}
+
+	defer keys_db.Close()
+
+	commits_db, err := pds.NewCommitsDatabase(ctx, opts.CommitsDatabaseURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create commits database, %w", err)
+	}
+
+	defer commits_db.Close()
+
+	blocks_db, err := pds.NewBlocksDatabase(ctx, opts.BlocksDatabaseURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create blocks database, %w", err)
+	}
+
+	defer blocks_db.Close()
+
+	acct, err := pds.GetAccount(ctx, accounts_db, opts.DID)
+
+	if err != nil {
+		return fmt.Errorf("Failed to retrieve account, %w", err)
+	}
+
+	if acct.Deleted != 0 {
+		return fmt.Errorf("Account has been deleted")
+	}
+
+	var r io.ReadCloser
+
+	switch {
+	case opts.BucketURI != "":
+
+		b, err := bucket.OpenBucket(ctx, opts.BucketURI)
+
+		if err != nil {
+			return fmt.Errorf("Failed to open bucket, %w", err)
+		}
+
+		defer b.Close()
+
+		bucket_r, err := b.NewReader(ctx, opts.Filename, nil)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create bucket reader, %w", err)
+		}
+
+		r = bucket_r
+
+	case opts.Filename == "-":
+		r = os.Stdin
+
+	default:
+
+		fh, err := os.Open(opts.Filename)
+
+		if err != nil {
+			return fmt.Errorf("Failed to open %s, %w", opts.Filename, err)
+		}
+
+		r = fh
+	}
+
+	defer r.Close()
+
+	import_opts := &pds.ImportRepoOptions{
+		RecordsDatabase: records_db,
+		KeysDatabase:    keys_db,
+		CommitsDatabase: commits_db,
+		BlocksDatabase:  blocks_db,
+	}
+
+	if opts.BlobsDatabaseURI != "" {
+
+		blobs_db, err := pds.NewBlobsDatabase(ctx, opts.BlobsDatabaseURI)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create blobs database, %w", err)
+		}
+
+		defer blobs_db.Close()
+
+		import_opts.BlobsDatabase = blobs_db
+	}
+
+	if opts.EventsDatabaseURI != "" {
+
+		events_db, err := pds.NewEventsDatabase(ctx, opts.EventsDatabaseURI)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create events database, %w", err)
+		}
+
+		defer events_db.Close()
+
+		sequencer, err := pds.NewSequencer(ctx, events_db)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create sequencer, %w", err)
+		}
+
+		import_opts.Sequencer = sequencer
+	}
+
+	commit, err := pds.ImportRepo(ctx, import_opts, acct.DID, r)
+
+	if err != nil {
+		return fmt.Errorf("Failed to import repo, %w", err)
+	}
+
+	logger.Debug("Imported repo", "commit", commit.CID, "rev", commit.Rev, "filename", opts.Filename)
+	return nil
+}
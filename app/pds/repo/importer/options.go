@@ -0,0 +1,83 @@
+package importer
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/sfomuseum/go-flags/flagset"
+)
+
+type RunOptions struct {
+	AccountsDatabaseURI string `json:"accounts_database_uri"`
+	RecordsDatabaseURI  string `json:"records_database_uri"`
+	KeysDatabaseURI     string `json:"keys_database_uri"`
+	CommitsDatabaseURI  string `json:"commits_database_uri"`
+	BlocksDatabaseURI   string `json:"blocks_database_uri"`
+	BlobsDatabaseURI    string `json:"blobs_database_uri"`
+	EventsDatabaseURI   string `json:"events_database_uri"`
+	DID                 string `json:"did"`
+	BucketURI           string `json:"bucket_uri"`
+	Filename            string `json:"filename"`
+	Verbose             bool   `json:"verbose"`
+}
+
+func OptionsFromFlagSet(ctx context.Context, fs *flag.FlagSet) (*RunOptions, error) {
+
+	flagset.Parse(fs)
+
+	if database_uri != "" {
+
+		if accounts_database_uri == "" {
+			accounts_database_uri = database_uri
+		}
+
+		if records_database_uri == "" {
+			records_database_uri = database_uri
+		}
+
+		if keys_database_uri == "" {
+			keys_database_uri = database_uri
+		}
+
+		if commits_database_uri == "" {
+			commits_database_uri = database_uri
+		}
+
+		if blocks_database_uri == "" {
+			blocks_database_uri = database_uri
+		}
+
+		if blobs_database_uri == "" {
+			blobs_database_uri = database_uri
+		}
+
+		if events_database_uri == "" {
+			events_database_uri = database_uri
+		}
+	}
+
+	if did == "" {
+		return nil, fmt.Errorf("Missing -did flag")
+	}
+
+	if filename == "" {
+		filename = fmt.Sprintf("%s.car", did)
+	}
+
+	opts := &RunOptions{
+		AccountsDatabaseURI: accounts_database_uri,
+		RecordsDatabaseURI:  records_database_uri,
+		KeysDatabaseURI:     keys_database_uri,
+		CommitsDatabaseURI:  commits_database_uri,
+		BlocksDatabaseURI:   blocks_database_uri,
+		BlobsDatabaseURI:    blobs_database_uri,
+		EventsDatabaseURI:   events_database_uri,
+		DID:                 did,
+		BucketURI:           bucket_uri,
+		Filename:            filename,
+		Verbose:             verbose,
+	}
+
+	return opts, nil
+}
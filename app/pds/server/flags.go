@@ -2,10 +2,39 @@ package server
 
 import (
 	"flag"
+	"time"
 
+	"github.com/sfomuseum/go-atproto/pds"
 	"github.com/sfomuseum/go-flags/flagset"
+	"github.com/sfomuseum/go-flags/multi"
 )
 
+var database_uri string
+
+var accounts_database_uri string
+var records_database_uri string
+var keys_database_uri string
+var commits_database_uri string
+var blocks_database_uri string
+var operations_database_uri string
+var events_database_uri string
+var blobs_database_uri string
+
+var blob_store_uri string
+var max_blob_size int64
+var blob_grace_period time.Duration
+
+var normalize_images bool
+var image_max_dimension int
+var image_max_size int64
+
+var authenticator_uri string
+
+var event_retention time.Duration
+
+var relay_uris multi.MultiString
+var hostname string
+
 var verbose bool
 var server_uri string
 
@@ -13,6 +42,31 @@ func DefaultFlagSet() *flag.FlagSet {
 
 	fs := flagset.NewFlagSet("server")
 
+	fs.StringVar(&database_uri, "database-uri", "", "An optional common database URI to apply to all other empty -{SUBJECT}-database-uri flags. This is a convenience flag for things like SQL databases.")
+
+	fs.StringVar(&accounts_database_uri, "account-database-uri", "", "A registered sfomuseum/go-atproto/pds.AccountsDatabase URI.")
+	fs.StringVar(&records_database_uri, "records-database-uri", "", "A registered sfomuseum/go-atproto/pds.RecordsDatabase URI.")
+	fs.StringVar(&keys_database_uri, "keys-database-uri", "", "A registered sfomuseum/go-atproto/pds.KeysDatabase URI.")
+	fs.StringVar(&operations_database_uri, "operations-database-uri", "", "A registered sfomuseum/go-atproto/pds.OperationsDatabase URI.")
+	fs.StringVar(&commits_database_uri, "commits-database-uri", "", "A registered sfomuseum/go-atproto/pds.CommitsDatabase URI.")
+	fs.StringVar(&blocks_database_uri, "blocks-database-uri", "", "A registered sfomuseum/go-atproto/pds.BlocksDatabase URI.")
+	fs.StringVar(&events_database_uri, "events-database-uri", "", "A registered sfomuseum/go-atproto/pds.EventsDatabase URI.")
+	fs.StringVar(&blobs_database_uri, "blobs-database-uri", "", "A registered sfomuseum/go-atproto/pds.BlobsDatabase URI.")
+
+	fs.StringVar(&blob_store_uri, "blob-store-uri", "", "A valid gocloud.dev/blob.Bucket URI where blob data is stored. If empty then the blob handlers (uploadBlob, getBlob and listBlobs) are disabled.")
+	fs.Int64Var(&max_blob_size, "max-blob-size", pds.DEFAULT_MAX_BLOB_SIZE, "The maximum size (in bytes) of an uploaded blob.")
+	fs.BoolVar(&normalize_images, "normalize-images", true, "Remove metadata (Exif, XMP, IPTC) from uploaded JPEG, PNG and WebP images and apply any -image-max-dimension or -image-max-size limits.")
+	fs.IntVar(&image_max_dimension, "image-max-dimension", 0, "The maximum width or height, in pixels, of uploaded images. Larger images are downsized. If 0 images are not downsized to fit a dimension.")
+	fs.Int64Var(&image_max_size, "image-max-size", 0, "The maximum size, in bytes, of uploaded images. Larger images are re-encoded and downsized. If 0 images are not downsized to fit a size.")
+	fs.DurationVar(&blob_grace_period, "blob-grace-period", pds.DEFAULT_BLOB_GRACE_PERIOD, "The amount of time that blobs which are not referenced by any records are retained before being garbage collected. If zero blobs are never garbage collected by the server.")
+
+	fs.StringVar(&authenticator_uri, "authenticator-uri", "null://", "A registered sfomuseum/go-atproto/auth.Authenticator URI. For example jwt://?audience={SERVICE_DID}")
+
+	fs.DurationVar(&event_retention, "event-retention", 72*time.Hour, "The amount of time to retain (com.atproto.sync.subscribeRepos) events for replaying to subscribers. If zero events are never pruned.")
+
+	fs.Var(&relay_uris, "relay-uri", "Zero or more relay URIs (or hostnames) to ask to crawl this PDS (using com.atproto.sync.requestCrawl) at startup and to notify of updates (using com.atproto.sync.notifyOfUpdate) periodically.")
+	fs.StringVar(&hostname, "hostname", "", "The public hostname of this PDS. Required if one or more -relay-uri flags are set.")
+
 	fs.BoolVar(&verbose, "verbose", false, "Enable verbose (debug) logging.")
 	fs.StringVar(&server_uri, "server-uri", "http://localhost:8080", "A valid aaronland/go-http/v3/server.Server URI.")
 
@@ -3,20 +3,94 @@ package server
 import (
 	"context"
 	"flag"
+	"time"
 
 	"github.com/sfomuseum/go-flags/flagset"
 )
 
 type RunOptions struct {
-	ServerURI string `json:"server_uri"`
+	ServerURI             string        `json:"server_uri"`
+	AccountsDatabaseURI   string        `json:"accounts_database_uri"`
+	RecordsDatabaseURI    string        `json:"records_database_uri"`
+	KeysDatabaseURI       string        `json:"keys_database_uri"`
+	CommitsDatabaseURI    string        `json:"commits_database_uri"`
+	BlocksDatabaseURI     string        `json:"blocks_database_uri"`
+	OperationsDatabaseURI string        `json:"operations_database_uri"`
+	EventsDatabaseURI     string        `json:"events_database_uri"`
+	BlobsDatabaseURI      string        `json:"blobs_database_uri"`
+	BlobStoreURI          string        `json:"blob_store_uri"`
+	MaxBlobSize           int64         `json:"max_blob_size"`
+	BlobGracePeriod       time.Duration `json:"blob_grace_period"`
+	NormalizeImages       bool          `json:"normalize_images"`
+	ImageMaxDimension     int           `json:"image_max_dimension"`
+	ImageMaxSize          int64         `json:"image_max_size"`
+	AuthenticatorURI      string        `json:"authenticator_uri"`
+	EventRetention        time.Duration `json:"event_retention"`
+	RelayURIs             []string      `json:"relay_uris"`
+	Hostname              string        `json:"hostname"`
+	Verbose               bool          `json:"verbose"`
 }
 
 func OptionsFromFlagSet(ctx context.Context, fs *flag.FlagSet) (*RunOptions, error) {
 
 	flagset.Parse(fs)
 
+	if database_uri != "" {
+
+		if accounts_database_uri == "" {
+			accounts_database_uri = database_uri
+		}
+
+		if records_database_uri == "" {
+			records_database_uri = database_uri
+		}
+
+		if keys_database_uri == "" {
+			keys_database_uri = database_uri
+		}
+
+		if commits_database_uri == "" {
+			commits_database_uri = database_uri
+		}
+
+		if blocks_database_uri == "" {
+			blocks_database_uri = database_uri
+		}
+
+		if operations_database_uri == "" {
+			operations_database_uri = database_uri
+		}
+
+		if events_database_uri == "" {
+			events_database_uri = database_uri
+		}
+
+		if blobs_database_uri == "" {
+			blobs_database_uri = database_uri
+		}
+	}
+
 	opts := &RunOptions{
-		ServerURI: server_uri,
+		ServerURI:             server_uri,
+		AccountsDatabaseURI:   accounts_database_uri,
+		RecordsDatabaseURI:    records_database_uri,
+		KeysDatabaseURI:       keys_database_uri,
+		CommitsDatabaseURI:    commits_database_uri,
+		BlocksDatabaseURI:     blocks_database_uri,
+		OperationsDatabaseURI: operations_database_uri,
+		EventsDatabaseURI:     events_database_uri,
+		BlobsDatabaseURI:      blobs_database_uri,
+		BlobStoreURI:          blob_store_uri,
+		MaxBlobSize:           max_blob_size,
+		BlobGracePeriod:       blob_grace_period,
+		NormalizeImages:       normalize_images,
+		ImageMaxDimension:     image_max_dimension,
+		ImageMaxSize:          image_max_size,
+		AuthenticatorURI:      authenticator_uri,
+		EventRetention:        event_retention,
+		RelayURIs:             relay_uris,
+		Hostname:              hostname,
+		Verbose:               verbose,
 	}
 
 	return opts, nil
@@ -3,15 +3,33 @@ package server
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"time"
 
 	aa_server "github.com/aaronland/go-http/v3/server"
+	"github.com/sfomuseum/go-atproto/auth"
 	"github.com/sfomuseum/go-atproto/http/xrpc/com/atproto/identity"
 	"github.com/sfomuseum/go-atproto/http/xrpc/com/atproto/repo"
+	"github.com/sfomuseum/go-atproto/http/xrpc/com/atproto/sync"
+	"github.com/sfomuseum/go-atproto/imaging"
 	"github.com/sfomuseum/go-atproto/pds"
+	"github.com/sfomuseum/go-atproto/relay"
 )
 
+// The interval at which the events database is checked for events added by other processes.
+const EVENTS_POLL_INTERVAL time.Duration = 1 * time.Second
+
+// The interval at which events older than `RunOptions.EventRetention` are pruned.
+const EVENTS_PRUNE_INTERVAL time.Duration = 1 * time.Hour
+
+// The interval at which blobs which are not referenced by any records, and are older than `RunOptions.BlobGracePeriod`, are garbage collected.
+const BLOBS_GC_INTERVAL time.Duration = 1 * time.Hour
+
+// The interval at which relays are notified (using com.atproto.sync.notifyOfUpdate) if any events have been emitted since they were last notified.
+const RELAY_NOTIFY_INTERVAL time.Duration = 20 * time.Minute
+
 func Run(ctx context.Context) error {
 	fs := DefaultFlagSet()
 	return RunWithFlagSet(ctx, fs)
@@ -30,8 +48,192 @@ func RunWithFlagSet(ctx context.Context, fs *flag.FlagSet) error {
 
 func RunWithOptions(ctx context.Context, opts *RunOptions) error {
 
-	var accounts_db pds.AccountsDatabase
-	var records_db pds.RecordsDatabase
+	if opts.Verbose {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+		slog.Debug("Verbose logging enabled")
+	}
+
+	if len(opts.RelayURIs) > 0 && opts.Hostname == "" {
+		return fmt.Errorf("Missing hostname, required to request crawls from relays")
+	}
+
+	if opts.BlobStoreURI != "" && opts.BlobsDatabaseURI == "" {
+		return fmt.Errorf("Missing blobs database URI, required when a blob store URI is defined")
+	}
+
+	accounts_db, err := pds.NewAccountsDatabase(ctx, opts.AccountsDatabaseURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create accounts database, %w", err)
+	}
+
+	defer accounts_db.Close()
+
+	records_db, err := pds.NewRecordsDatabase(ctx, opts.RecordsDatabaseURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create records database, %w", err)
+	}
+
+	defer records_db.Close()
+
+	keys_db, err := pds.NewKeysDatabase(ctx, opts.KeysDatabaseURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create keys database, %w", err)
+	}
+
+	defer keys_db.Close()
+
+	commits_db, err := pds.NewCommitsDatabase(ctx, opts.CommitsDatabaseURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create commits database, %w", err)
+	}
+
+	defer commits_db.Close()
+
+	operations_db, err := pds.NewOperationsDatabase(ctx, opts.OperationsDatabaseURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create operations database, %w", err)
+	}
+
+	defer operations_db.Close()
+
+	authenticator, err := auth.NewAuthenticator(ctx, opts.AuthenticatorURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create authenticator, %w", err)
+	}
+
+	blocks_db, err := pds.NewBlocksDatabase(ctx, opts.BlocksDatabaseURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create blocks database, %w", err)
+	}
+
+	defer blocks_db.Close()
+
+	events_db, err := pds.NewEventsDatabase(ctx, opts.EventsDatabaseURI)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create events database, %w", err)
+	}
+
+	defer events_db.Close()
+
+	// The blobs database and blob store are optional. If there is no blobs database the blobs referenced by
+	// records are not recorded and if there is no blob store the blob handlers are disabled.
+
+	var blobs_db pds.BlobsDatabase
+	var blob_store *pds.BlobStore
+
+	if opts.BlobsDatabaseURI != "" {
+
+		blobs_db, err = pds.NewBlobsDatabase(ctx, opts.BlobsDatabaseURI)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create blobs database, %w", err)
+		}
+
+		defer blobs_db.Close()
+	}
+
+	if opts.BlobStoreURI != "" {
+
+		blob_store, err = pds.NewBlobStore(ctx, opts.BlobStoreURI)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create blob store, %w", err)
+		}
+
+		defer blob_store.Close()
+	}
+
+	sequencer, err := pds.NewSequencer(ctx, events_db)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create sequencer, %w", err)
+	}
+
+	// Broadcast events added by other processes (for example account events) and prune old events
+
+	go func() {
+
+		ticker := time.NewTicker(EVENTS_POLL_INTERVAL)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+
+				err := sequencer.Poll(ctx)
+
+				if err != nil {
+					slog.Error("Failed to poll for events", "error", err)
+				}
+			}
+		}
+	}()
+
+	if opts.EventRetention > 0 {
+
+		go func() {
+
+			ticker := time.NewTicker(EVENTS_PRUNE_INTERVAL)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+
+					before := time.Now().Add(-opts.EventRetention)
+					err := pds.PruneEvents(ctx, events_db, before.Unix())
+
+					if err != nil {
+						slog.Error("Failed to prune events", "error", err)
+					}
+				}
+			}
+		}()
+	}
+
+	if blob_store != nil && opts.BlobGracePeriod > 0 {
+
+		go func() {
+
+			ticker := time.NewTicker(BLOBS_GC_INTERVAL)
+			defer ticker.Stop()
+
+			gc_opts := &pds.GCBlobsOptions{
+				BlobsDatabase: blobs_db,
+				BlobStore:     blob_store,
+				GracePeriod:   opts.BlobGracePeriod,
+			}
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+
+					deleted, err := pds.GCBlobs(ctx, gc_opts)
+
+					if err != nil {
+						slog.Error("Failed to garbage collect blobs", "error", err)
+						continue
+					}
+
+					slog.Debug("Garbage collected blobs", "count", len(deleted))
+				}
+			}
+		}()
+	}
 
 	mux := http.NewServeMux()
 
@@ -53,6 +255,7 @@ func RunWithOptions(ctx context.Context, opts *RunOptions) error {
 
 	get_record_opts := &repo.GetRecordHandlerOptions{
 		RecordsDatabase: records_db,
+		CommitsDatabase: commits_db,
 	}
 
 	get_record, err := repo.GetRecordHandler(get_record_opts)
@@ -63,12 +266,377 @@ func RunWithOptions(ctx context.Context, opts *RunOptions) error {
 
 	mux.Handle(repo.GetRecordHandlerURI, get_record)
 
+	// Describe repo
+
+	describe_repo_opts := &repo.DescribeRepoHandlerOptions{
+		AccountsDatabase:   accounts_db,
+		OperationsDatabase: operations_db,
+		RecordsDatabase:    records_db,
+	}
+
+	describe_repo, err := repo.DescribeRepoHandler(describe_repo_opts)
+
+	if err != nil {
+		return err
+	}
+
+	mux.Handle(repo.DescribeRepoHandlerURI, describe_repo)
+
+	// List records
+
+	list_records_opts := &repo.ListRecordsHandlerOptions{
+		AccountsDatabase: accounts_db,
+		RecordsDatabase:  records_db,
+	}
+
+	list_records, err := repo.ListRecordsHandler(list_records_opts)
+
+	if err != nil {
+		return err
+	}
+
+	mux.Handle(repo.ListRecordsHandlerURI, list_records)
+
+	// Put record
+
+	put_record_opts := &repo.PutRecordHandlerOptions{
+		AccountsDatabase: accounts_db,
+		RecordsDatabase:  records_db,
+		KeysDatabase:     keys_db,
+		CommitsDatabase:  commits_db,
+		BlocksDatabase:   blocks_db,
+		Authenticator:    authenticator,
+		Sequencer:        sequencer,
+		BlobsDatabase:    blobs_db,
+	}
+
+	put_record, err := repo.PutRecordHandler(put_record_opts)
+
+	if err != nil {
+		return err
+	}
+
+	mux.Handle(repo.PutRecordHandlerURI, put_record)
+
+	// Create record
+
+	create_record_opts := &repo.CreateRecordHandlerOptions{
+		AccountsDatabase: accounts_db,
+		RecordsDatabase:  records_db,
+		KeysDatabase:     keys_db,
+		CommitsDatabase:  commits_db,
+		BlocksDatabase:   blocks_db,
+		Authenticator:    authenticator,
+		Sequencer:        sequencer,
+		BlobsDatabase:    blobs_db,
+	}
+
+	create_record, err := repo.CreateRecordHandler(create_record_opts)
+
+	if err != nil {
+		return err
+	}
+
+	mux.Handle(repo.CreateRecordHandlerURI, create_record)
+
+	// Delete record
+
+	delete_record_opts := &repo.DeleteRecordHandlerOptions{
+		AccountsDatabase: accounts_db,
+		RecordsDatabase:  records_db,
+		KeysDatabase:     keys_db,
+		CommitsDatabase:  commits_db,
+		BlocksDatabase:   blocks_db,
+		Authenticator:    authenticator,
+		Sequencer:        sequencer,
+		BlobsDatabase:    blobs_db,
+	}
+
+	delete_record, err := repo.DeleteRecordHandler(delete_record_opts)
+
+	if err != nil {
+		return err
+	}
+
+	mux.Handle(repo.DeleteRecordHandlerURI, delete_record)
+
+	// Apply writes
+
+	apply_writes_opts := &repo.ApplyWritesHandlerOptions{
+		AccountsDatabase: accounts_db,
+		RecordsDatabase:  records_db,
+		KeysDatabase:     keys_db,
+		CommitsDatabase:  commits_db,
+		BlocksDatabase:   blocks_db,
+		Authenticator:    authenticator,
+		Sequencer:        sequencer,
+		BlobsDatabase:    blobs_db,
+	}
+
+	apply_writes, err := repo.ApplyWritesHandler(apply_writes_opts)
+
+	if err != nil {
+		return err
+	}
+
+	mux.Handle(repo.ApplyWritesHandlerURI, apply_writes)
+
+	// Import repo
+
+	import_repo_opts := &repo.ImportRepoHandlerOptions{
+		AccountsDatabase: accounts_db,
+		RecordsDatabase:  records_db,
+		KeysDatabase:     keys_db,
+		CommitsDatabase:  commits_db,
+		BlocksDatabase:   blocks_db,
+		Authenticator:    authenticator,
+		BlobsDatabase:    blobs_db,
+		Sequencer:        sequencer,
+	}
+
+	import_repo, err := repo.ImportRepoHandler(import_repo_opts)
+
+	if err != nil {
+		return err
+	}
+
+	mux.Handle(repo.ImportRepoHandlerURI, import_repo)
+
+	// Blobs (upload blob, get blob and list blobs)
+
+	if blob_store != nil {
+
+		upload_blob_opts := &repo.UploadBlobHandlerOptions{
+			AccountsDatabase: accounts_db,
+			BlobsDatabase:    blobs_db,
+			BlobStore:        blob_store,
+			Authenticator:    authenticator,
+			MaxBlobSize:      opts.MaxBlobSize,
+		}
+
+		if opts.NormalizeImages {
+
+			upload_blob_opts.Images = &imaging.Options{
+				MaxDimension: opts.ImageMaxDimension,
+				MaxSize:      opts.ImageMaxSize,
+			}
+		}
+
+		upload_blob, err := repo.UploadBlobHandler(upload_blob_opts)
+
+		if err != nil {
+			return err
+		}
+
+		mux.Handle(repo.UploadBlobHandlerURI, upload_blob)
+
+		get_blob_opts := &sync.GetBlobHandlerOptions{
+			AccountsDatabase: accounts_db,
+			BlobsDatabase:    blobs_db,
+			BlobStore:        blob_store,
+		}
+
+		get_blob, err := sync.GetBlobHandler(get_blob_opts)
+
+		if err != nil {
+			return err
+		}
+
+		mux.Handle(sync.GetBlobHandlerURI, get_blob)
+
+		list_blobs_opts := &sync.ListBlobsHandlerOptions{
+			AccountsDatabase: accounts_db,
+			BlobsDatabase:    blobs_db,
+		}
+
+		list_blobs, err := sync.ListBlobsHandler(list_blobs_opts)
+
+		if err != nil {
+			return err
+		}
+
+		mux.Handle(sync.ListBlobsHandlerURI, list_blobs)
+
+	} else {
+		slog.Debug("No blob store URI defined, blob handlers are disabled")
+	}
+
+	// Get repo (sync)
+
+	get_repo_opts := &sync.GetRepoHandlerOptions{
+		AccountsDatabase: accounts_db,
+		RecordsDatabase:  records_db,
+		CommitsDatabase:  commits_db,
+		BlocksDatabase:   blocks_db,
+	}
+
+	get_repo, err := sync.GetRepoHandler(get_repo_opts)
+
+	if err != nil {
+		return err
+	}
+
+	mux.Handle(sync.GetRepoHandlerURI, get_repo)
+
+	// List repos (sync)
+
+	list_repos_opts := &sync.ListReposHandlerOptions{
+		AccountsDatabase: accounts_db,
+		CommitsDatabase:  commits_db,
+	}
+
+	list_repos, err := sync.ListReposHandler(list_repos_opts)
+
+	if err != nil {
+		return err
+	}
+
+	mux.Handle(sync.ListReposHandlerURI, list_repos)
+
+	// Get blocks (sync)
+
+	get_blocks_opts := &sync.GetBlocksHandlerOptions{
+		AccountsDatabase: accounts_db,
+		BlocksDatabase:   blocks_db,
+	}
+
+	get_blocks, err := sync.GetBlocksHandler(get_blocks_opts)
+
+	if err != nil {
+		return err
+	}
+
+	mux.Handle(sync.GetBlocksHandlerURI, get_blocks)
+
+	// Get record (sync)
+
+	sync_get_record_opts := &sync.GetRecordHandlerOptions{
+		AccountsDatabase: accounts_db,
+		RecordsDatabase:  records_db,
+		CommitsDatabase:  commits_db,
+	}
+
+	sync_get_record, err := sync.GetRecordHandler(sync_get_record_opts)
+
+	if err != nil {
+		return err
+	}
+
+	mux.Handle(sync.GetRecordHandlerURI, sync_get_record)
+
+	// Get latest commit (sync)
+
+	get_latest_commit_opts := &sync.GetLatestCommitHandlerOptions{
+		AccountsDatabase: accounts_db,
+		CommitsDatabase:  commits_db,
+	}
+
+	get_latest_commit, err := sync.GetLatestCommitHandler(get_latest_commit_opts)
+
+	if err != nil {
+		return err
+	}
+
+	mux.Handle(sync.GetLatestCommitHandlerURI, get_latest_commit)
+
+	// Get repo status (sync)
+
+	get_repo_status_opts := &sync.GetRepoStatusHandlerOptions{
+		AccountsDatabase: accounts_db,
+		CommitsDatabase:  commits_db,
+	}
+
+	get_repo_status, err := sync.GetRepoStatusHandler(get_repo_status_opts)
+
+	if err != nil {
+		return err
+	}
+
+	mux.Handle(sync.GetRepoStatusHandlerURI, get_repo_status)
+
+	// Subscribe repos (sync)
+
+	subscribe_repos_opts := &sync.SubscribeReposHandlerOptions{
+		Sequencer: sequencer,
+	}
+
+	subscribe_repos, err := sync.SubscribeReposHandler(subscribe_repos_opts)
+
+	if err != nil {
+		return err
+	}
+
+	mux.Handle(sync.SubscribeReposHandlerURI, subscribe_repos)
+
 	s, err := aa_server.NewServer(ctx, opts.ServerURI)
 
 	if err != nil {
 		return err
 	}
 
+	// Relays are notified in the background so that slow or unavailable relays don't delay startup
+
+	if len(opts.RelayURIs) > 0 {
+
+		go func() {
+
+			relay_cl := relay.DefaultClient()
+			err := relay_cl.RequestCrawlAll(ctx, opts.RelayURIs, opts.Hostname)
+
+			if err != nil {
+				slog.Error("Failed to request crawl from one or more relays", "error", err)
+			}
+		}()
+	}
+
+	// Relays are expected to follow the firehose once they have crawled this PDS but they are also told periodically
+	// if there have been any updates, for relays which have dropped their connection
+
+	if len(opts.RelayURIs) > 0 {
+
+		go func() {
+
+			last_seq, err := pds.GetLatestSequence(ctx, events_db)
+
+			if err != nil {
+				slog.Error("Failed to retrieve latest sequence, relays will not be notified of updates", "error", err)
+				return
+			}
+
+			relay_cl := relay.DefaultClient()
+
+			ticker := time.NewTicker(RELAY_NOTIFY_INTERVAL)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+
+					seq, err := pds.GetLatestSequence(ctx, events_db)
+
+					if err != nil {
+						slog.Error("Failed to retrieve latest sequence", "error", err)
+						continue
+					}
+
+					if seq <= last_seq {
+						continue
+					}
+
+					last_seq = seq
+
+					err = relay_cl.NotifyOfUpdateAll(ctx, opts.RelayURIs, opts.Hostname)
+
+					if err != nil {
+						slog.Error("Failed to notify one or more relays of updates", "error", err)
+					}
+				}
+			}
+		}()
+	}
+
 	slog.Info("Listening for requests", "address", s.Address())
 	return s.ListenAndServe(ctx, mux)
 }
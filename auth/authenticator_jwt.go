@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/identity"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/sfomuseum/go-atproto"
+)
+
+// JWTAuthenticator implements the `Authenticator` interface for requests with an "Authorization: Bearer {JWT}"
+// header containing an atproto inter-service authentication token. The token's signature is verified using the
+// "atproto" key published in the DID document of the token's issuer.
+type JWTAuthenticator struct {
+	Authenticator
+	audience  string
+	directory identity.Directory
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterAuthenticator(ctx, "jwt", NewJWTAuthenticator)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewJWTAuthenticator returns a new `JWTAuthenticator` instance configured by 'uri' which is expected to take
+// the form of:
+//
+//	jwt://?{PARAMETERS}
+//
+// Where {PARAMETERS} are:
+// * `audience` – The DID of this service. This is required. Tokens whose "aud" claim does not match will be rejected.
+//
+// Tokens must also have a "lxm" claim matching the NSID of the XRPC method being requested. This package does not
+// issue tokens on behalf of clients; they are expected to be minted by (or on behalf of) the account's own PDS (for
+// example using com.atproto.server.getServiceAuth) or by the `pds-service-token` tool for accounts hosted here.
+func NewJWTAuthenticator(ctx context.Context, uri string) (Authenticator, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	audience := q.Get("audience")
+
+	if audience == "" {
+		return nil, fmt.Errorf("Missing ?audience= parameter")
+	}
+
+	a := &JWTAuthenticator{
+		audience:  audience,
+		directory: identity.DefaultDirectory(),
+	}
+
+	return a, nil
+}
+
+func (a *JWTAuthenticator) GetDIDForRequest(req *http.Request) (string, error) {
+
+	str_auth := req.Header.Get("Authorization")
+
+	if !strings.HasPrefix(str_auth, "Bearer ") {
+		return "", atproto.ErrNotAuthorized
+	}
+
+	tk, err := ParseServiceToken(strings.TrimPrefix(str_auth, "Bearer "))
+
+	if err != nil {
+		return "", fmt.Errorf("%w, %w", atproto.ErrNotAuthorized, err)
+	}
+
+	now := time.Now()
+
+	if tk.Claims.ExpiresAt <= now.Unix() {
+		return "", fmt.Errorf("%w, token has expired", atproto.ErrNotAuthorized)
+	}
+
+	if tk.Claims.Audience != a.audience {
+		return "", fmt.Errorf("%w, invalid audience", atproto.ErrNotAuthorized)
+	}
+
+	nsid := strings.TrimPrefix(req.URL.Path, "/xrpc/")
+
+	if tk.Claims.LexiconMethod != nsid {
+		return "", fmt.Errorf("%w, token is not valid for %s", atproto.ErrNotAuthorized, nsid)
+	}
+
+	// The issuer may include a service fragment (for example "did:plc:xyz#atproto_labeler")
+
+	iss, _, _ := strings.Cut(tk.Claims.Issuer, "#")
+
+	did, err := syntax.ParseDID(iss)
+
+	if err != nil {
+		return "", fmt.Errorf("%w, invalid issuer, %w", atproto.ErrNotAuthorized, err)
+	}
+
+	ctx := req.Context()
+
+	id, err := a.directory.LookupDID(ctx, did)
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to resolve issuer, %w", err)
+	}
+
+	pub_key, err := id.PublicKey()
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to derive public key for issuer, %w", err)
+	}
+
+	err = tk.Verify(pub_key)
+
+	if err != nil {
+		return "", fmt.Errorf("%w, invalid signature, %w", atproto.ErrNotAuthorized, err)
+	}
+
+	return did.String(), nil
+}
@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/sfomuseum/go-atproto"
+)
+
+// NullAuthenticator implements the `Authenticator` interface but will never authenticate a request.
+type NullAuthenticator struct {
+	Authenticator
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterAuthenticator(ctx, "null", NewNullAuthenticator)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func NewNullAuthenticator(ctx context.Context, uri string) (Authenticator, error) {
+	a := &NullAuthenticator{}
+	return a, nil
+}
+
+func (a *NullAuthenticator) GetDIDForRequest(req *http.Request) (string, error) {
+	return "", atproto.ErrNotAuthorized
+}
@@ -0,0 +1,187 @@
+package auth
+
+// https://atproto.com/specs/xrpc#inter-service-authentication-jwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	at_crypto "github.com/bluesky-social/indigo/atproto/crypto"
+)
+
+// ServiceTokenHeader is the header of an atproto inter-service authentication JWT.
+type ServiceTokenHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+}
+
+// ServiceTokenClaims are the claims of an atproto inter-service authentication JWT.
+type ServiceTokenClaims struct {
+	// The DID of the account making the request.
+	Issuer string `json:"iss"`
+	// The DID of the service the request is being made to.
+	Audience  string `json:"aud"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ExpiresAt int64  `json:"exp"`
+	// The NSID of the XRPC method the token is bound to.
+	LexiconMethod string `json:"lxm,omitempty"`
+	Nonce         string `json:"jti,omitempty"`
+}
+
+// ServiceToken is a parsed (but not verified) atproto inter-service authentication JWT.
+type ServiceToken struct {
+	Header    *ServiceTokenHeader
+	Claims    *ServiceTokenClaims
+	Signature []byte
+	// The "{HEADER}.{CLAIMS}" string that 'Signature' was produced for.
+	signing_input string
+}
+
+// NewServiceToken returns a new JWT string for 'claims' signed by 'key'. If the `IssuedAt` or `ExpiresAt`
+// claims are empty they will be assigned the current time and the current time plus 'ttl' respectively.
+func NewServiceToken(key at_crypto.PrivateKey, claims *ServiceTokenClaims, ttl time.Duration) (string, error) {
+
+	var alg string
+
+	switch key.(type) {
+	case *at_crypto.PrivateKeyK256:
+		alg = "ES256K"
+	case *at_crypto.PrivateKeyP256:
+		alg = "ES256"
+	default:
+		return "", fmt.Errorf("Unsupported key type")
+	}
+
+	now := time.Now()
+
+	if claims.IssuedAt == 0 {
+		claims.IssuedAt = now.Unix()
+	}
+
+	if claims.ExpiresAt == 0 {
+		claims.ExpiresAt = now.Add(ttl).Unix()
+	}
+
+	header := &ServiceTokenHeader{
+		Algorithm: alg,
+		Type:      "JWT",
+	}
+
+	enc_header, err := encodeTokenSegment(header)
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode header, %w", err)
+	}
+
+	enc_claims, err := encodeTokenSegment(claims)
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode claims, %w", err)
+	}
+
+	signing_input := fmt.Sprintf("%s.%s", enc_header, enc_claims)
+
+	sig, err := key.HashAndSign([]byte(signing_input))
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to sign token, %w", err)
+	}
+
+	enc_sig := base64.RawURLEncoding.EncodeToString(sig)
+	return fmt.Sprintf("%s.%s", signing_input, enc_sig), nil
+}
+
+// ParseServiceToken parses 't' in to a `ServiceToken` instance. It does not verify the token's signature or claims.
+func ParseServiceToken(t string) (*ServiceToken, error) {
+
+	parts := strings.Split(t, ".")
+
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("Invalid token")
+	}
+
+	// Segments are decoded in to values, rather than pointers, so that a segment which is the JSON literal
+	// "null" does not produce a nil header or claims
+
+	var header ServiceTokenHeader
+	var claims ServiceTokenClaims
+
+	err := decodeTokenSegment(parts[0], &header)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode header, %w", err)
+	}
+
+	err = decodeTokenSegment(parts[1], &claims)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode claims, %w", err)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode signature, %w", err)
+	}
+
+	switch header.Algorithm {
+	case "ES256K", "ES256":
+		// pass
+	default:
+		return nil, fmt.Errorf("Unsupported algorithm '%s'", header.Algorithm)
+	}
+
+	tk := &ServiceToken{
+		Header:        &header,
+		Claims:        &claims,
+		Signature:     sig,
+		signing_input: fmt.Sprintf("%s.%s", parts[0], parts[1]),
+	}
+
+	return tk, nil
+}
+
+// Verify ensures that the signature for 'tk' was produced by 'key'.
+func (tk *ServiceToken) Verify(key at_crypto.PublicKey) error {
+
+	switch key.(type) {
+	case *at_crypto.PublicKeyK256:
+
+		if tk.Header.Algorithm != "ES256K" {
+			return fmt.Errorf("Algorithm does not match key type")
+		}
+
+	case *at_crypto.PublicKeyP256:
+
+		if tk.Header.Algorithm != "ES256" {
+			return fmt.Errorf("Algorithm does not match key type")
+		}
+	}
+
+	return key.HashAndVerifyLenient([]byte(tk.signing_input), tk.Signature)
+}
+
+func encodeTokenSegment(v any) (string, error) {
+
+	b, err := json.Marshal(v)
+
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeTokenSegment(s string, v any) error {
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	at_crypto "github.com/bluesky-social/indigo/atproto/crypto"
+	"github.com/sfomuseum/go-atproto"
+)
+
+func TestServiceToken(t *testing.T) {
+
+	key, err := at_crypto.GeneratePrivateKeyK256()
+
+	if err != nil {
+		t.Fatalf("Failed to generate key, %v", err)
+	}
+
+	claims := &ServiceTokenClaims{
+		Issuer:        "did:plc:ewvi7nxzyoun6zhxrhs64oiz",
+		Audience:      "did:web:pds.example.com",
+		LexiconMethod: "com.atproto.repo.putRecord",
+	}
+
+	t_str, err := NewServiceToken(key, claims, time.Minute)
+
+	if err != nil {
+		t.Fatalf("Failed to create token, %v", err)
+	}
+
+	tk, err := ParseServiceToken(t_str)
+
+	if err != nil {
+		t.Fatalf("Failed to parse token, %v", err)
+	}
+
+	if tk.Header.Algorithm != "ES256K" {
+		t.Fatalf("Unexpected algorithm, %s", tk.Header.Algorithm)
+	}
+
+	if tk.Claims.Issuer != claims.Issuer || tk.Claims.Audience != claims.Audience || tk.Claims.LexiconMethod != claims.LexiconMethod {
+		t.Fatalf("Parsed claims do not match, %v", tk.Claims)
+	}
+
+	if tk.Claims.ExpiresAt <= tk.Claims.IssuedAt {
+		t.Fatalf("Expected expiry after issue time")
+	}
+
+	pub_key, err := key.PublicKey()
+
+	if err != nil {
+		t.Fatalf("Failed to derive public key, %v", err)
+	}
+
+	err = tk.Verify(pub_key)
+
+	if err != nil {
+		t.Fatalf("Failed to verify token, %v", err)
+	}
+
+	other_key, err := at_crypto.GeneratePrivateKeyK256()
+
+	if err != nil {
+		t.Fatalf("Failed to generate key, %v", err)
+	}
+
+	other_pub_key, err := other_key.PublicKey()
+
+	if err != nil {
+		t.Fatalf("Failed to derive public key, %v", err)
+	}
+
+	err = tk.Verify(other_pub_key)
+
+	if err == nil {
+		t.Fatalf("Expected token to fail verification with a different key")
+	}
+}
+
+func TestParseServiceTokenInvalid(t *testing.T) {
+
+	enc := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	header := enc(`{"alg":"ES256K","typ":"JWT"}`)
+	claims := enc(`{"iss":"did:plc:ewvi7nxzyoun6zhxrhs64oiz","aud":"did:web:pds.example.com","exp":1}`)
+	sig := enc("signature")
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"too few segments", header + "." + claims},
+		{"invalid header", "!!!." + claims + "." + sig},
+		{"invalid claims", header + "." + enc("{") + "." + sig},
+		{"invalid signature", header + "." + claims + ".!!!"},
+		{"null header", enc("null") + "." + claims + "." + sig},
+		{"unsupported algorithm", enc(`{"alg":"none"}`) + "." + claims + "." + sig},
+	}
+
+	for _, tt := range tests {
+
+		_, err := ParseServiceToken(tt.token)
+
+		if err == nil {
+			t.Fatalf("Expected an error for %s", tt.name)
+		}
+	}
+
+	// A "null" claims segment must not produce nil claims
+
+	tk, err := ParseServiceToken(header + "." + enc("null") + "." + sig)
+
+	if err != nil {
+		t.Fatalf("Failed to parse token with null claims, %v", err)
+	}
+
+	if tk.Header == nil || tk.Claims == nil {
+		t.Fatalf("Expected header and claims to be defined")
+	}
+
+	if tk.Claims.ExpiresAt != 0 {
+		t.Fatalf("Expected empty claims")
+	}
+}
+
+func TestJWTAuthenticatorNullClaims(t *testing.T) {
+
+	ctx := context.Background()
+
+	a, err := NewAuthenticator(ctx, "jwt://?audience=did:web:pds.example.com")
+
+	if err != nil {
+		t.Fatalf("Failed to create authenticator, %v", err)
+	}
+
+	enc := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tokens := []string{
+		enc("null") + "." + enc("null") + "." + enc("signature"),
+		enc(`{"alg":"ES256K"}`) + "." + enc("null") + "." + enc("signature"),
+	}
+
+	for _, t_str := range tokens {
+
+		req := httptest.NewRequest(http.MethodPost, "/xrpc/com.atproto.repo.putRecord", nil)
+		req.Header.Set("Authorization", "Bearer "+t_str)
+
+		_, err := a.GetDIDForRequest(req)
+
+		if !errors.Is(err, atproto.ErrNotAuthorized) {
+			t.Fatalf("Expected not authorized error, got %v", err)
+		}
+	}
+}
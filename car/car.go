@@ -0,0 +1,187 @@
+package car
+
+// https://ipld.io/specs/transport/car/carv1/
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-cid"
+	"github.com/sfomuseum/go-atproto/dagcbor"
+)
+
+// The MIME type for CAR files.
+const CONTENT_TYPE string = "application/vnd.ipld.car"
+
+// Writer writes CARv1 data (a header followed by zero or more blocks) to an underlying `io.Writer`.
+type Writer struct {
+	writer io.Writer
+}
+
+// The maximum size of an individual section (header or block) that will be read by a `Reader`.
+const MAX_SECTION_SIZE uint64 = 2 * 1024 * 1024
+
+// Reader reads CARv1 data from an underlying `io.Reader`.
+type Reader struct {
+	reader *bufio.Reader
+	// The root CIDs declared by the CAR header.
+	Roots []cid.Cid
+}
+
+// NewWriter returns a new `Writer` instance which writes to 'wr' after first writing a CARv1 header for 'roots'.
+func NewWriter(wr io.Writer, roots ...cid.Cid) (*Writer, error) {
+
+	// CAR files without any roots (for example com.atproto.sync.getBlocks responses) must still encode an empty list
+	if roots == nil {
+		roots = make([]cid.Cid, 0)
+	}
+
+	header := map[string]any{
+		"version": int64(1),
+		"roots":   roots,
+	}
+
+	b, err := dagcbor.Marshal(header)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode header, %w", err)
+	}
+
+	car_wr := &Writer{
+		writer: wr,
+	}
+
+	err = car_wr.writeSection(b)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to write header, %w", err)
+	}
+
+	return car_wr, nil
+}
+
+// WriteBlock writes the block 'data', identified by 'c', to the underlying writer.
+func (car_wr *Writer) WriteBlock(c cid.Cid, data []byte) error {
+
+	b := append(c.Bytes(), data...)
+	return car_wr.writeSection(b)
+}
+
+func (car_wr *Writer) writeSection(b []byte) error {
+
+	sz := binary.AppendUvarint(nil, uint64(len(b)))
+
+	_, err := car_wr.writer.Write(sz)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = car_wr.writer.Write(b)
+	return err
+}
+
+// NewReader returns a new `Reader` instance which reads from 'r' after first reading and validating its CARv1 header.
+func NewReader(r io.Reader) (*Reader, error) {
+
+	car_r := &Reader{
+		reader: bufio.NewReader(r),
+	}
+
+	b, err := car_r.readSection()
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read header, %w", err)
+	}
+
+	var header map[string]any
+
+	err = dagcbor.Unmarshal(b, &header)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode header, %w", err)
+	}
+
+	version, ok := header["version"].(int)
+
+	if !ok || version != 1 {
+		return nil, fmt.Errorf("Unsupported CAR version (%v)", header["version"])
+	}
+
+	roots, ok := header["roots"].([]any)
+
+	if !ok {
+		return nil, fmt.Errorf("Invalid header, missing roots")
+	}
+
+	car_r.Roots = make([]cid.Cid, len(roots))
+
+	for i, r := range roots {
+
+		c, ok := r.(cid.Cid)
+
+		if !ok {
+			return nil, fmt.Errorf("Invalid header, root at offset %d is not a CID", i)
+		}
+
+		car_r.Roots[i] = c
+	}
+
+	return car_r, nil
+}
+
+// NextBlock returns the CID and data for the next block in the underlying reader. It returns `io.EOF` when there
+// are no more blocks. An error is returned if the data for a block does not match its CID.
+func (car_r *Reader) NextBlock() (cid.Cid, []byte, error) {
+
+	b, err := car_r.readSection()
+
+	if err != nil {
+		return cid.Undef, nil, err
+	}
+
+	n, c, err := cid.CidFromBytes(b)
+
+	if err != nil {
+		return cid.Undef, nil, fmt.Errorf("Failed to read block CID, %w", err)
+	}
+
+	data := b[n:]
+
+	data_cid, err := c.Prefix().Sum(data)
+
+	if err != nil {
+		return cid.Undef, nil, fmt.Errorf("Failed to derive CID for block %s, %w", c, err)
+	}
+
+	if !data_cid.Equals(c) {
+		return cid.Undef, nil, fmt.Errorf("Data for block %s does not match its CID", c)
+	}
+
+	return c, data, nil
+}
+
+func (car_r *Reader) readSection() ([]byte, error) {
+
+	sz, err := binary.ReadUvarint(car_r.reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if sz == 0 || sz > MAX_SECTION_SIZE {
+		return nil, fmt.Errorf("Invalid section size (%d)", sz)
+	}
+
+	b := make([]byte, sz)
+
+	_, err = io.ReadFull(car_r.reader, b)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read section, %w", err)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,178 @@
+package car
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/sfomuseum/go-atproto/dagcbor"
+)
+
+func testBlock(t *testing.T, v any) (cid.Cid, []byte) {
+
+	b, c, err := dagcbor.MarshalWithCID(v)
+
+	if err != nil {
+		t.Fatalf("Failed to encode block, %v", err)
+	}
+
+	return c, b
+}
+
+func TestRoundTrip(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		roots  int
+		blocks int
+	}{
+		{"no roots or blocks", 0, 0},
+		{"root and blocks", 1, 3},
+		{"multiple roots", 2, 2},
+	}
+
+	for _, tt := range tests {
+
+		t.Run(tt.name, func(t *testing.T) {
+
+			cids := make([]cid.Cid, tt.blocks)
+			blocks := make([][]byte, tt.blocks)
+
+			for i := 0; i < tt.blocks; i++ {
+				cids[i], blocks[i] = testBlock(t, map[string]any{"i": int64(i)})
+			}
+
+			var roots []cid.Cid
+
+			for i := 0; i < tt.roots; i++ {
+				c, _ := testBlock(t, map[string]any{"root": int64(i)})
+				roots = append(roots, c)
+			}
+
+			var buf bytes.Buffer
+
+			car_wr, err := NewWriter(&buf, roots...)
+
+			if err != nil {
+				t.Fatalf("Failed to create writer, %v", err)
+			}
+
+			for i, c := range cids {
+
+				err := car_wr.WriteBlock(c, blocks[i])
+
+				if err != nil {
+					t.Fatalf("Failed to write block, %v", err)
+				}
+			}
+
+			car_r, err := NewReader(&buf)
+
+			if err != nil {
+				t.Fatalf("Failed to create reader, %v", err)
+			}
+
+			if len(car_r.Roots) != tt.roots {
+				t.Fatalf("Expected %d roots, got %d", tt.roots, len(car_r.Roots))
+			}
+
+			for i, c := range roots {
+
+				if !car_r.Roots[i].Equals(c) {
+					t.Fatalf("Expected root %s, got %s", c, car_r.Roots[i])
+				}
+			}
+
+			for i := 0; ; i++ {
+
+				c, data, err := car_r.NextBlock()
+
+				if err == io.EOF {
+
+					if i != tt.blocks {
+						t.Fatalf("Expected %d blocks, got %d", tt.blocks, i)
+					}
+
+					break
+				}
+
+				if err != nil {
+					t.Fatalf("Failed to read block, %v", err)
+				}
+
+				if !c.Equals(cids[i]) || !bytes.Equal(data, blocks[i]) {
+					t.Fatalf("Block %d does not match", i)
+				}
+			}
+		})
+	}
+}
+
+func TestReaderInvalid(t *testing.T) {
+
+	c, data := testBlock(t, map[string]any{"hello": "world"})
+
+	var buf bytes.Buffer
+
+	car_wr, err := NewWriter(&buf, c)
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	err = car_wr.WriteBlock(c, data)
+
+	if err != nil {
+		t.Fatalf("Failed to write block, %v", err)
+	}
+
+	valid := buf.Bytes()
+
+	// The last byte of the block data, which no longer matches its CID
+	tampered := bytes.Clone(valid)
+	tampered[len(tampered)-1] ^= 0xFF
+
+	// A header whose section size exceeds MAX_SECTION_SIZE
+	too_large := binary.AppendUvarint(nil, MAX_SECTION_SIZE+1)
+
+	header_v2, _ := dagcbor.Marshal(map[string]any{"version": int64(2), "roots": []cid.Cid{}})
+	header_v2 = append(binary.AppendUvarint(nil, uint64(len(header_v2))), header_v2...)
+
+	tests := []struct {
+		name   string
+		body   []byte
+		header bool
+	}{
+		{"empty", []byte{}, false},
+		{"section too large", too_large, false},
+		{"unsupported version", header_v2, false},
+		{"truncated block", valid[:len(valid)-1], true},
+		{"block does not match CID", tampered, true},
+	}
+
+	for _, tt := range tests {
+
+		car_r, err := NewReader(bytes.NewReader(tt.body))
+
+		if !tt.header {
+
+			if err == nil {
+				t.Fatalf("Expected an error reading header for %s", tt.name)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("Failed to read header for %s, %v", tt.name, err)
+		}
+
+		_, _, err = car_r.NextBlock()
+
+		if err == nil || err == io.EOF {
+			t.Fatalf("Expected an error reading block for %s, got %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+// go run cmd/firehose-tail/main.go -host bsky.network -collection app.bsky.feed.post | jq
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+
+	"github.com/sfomuseum/go-atproto/firehose"
+	"github.com/sfomuseum/go-flags/multi"
+)
+
+func main() {
+
+	var host string
+	var cursor int64
+	var checkpoint_uri string
+	var verify bool
+	var verbose bool
+
+	var dids multi.MultiString
+	var collections multi.MultiString
+
+	flag.StringVar(&host, "host", "bsky.network", "The host (a PDS or a relay) to read events from. This may be a URL or a hostname, in which case \"wss\" is assumed.")
+	flag.Int64Var(&cursor, "cursor", -1, "The cursor (sequence number) to start reading events from, if no cursor has been stored by -checkpoint-uri. If negative only new events are read.")
+	flag.StringVar(&checkpoint_uri, "checkpoint-uri", "null://", "A registered sfomuseum/go-atproto/firehose.Checkpoint URI used to store the cursor of the last event read.")
+	flag.BoolVar(&verify, "verify", false, "If true verify the signature of commits using the current signing key for each account.")
+	flag.Var(&dids, "did", "Zero or more DIDs to limit events to.")
+	flag.Var(&collections, "collection", "Zero or more collections to limit commit operations to. Commits without any matching operations are not printed.")
+	flag.BoolVar(&verbose, "verbose", false, "Enable verbose (debug) logging.")
+
+	flag.Parse()
+
+	if verbose {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+		slog.Debug("Verbose logging enabled")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cp, err := firehose.NewCheckpoint(ctx, checkpoint_uri)
+
+	if err != nil {
+		log.Fatalf("Failed to create checkpoint, %v", err)
+	}
+
+	defer cp.Close()
+
+	c := firehose.NewConsumer(host, cp)
+	c.StartCursor = cursor
+	c.Verify = verify
+
+	enc := json.NewEncoder(os.Stdout)
+
+	handler := func(ctx context.Context, e *firehose.Event) error {
+
+		if len(dids) > 0 && !slices.Contains(dids, e.DID) {
+			return nil
+		}
+
+		if len(collections) > 0 {
+
+			if e.Commit == nil {
+				return nil
+			}
+
+			ops := make([]*firehose.Op, 0)
+
+			for _, op := range e.Commit.Ops {
+
+				collection, _, _ := strings.Cut(op.Path, "/")
+
+				if slices.Contains(collections, collection) {
+					ops = append(ops, op)
+				}
+			}
+
+			if len(ops) == 0 {
+				return nil
+			}
+
+			e.Commit.Ops = ops
+		}
+
+		return enc.Encode(e)
+	}
+
+	err = c.Consume(ctx, handler)
+
+	if err != nil && err != context.Canceled {
+		log.Fatalf("Failed to consume events, %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	_ "github.com/mattn/go-sqlite3"
+	_ "gocloud.dev/blob/fileblob"
+	_ "gocloud.dev/blob/memblob"
+
+	"github.com/sfomuseum/go-atproto/app/pds/repo/export"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+func main() {
+
+	ctx := context.Background()
+
+	err := pds.RegisterBlobRecordsSchemes(ctx)
+
+	if err != nil {
+		log.Fatalf("Failed to register blob schemes, %v", err)
+	}
+
+	err = export.Run(ctx)
+
+	if err != nil {
+		log.Fatalf("Failed to run export repo, %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	_ "github.com/mattn/go-sqlite3"
+	_ "gocloud.dev/blob/fileblob"
+	_ "gocloud.dev/blob/memblob"
+
+	"github.com/sfomuseum/go-atproto/app/pds/blob/gc"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+func main() {
+
+	ctx := context.Background()
+
+	err := pds.RegisterBlobBlobsSchemes(ctx)
+
+	if err != nil {
+		log.Fatalf("Failed to register blob schemes, %v", err)
+	}
+
+	err = gc.Run(ctx)
+
+	if err != nil {
+		log.Fatalf("Failed to garbage collect blobs, %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	_ "github.com/mattn/go-sqlite3"
+	_ "gocloud.dev/blob/fileblob"
+	_ "gocloud.dev/blob/memblob"
+
+	"github.com/sfomuseum/go-atproto/app/pds/repo/importer"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+func main() {
+
+	ctx := context.Background()
+
+	err := pds.RegisterBlobRecordsSchemes(ctx)
+
+	if err != nil {
+		log.Fatalf("Failed to register blob schemes, %v", err)
+	}
+
+	err = pds.RegisterBlobBlobsSchemes(ctx)
+
+	if err != nil {
+		log.Fatalf("Failed to register blob schemes, %v", err)
+	}
+
+	err = importer.Run(ctx)
+
+	if err != nil {
+		log.Fatalf("Failed to run import repo, %v", err)
+	}
+}
@@ -4,13 +4,37 @@ import (
 	"context"
 	"log"
 
+	_ "github.com/mattn/go-sqlite3"
+	_ "gocloud.dev/blob/fileblob"
+	_ "gocloud.dev/blob/memblob"
+
 	"github.com/sfomuseum/go-atproto/app/pds/server"
+	"github.com/sfomuseum/go-atproto/pds"
 )
 
 func main() {
 
 	ctx := context.Background()
-	err := server.Run(ctx)
+
+	err := pds.RegisterBlobAccountsSchemes(ctx)
+
+	if err != nil {
+		log.Fatalf("Failed to register blob schemes, %v", err)
+	}
+
+	err = pds.RegisterBlobRecordsSchemes(ctx)
+
+	if err != nil {
+		log.Fatalf("Failed to register blob schemes, %v", err)
+	}
+
+	err = pds.RegisterBlobBlobsSchemes(ctx)
+
+	if err != nil {
+		log.Fatalf("Failed to register blob schemes, %v", err)
+	}
+
+	err = server.Run(ctx)
 
 	if err != nil {
 		log.Fatalf("Failed to run server, %v", err)
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/sfomuseum/go-atproto/app/pds/account/token"
+)
+
+func main() {
+
+	ctx := context.Background()
+	err := token.Run(ctx)
+
+	if err != nil {
+		log.Fatalf("Failed to run service token, %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package dagcbor
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/bluesky-social/indigo/atproto/data"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/multiformats/go-multihash"
+)
+
+// Marshal encodes 'v' as canonical DAG-CBOR. Map keys are sorted (length first, then bytewise) and
+// `cid.Cid` values are encoded as CBOR tag 42 links.
+func Marshal(v any) ([]byte, error) {
+	return cbor.DumpObject(v)
+}
+
+// Unmarshal decodes DAG-CBOR encoded 'b' in to 'v'.
+func Unmarshal(b []byte, v any) error {
+	return cbor.DecodeInto(b, v)
+}
+
+// CID returns the CIDv1 (dag-cbor codec, sha-256 multihash) for DAG-CBOR encoded 'b'.
+func CID(b []byte) (cid.Cid, error) {
+
+	c, err := cid.NewPrefixV1(cid.DagCBOR, multihash.SHA2_256).Sum(b)
+
+	if err != nil {
+		return cid.Undef, fmt.Errorf("Failed to derive CID, %w", err)
+	}
+
+	return c, nil
+}
+
+// MarshalWithCID encodes 'v' as canonical DAG-CBOR and returns the encoded bytes and their CID.
+func MarshalWithCID(v any) ([]byte, cid.Cid, error) {
+
+	b, err := Marshal(v)
+
+	if err != nil {
+		return nil, cid.Undef, fmt.Errorf("Failed to marshal DAG-CBOR, %w", err)
+	}
+
+	c, err := CID(b)
+
+	if err != nil {
+		return nil, cid.Undef, err
+	}
+
+	return b, c, nil
+}
+
+// FromJSON parses 'b' as atproto data model JSON (including "$link", "$bytes" and "blob" objects) and
+// returns its canonical DAG-CBOR encoding.
+func FromJSON(b []byte) ([]byte, error) {
+
+	obj, err := data.UnmarshalJSON(b)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse atproto data, %w", err)
+	}
+
+	return data.MarshalCBOR(obj)
+}
+
+// ToJSON decodes DAG-CBOR encoded 'b' as atproto data and returns its atproto data model JSON encoding.
+func ToJSON(b []byte) ([]byte, error) {
+
+	obj, err := data.UnmarshalCBOR(b)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse atproto data, %w", err)
+	}
+
+	return json.Marshal(obj)
+}
@@ -9,3 +9,24 @@ var ErrNotImplemented = errors.New("Not implemented")
 
 // ErrNotFound is an error indicating that an item is not present or was not found.
 var ErrNotFound = errors.New("Not found")
+
+// ErrInvalidCID is an error indicating that a CID does not match the content it is meant to address.
+var ErrInvalidCID = errors.New("Invalid CID")
+
+// ErrNotAuthorized is an error indicating that a request has not been (or could not be) authenticated.
+var ErrNotAuthorized = errors.New("Not authorized")
+
+// ErrInvalidSwap is an error indicating that the expected ("swap") CID of a record or commit does not match its current value.
+var ErrInvalidSwap = errors.New("Invalid swap")
+
+// ErrInvalidWrite is an error indicating that a write (create, update, delete) operation can not be applied to a repository.
+var ErrInvalidWrite = errors.New("Invalid write")
+
+// ErrInvalidSignature is an error indicating that a signature was not produced by the expected key.
+var ErrInvalidSignature = errors.New("Invalid signature")
+
+// ErrBlobTooLarge is an error indicating that a blob exceeds the maximum allowed size.
+var ErrBlobTooLarge = errors.New("Blob too large")
+
+// ErrInvalidMediaType is an error indicating that the declared media type of a blob does not match its content.
+var ErrInvalidMediaType = errors.New("Invalid media type")
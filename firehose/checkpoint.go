@@ -0,0 +1,100 @@
+package firehose
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/aaronland/go-roster"
+)
+
+// Checkpoint persists the cursor (sequence number of the last processed event) for an event stream so that
+// consumers can resume from where they left off.
+type Checkpoint interface {
+	// GetCursor returns the last stored cursor. If no cursor has been stored `atproto.ErrNotFound` is returned.
+	GetCursor(context.Context) (int64, error)
+	SetCursor(context.Context, int64) error
+	Close() error
+}
+
+var checkpoint_roster roster.Roster
+
+// CheckpointInitializationFunc is a function defined by individual checkpoint package and used to create
+// an instance of that checkpoint
+type CheckpointInitializationFunc func(ctx context.Context, uri string) (Checkpoint, error)
+
+// RegisterCheckpoint registers 'scheme' as a key pointing to 'init_func' in an internal lookup table
+// used to create new `Checkpoint` instances by the `NewCheckpoint` method.
+func RegisterCheckpoint(ctx context.Context, scheme string, init_func CheckpointInitializationFunc) error {
+
+	err := ensureCheckpointRoster()
+
+	if err != nil {
+		return err
+	}
+
+	return checkpoint_roster.Register(ctx, scheme, init_func)
+}
+
+func ensureCheckpointRoster() error {
+
+	if checkpoint_roster == nil {
+
+		r, err := roster.NewDefaultRoster()
+
+		if err != nil {
+			return err
+		}
+
+		checkpoint_roster = r
+	}
+
+	return nil
+}
+
+// NewCheckpoint returns a new `Checkpoint` instance configured by 'uri'. The value of 'uri' is parsed
+// as a `url.URL` and its scheme is used as the key for a corresponding `CheckpointInitializationFunc`
+// function used to instantiate the new `Checkpoint`. It is assumed that the scheme (and initialization
+// function) have been registered by the `RegisterCheckpoint` method.
+func NewCheckpoint(ctx context.Context, uri string) (Checkpoint, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := u.Scheme
+
+	i, err := checkpoint_roster.Driver(ctx, scheme)
+
+	if err != nil {
+		return nil, err
+	}
+
+	init_func := i.(CheckpointInitializationFunc)
+	return init_func(ctx, uri)
+}
+
+// Schemes returns the list of schemes that have been registered.
+func CheckpointSchemes() []string {
+
+	ctx := context.Background()
+	schemes := []string{}
+
+	err := ensureCheckpointRoster()
+
+	if err != nil {
+		return schemes
+	}
+
+	for _, dr := range checkpoint_roster.Drivers(ctx) {
+		scheme := fmt.Sprintf("%s://", strings.ToLower(dr))
+		schemes = append(schemes, scheme)
+	}
+
+	sort.Strings(schemes)
+	return schemes
+}
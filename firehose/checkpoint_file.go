@@ -0,0 +1,113 @@
+package firehose
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sfomuseum/go-atproto"
+)
+
+// FileCheckpoint implements the `Checkpoint` interface by storing the cursor in a file on the local filesystem.
+type FileCheckpoint struct {
+	Checkpoint
+	path string
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterCheckpoint(ctx, "file", NewFileCheckpoint)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewFileCheckpoint returns a new `FileCheckpoint` instance configured by 'uri' which is expected to take
+// the form of:
+//
+//	file://{PATH}
+//
+// Where {PATH} is the path of the file to store the cursor in. The file will be created if it does not exist. Absolute
+// paths take the form "file:///path/to/cursor.txt" and relative paths, which are resolved against the current working
+// directory, take the form "file://cursor.txt" or "file://path/to/cursor.txt".
+func NewFileCheckpoint(ctx context.Context, uri string) (Checkpoint, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	// For relative paths the first path segment is parsed as the URI's host
+
+	path := u.Host + u.Path
+
+	if path == "" {
+		return nil, fmt.Errorf("Missing path")
+	}
+
+	abs_path, err := filepath.Abs(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive absolute path for %s, %w", path, err)
+	}
+
+	cp := &FileCheckpoint{
+		path: abs_path,
+	}
+
+	return cp, nil
+}
+
+func (cp *FileCheckpoint) GetCursor(ctx context.Context) (int64, error) {
+
+	b, err := os.ReadFile(cp.path)
+
+	if err != nil {
+
+		if os.IsNotExist(err) {
+			return 0, atproto.ErrNotFound
+		}
+
+		return 0, fmt.Errorf("Failed to read %s, %w", cp.path, err)
+	}
+
+	cursor, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+
+	if err != nil {
+		return 0, fmt.Errorf("Invalid cursor in %s, %w", cp.path, err)
+	}
+
+	return cursor, nil
+}
+
+func (cp *FileCheckpoint) SetCursor(ctx context.Context, cursor int64) error {
+
+	// Write to a temporary file and rename it so that a crash never leaves a partially written cursor
+
+	tmp_path := fmt.Sprintf("%s.tmp", cp.path)
+
+	err := os.WriteFile(tmp_path, []byte(strconv.FormatInt(cursor, 10)), 0644)
+
+	if err != nil {
+		return fmt.Errorf("Failed to write %s, %w", tmp_path, err)
+	}
+
+	err = os.Rename(tmp_path, cp.path)
+
+	if err != nil {
+		return fmt.Errorf("Failed to rename %s, %w", tmp_path, err)
+	}
+
+	return nil
+}
+
+func (cp *FileCheckpoint) Close() error {
+	return nil
+}
@@ -0,0 +1,102 @@
+package firehose
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/sfomuseum/go-atproto"
+)
+
+func TestNewFileCheckpoint(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+	t.Chdir(root)
+
+	// The working directory may be a symlink (for example on macOS) so paths are compared relative to it
+
+	cwd, err := filepath.Abs(".")
+
+	if err != nil {
+		t.Fatalf("Failed to derive working directory, %v", err)
+	}
+
+	tests := []struct {
+		uri      string
+		expected string
+		ok       bool
+	}{
+		{"file://cursor.txt", filepath.Join(cwd, "cursor.txt"), true},
+		{"file://data/cursor.txt", filepath.Join(cwd, "data", "cursor.txt"), true},
+		{"file://./cursor.txt", filepath.Join(cwd, "cursor.txt"), true},
+		{"file://" + filepath.Join(root, "abs.txt"), filepath.Join(root, "abs.txt"), true},
+		{"file://", "", false},
+	}
+
+	for _, tt := range tests {
+
+		cp, err := NewCheckpoint(ctx, tt.uri)
+
+		if !tt.ok {
+
+			if err == nil {
+				t.Fatalf("Expected an error for %s", tt.uri)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("Failed to create checkpoint for %s, %v", tt.uri, err)
+		}
+
+		path := cp.(*FileCheckpoint).path
+
+		if path != tt.expected {
+			t.Fatalf("Expected path %s for %s, got %s", tt.expected, tt.uri, path)
+		}
+	}
+}
+
+func TestFileCheckpointCursor(t *testing.T) {
+
+	ctx := context.Background()
+
+	t.Chdir(t.TempDir())
+
+	cp, err := NewCheckpoint(ctx, "file://cursor.txt")
+
+	if err != nil {
+		t.Fatalf("Failed to create checkpoint, %v", err)
+	}
+
+	defer cp.Close()
+
+	_, err = cp.GetCursor(ctx)
+
+	if !errors.Is(err, atproto.ErrNotFound) {
+		t.Fatalf("Expected not found error, got %v", err)
+	}
+
+	for _, cursor := range []int64{1, 12345, 42} {
+
+		err := cp.SetCursor(ctx, cursor)
+
+		if err != nil {
+			t.Fatalf("Failed to set cursor, %v", err)
+		}
+
+		v, err := cp.GetCursor(ctx)
+
+		if err != nil {
+			t.Fatalf("Failed to get cursor, %v", err)
+		}
+
+		if v != cursor {
+			t.Fatalf("Expected cursor %d, got %d", cursor, v)
+		}
+	}
+}
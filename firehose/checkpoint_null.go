@@ -0,0 +1,39 @@
+package firehose
+
+import (
+	"context"
+
+	"github.com/sfomuseum/go-atproto"
+)
+
+// NullCheckpoint implements the `Checkpoint` interface but does not store anything.
+type NullCheckpoint struct {
+	Checkpoint
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterCheckpoint(ctx, "null", NewNullCheckpoint)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func NewNullCheckpoint(ctx context.Context, uri string) (Checkpoint, error) {
+	cp := &NullCheckpoint{}
+	return cp, nil
+}
+
+func (cp *NullCheckpoint) GetCursor(ctx context.Context) (int64, error) {
+	return 0, atproto.ErrNotFound
+}
+
+func (cp *NullCheckpoint) SetCursor(ctx context.Context, cursor int64) error {
+	return nil
+}
+
+func (cp *NullCheckpoint) Close() error {
+	return nil
+}
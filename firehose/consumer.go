@@ -0,0 +1,328 @@
+package firehose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/identity"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/gorilla/websocket"
+	"github.com/sfomuseum/go-atproto"
+)
+
+const SUBSCRIBE_REPOS_PATH string = "/xrpc/com.atproto.sync.subscribeRepos"
+
+// The default interval at which the cursor is stored by a consumer's `Checkpoint`.
+const DEFAULT_CHECKPOINT_INTERVAL time.Duration = 5 * time.Second
+
+// The default amount of time to wait before reconnecting to a stream. This is doubled after each failed attempt.
+const DEFAULT_RECONNECT_BACKOFF time.Duration = 1 * time.Second
+
+// The maximum amount of time to wait before reconnecting to a stream.
+const MAX_RECONNECT_BACKOFF time.Duration = 1 * time.Minute
+
+// The maximum amount of time to wait for a message (or ping) before a connection is considered dead.
+const READ_TIMEOUT time.Duration = 2 * time.Minute
+
+const USER_AGENT string = "sfomuseum/go-atproto"
+
+// EventHandler is a function which is called for every event read from a stream. If it returns an error the
+// consumer stops and returns that error.
+type EventHandler func(context.Context, *Event) error
+
+// Consumer reads, and decodes, events from a com.atproto.sync.subscribeRepos event stream reconnecting (from the
+// last processed event) if the connection is lost.
+type Consumer struct {
+	// The URI of the host to read events from. This may be a URL or a hostname, in which case "wss" is assumed.
+	Host string
+	// The `Checkpoint` used to store the cursor of the last processed event.
+	Checkpoint Checkpoint
+	// The interval at which the cursor is stored by `Checkpoint`.
+	CheckpointInterval time.Duration
+	// The cursor to start from if `Checkpoint` has not stored a cursor. If negative only new events are read.
+	StartCursor int64
+	// If true the signatures of commits are verified, see `VerifyCommit`. Commits which fail verification are
+	// still passed to the `EventHandler` with `Commit.Verified` set to false.
+	Verify bool
+	// The `identity.Directory` used to verify commits. If nil then `identity.DefaultDirectory` is used.
+	Directory identity.Directory
+	Dialer    *websocket.Dialer
+}
+
+// handlerError wraps errors returned by an `EventHandler` so they can be distinguished from connection errors.
+type handlerError struct {
+	err error
+}
+
+func (e *handlerError) Error() string {
+	return e.err.Error()
+}
+
+// NewConsumer returns a new `Consumer` instance for 'host' which stores its cursor using 'cp'.
+func NewConsumer(host string, cp Checkpoint) *Consumer {
+
+	c := &Consumer{
+		Host:               host,
+		Checkpoint:         cp,
+		CheckpointInterval: DEFAULT_CHECKPOINT_INTERVAL,
+		StartCursor:        -1,
+		Dialer:             websocket.DefaultDialer,
+	}
+
+	return c
+}
+
+// Consume reads events from the consumer's host, passing each one to 'handler', until 'ctx' is cancelled, 'handler'
+// returns an error or the host sends an error which is not recoverable (for example "FutureCursor"). Dropped connections
+// are reconnected (with an exponential backoff) starting from the last processed event.
+func (c *Consumer) Consume(ctx context.Context, handler EventHandler) error {
+
+	if c.Directory == nil && c.Verify {
+		c.Directory = identity.DefaultDirectory()
+	}
+
+	cursor, err := c.Checkpoint.GetCursor(ctx)
+
+	if err != nil {
+
+		if err != atproto.ErrNotFound {
+			return fmt.Errorf("Failed to retrieve cursor, %w", err)
+		}
+
+		cursor = c.StartCursor
+	}
+
+	logger := slog.Default()
+	logger = logger.With("host", c.Host)
+
+	backoff := DEFAULT_RECONNECT_BACKOFF
+
+	for {
+
+		start := cursor
+		err := c.consume(ctx, &cursor, handler)
+
+		// Always store the last processed event, regardless of why the connection ended
+
+		if cursor >= 0 {
+
+			err := c.Checkpoint.SetCursor(ctx, cursor)
+
+			if err != nil {
+				logger.Error("Failed to store cursor", "cursor", cursor, "error", err)
+			}
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var h_err *handlerError
+
+		if errors.As(err, &h_err) {
+			return h_err.err
+		}
+
+		var s_err *StreamError
+
+		if errors.As(err, &s_err) && s_err.Name == "FutureCursor" {
+			return err
+		}
+
+		if cursor != start {
+			backoff = DEFAULT_RECONNECT_BACKOFF
+		}
+
+		logger.Warn("Connection lost, reconnecting", "cursor", cursor, "backoff", backoff, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+			// pass
+		}
+
+		backoff = min(backoff*2, MAX_RECONNECT_BACKOFF)
+	}
+}
+
+// consume reads events from a single connection to the consumer's host, updating 'cursor' after each event has been
+// handled, until the connection ends.
+func (c *Consumer) consume(ctx context.Context, cursor *int64, handler EventHandler) error {
+
+	stream_uri, err := c.streamURL(*cursor)
+
+	if err != nil {
+		return err
+	}
+
+	headers := http.Header{}
+	headers.Set("User-Agent", USER_AGENT)
+
+	conn, _, err := c.Dialer.DialContext(ctx, stream_uri, headers)
+
+	if err != nil {
+		return fmt.Errorf("Failed to connect to %s, %w", stream_uri, err)
+	}
+
+	defer conn.Close()
+
+	logger := slog.Default()
+	logger = logger.With("host", c.Host)
+
+	logger.Debug("Connected", "uri", stream_uri)
+
+	// Closing the connection is the only way to interrupt a blocking read
+
+	done := make(chan bool)
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+			// pass
+		}
+	}()
+
+	conn.SetPingHandler(func(data string) error {
+		conn.SetReadDeadline(time.Now().Add(READ_TIMEOUT))
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(10*time.Second))
+	})
+
+	last_checkpoint := time.Now()
+
+	for {
+
+		conn.SetReadDeadline(time.Now().Add(READ_TIMEOUT))
+
+		msg_type, msg, err := conn.ReadMessage()
+
+		if err != nil {
+			return fmt.Errorf("Failed to read message, %w", err)
+		}
+
+		if msg_type != websocket.BinaryMessage {
+			continue
+		}
+
+		f, err := ParseFrame(msg)
+
+		if err != nil {
+			return err
+		}
+
+		e, err := DecodeEvent(f)
+
+		if err != nil {
+			// Skip events which can't be decoded rather than reconnecting and reading them again
+			logger.Error("Failed to decode event", "type", f.Type, "error", err)
+			continue
+		}
+
+		if e.Type == EVENT_INFO {
+			name, _ := e.Body["name"].(string)
+			message, _ := e.Body["message"].(string)
+			logger.Info("Received info message", "name", name, "message", message)
+		}
+
+		if c.Verify {
+			c.verify(ctx, e)
+		}
+
+		err = handler(ctx, e)
+
+		if err != nil {
+			return &handlerError{err: err}
+		}
+
+		if e.Seq > 0 {
+			*cursor = e.Seq
+		}
+
+		if time.Since(last_checkpoint) >= c.CheckpointInterval {
+
+			err := c.Checkpoint.SetCursor(ctx, *cursor)
+
+			if err != nil {
+				logger.Error("Failed to store cursor", "cursor", *cursor, "error", err)
+			}
+
+			last_checkpoint = time.Now()
+		}
+	}
+}
+
+// verify verifies the commit for "#commit" events and purges any cached identity for "#identity" events
+// so that subsequent commits are verified with the account's current signing key.
+func (c *Consumer) verify(ctx context.Context, e *Event) {
+
+	switch e.Type {
+	case EVENT_COMMIT:
+
+		err := VerifyCommit(ctx, c.Directory, e)
+
+		if err != nil {
+			slog.Warn("Failed to verify commit", "seq", e.Seq, "did", e.DID, "error", err)
+		}
+
+	case EVENT_IDENTITY:
+
+		atid, err := syntax.ParseAtIdentifier(e.DID)
+
+		if err != nil {
+			return
+		}
+
+		err = c.Directory.Purge(ctx, *atid)
+
+		if err != nil {
+			slog.Warn("Failed to purge identity", "did", e.DID, "error", err)
+		}
+	}
+}
+
+func (c *Consumer) streamURL(cursor int64) (string, error) {
+
+	host := c.Host
+
+	if !strings.Contains(host, "://") {
+		host = fmt.Sprintf("wss://%s", host)
+	}
+
+	u, err := url.Parse(host)
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to parse host URI, %w", err)
+	}
+
+	if u.Host == "" {
+		return "", fmt.Errorf("Invalid host URI, missing host")
+	}
+
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	}
+
+	u.Path = SUBSCRIBE_REPOS_PATH
+	u.RawQuery = ""
+
+	if cursor >= 0 {
+		q := url.Values{}
+		q.Set("cursor", strconv.FormatInt(cursor, 10))
+		u.RawQuery = q.Encode()
+	}
+
+	return u.String(), nil
+}
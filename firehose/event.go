@@ -0,0 +1,255 @@
+package firehose
+
+// https://atproto.com/specs/sync#firehose
+// https://docs.bsky.app/docs/api/com-atproto-sync-subscribe-repos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/bluesky-social/indigo/atproto/data"
+	"github.com/bluesky-social/indigo/atproto/identity"
+	"github.com/ipfs/go-cid"
+	"github.com/sfomuseum/go-atproto/car"
+	"github.com/sfomuseum/go-atproto/dagcbor"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+const EVENT_COMMIT string = "#commit"
+const EVENT_SYNC string = "#sync"
+const EVENT_IDENTITY string = "#identity"
+const EVENT_ACCOUNT string = "#account"
+const EVENT_INFO string = "#info"
+
+// Event is a decoded message from a com.atproto.sync.subscribeRepos event stream.
+type Event struct {
+	// The sequence number of the event. Zero for "#info" events.
+	Seq  int64  `json:"seq,omitempty"`
+	Type string `json:"type"`
+	// The DID of the repository (account) the event is for. Empty for "#info" events.
+	DID  string `json:"did,omitempty"`
+	Time string `json:"time,omitempty"`
+	// The (atproto data model) body of the event. For "#commit" events the "blocks" and "ops" properties
+	// are omitted in favour of `Commit`.
+	Body map[string]any `json:"body"`
+	// The decoded commit for "#commit" events.
+	Commit *Commit `json:"commit,omitempty"`
+}
+
+// Commit is the decoded commit included in a "#commit" event.
+type Commit struct {
+	// The CID of the signed commit object.
+	CID   string `json:"cid"`
+	Rev   string `json:"rev"`
+	Since string `json:"since,omitempty"`
+	// Whether the commit was too big to include all of its blocks. Deprecated but still sent by some servers.
+	TooBig bool  `json:"tooBig,omitempty"`
+	Ops    []*Op `json:"ops"`
+	// Whether the commit's signature has been verified, see `VerifyCommit`.
+	Verified bool `json:"verified"`
+	blocks   map[cid.Cid][]byte
+}
+
+// Op is an individual record operation included in a commit.
+type Op struct {
+	Action string `json:"action"`
+	// The "{COLLECTION}/{RKEY}" path of the record.
+	Path string `json:"path"`
+	// The CID of the new record. Empty for delete operations.
+	CID string `json:"cid,omitempty"`
+	// The (atproto data model) value of the new record, if its block was included in the commit.
+	Record map[string]any `json:"record,omitempty"`
+}
+
+// Block returns the DAG-CBOR encoded data for the block identified by 'c' if it was included in the commit.
+func (c *Commit) Block(block_cid cid.Cid) ([]byte, bool) {
+	b, ok := c.blocks[block_cid]
+	return b, ok
+}
+
+// DecodeEvent decodes the body of 'f' as a com.atproto.sync.subscribeRepos event. Events of types which
+// are not known are decoded with a generic body.
+func DecodeEvent(f *Frame) (*Event, error) {
+
+	body, err := data.UnmarshalCBOR(f.Body)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode %s event, %w", f.Type, err)
+	}
+
+	e := &Event{
+		Type: f.Type,
+		Body: body,
+	}
+
+	seq, ok := body["seq"].(int64)
+
+	if ok {
+		e.Seq = seq
+	}
+
+	e.Time, _ = body["time"].(string)
+
+	switch f.Type {
+	case EVENT_COMMIT:
+
+		e.DID, _ = body["repo"].(string)
+
+		c, err := decodeCommit(f.Body)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to decode commit for event %d, %w", e.Seq, err)
+		}
+
+		e.Commit = c
+
+		delete(body, "blocks")
+		delete(body, "ops")
+
+	default:
+		e.DID, _ = body["did"].(string)
+	}
+
+	return e, nil
+}
+
+// VerifyCommit ensures that the commit for 'e', which must be a "#commit" event, was signed by the current signing
+// key for the event's DID, as resolved by 'dir', and assigns the result to `Commit.Verified`. If the signature is not
+// valid an error wrapping `atproto.ErrInvalidSignature` is returned.
+func VerifyCommit(ctx context.Context, dir identity.Directory, e *Event) error {
+
+	if e.Commit == nil {
+		return fmt.Errorf("Event is not a commit")
+	}
+
+	e.Commit.Verified = false
+
+	commit_cid, err := cid.Decode(e.Commit.CID)
+
+	if err != nil {
+		return fmt.Errorf("Invalid commit CID, %w", err)
+	}
+
+	b, exists := e.Commit.Block(commit_cid)
+
+	if !exists {
+		return fmt.Errorf("Missing block for commit %s", commit_cid)
+	}
+
+	c, err := pds.DecodeCommit(b)
+
+	if err != nil {
+		return err
+	}
+
+	if c.DID != e.DID {
+		return fmt.Errorf("Commit is for %s not %s", c.DID, e.DID)
+	}
+
+	if c.Rev != e.Commit.Rev {
+		return fmt.Errorf("Commit revision %s does not match event revision %s", c.Rev, e.Commit.Rev)
+	}
+
+	err = pds.VerifyCommitForDID(ctx, dir, c)
+
+	if err != nil {
+		return err
+	}
+
+	e.Commit.Verified = true
+	return nil
+}
+
+func decodeCommit(b []byte) (*Commit, error) {
+
+	var body map[string]any
+
+	err := dagcbor.Unmarshal(b, &body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	commit_cid, ok := body["commit"].(cid.Cid)
+
+	if !ok {
+		return nil, fmt.Errorf("Missing commit CID")
+	}
+
+	c := &Commit{
+		CID:    commit_cid.String(),
+		Ops:    make([]*Op, 0),
+		blocks: make(map[cid.Cid][]byte),
+	}
+
+	c.Rev, _ = body["rev"].(string)
+	c.Since, _ = body["since"].(string)
+	c.TooBig, _ = body["tooBig"].(bool)
+
+	car_body, _ := body["blocks"].([]byte)
+
+	if len(car_body) > 0 {
+
+		car_r, err := car.NewReader(bytes.NewReader(car_body))
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read blocks, %w", err)
+		}
+
+		for {
+
+			block_cid, block_data, err := car_r.NextBlock()
+
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("Failed to read block, %w", err)
+			}
+
+			c.blocks[block_cid] = block_data
+		}
+	}
+
+	ops, _ := body["ops"].([]any)
+
+	for i, v := range ops {
+
+		obj, ok := v.(map[string]any)
+
+		if !ok {
+			return nil, fmt.Errorf("Invalid op at offset %d", i)
+		}
+
+		op := &Op{}
+		op.Action, _ = obj["action"].(string)
+		op.Path, _ = obj["path"].(string)
+
+		op_cid, ok := obj["cid"].(cid.Cid)
+
+		if ok {
+
+			op.CID = op_cid.String()
+
+			rec_body, exists := c.blocks[op_cid]
+
+			if exists {
+
+				rec, err := data.UnmarshalCBOR(rec_body)
+
+				if err != nil {
+					return nil, fmt.Errorf("Failed to decode record %s, %w", op.Path, err)
+				}
+
+				op.Record = rec
+			}
+		}
+
+		c.Ops = append(c.Ops, op)
+	}
+
+	return c, nil
+}
@@ -0,0 +1,118 @@
+package firehose
+
+// https://atproto.com/specs/event-stream
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/sfomuseum/go-atproto/dagcbor"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+// The header "op" value for regular messages.
+const OP_MESSAGE int64 = 1
+
+// The header "op" value for error messages.
+const OP_ERROR int64 = -1
+
+// Frame is a single (binary) message read from an event stream, split in to its header and (DAG-CBOR encoded) body.
+type Frame struct {
+	Op int64
+	// The message type (for example "#commit"). Empty for error frames.
+	Type string
+	Body []byte
+}
+
+// StreamError is an error sent by the server in an error frame, after which the server closes the connection.
+type StreamError struct {
+	Name    string
+	Message string
+}
+
+func (e *StreamError) Error() string {
+
+	if e.Message == "" {
+		return e.Name
+	}
+
+	return fmt.Sprintf("%s: %s", e.Name, e.Message)
+}
+
+// ParseFrame splits 'b' in to its DAG-CBOR encoded header and body and decodes the header. If 'b' is an error frame a
+// `StreamError` is returned as the error.
+func ParseFrame(b []byte) (*Frame, error) {
+
+	// Frames are two concatenated DAG-CBOR objects so the header is read as a single (undecoded) item
+	// and whatever remains is the body
+
+	r := bytes.NewReader(b)
+
+	var raw_header cbg.Deferred
+
+	err := raw_header.UnmarshalCBOR(r)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read frame header, %w", err)
+	}
+
+	body := b[len(b)-r.Len():]
+
+	if len(body) == 0 {
+		return nil, fmt.Errorf("Frame is missing body")
+	}
+
+	var header map[string]any
+
+	err = dagcbor.Unmarshal(raw_header.Raw, &header)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode frame header, %w", err)
+	}
+
+	op, ok := header["op"].(int)
+
+	if !ok {
+		return nil, fmt.Errorf("Invalid frame header, missing op")
+	}
+
+	f := &Frame{
+		Op:   int64(op),
+		Body: body,
+	}
+
+	switch f.Op {
+	case OP_MESSAGE:
+
+		t, ok := header["t"].(string)
+
+		if !ok {
+			return nil, fmt.Errorf("Invalid frame header, missing message type")
+		}
+
+		f.Type = t
+
+	case OP_ERROR:
+
+		var obj map[string]any
+
+		err := dagcbor.Unmarshal(body, &obj)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to decode error frame, %w", err)
+		}
+
+		name, _ := obj["error"].(string)
+		message, _ := obj["message"].(string)
+
+		return nil, &StreamError{
+			Name:    name,
+			Message: message,
+		}
+
+	default:
+		return nil, fmt.Errorf("Invalid frame header, unsupported op (%d)", f.Op)
+	}
+
+	return f, nil
+}
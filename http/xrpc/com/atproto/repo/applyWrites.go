@@ -0,0 +1,245 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aaronland/go-http/v3/slog"
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/auth"
+	"github.com/sfomuseum/go-atproto/http/xrpc"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+const ApplyWritesHandlerURI string = "/xrpc/com.atproto.repo.applyWrites"
+const ApplyWritesHandlerMethod string = http.MethodPost
+
+// The maximum size (in bytes) of an applyWrites request body.
+const MAX_APPLY_WRITES_SIZE int64 = 10 * 1024 * 1024
+
+const APPLY_WRITES_CREATE string = "com.atproto.repo.applyWrites#create"
+const APPLY_WRITES_UPDATE string = "com.atproto.repo.applyWrites#update"
+const APPLY_WRITES_DELETE string = "com.atproto.repo.applyWrites#delete"
+
+type ApplyWritesWrite struct {
+	Type       string          `json:"$type"`
+	Collection string          `json:"collection"`
+	RKey       string          `json:"rkey,omitempty"`
+	Value      json.RawMessage `json:"value,omitempty"`
+	SwapRecord string          `json:"swapRecord,omitempty"`
+}
+
+type ApplyWritesRequest struct {
+	Repo       string              `json:"repo"`
+	Validate   *bool               `json:"validate,omitempty"`
+	Writes     []*ApplyWritesWrite `json:"writes"`
+	SwapCommit string              `json:"swapCommit,omitempty"`
+}
+
+type ApplyWritesResult struct {
+	Type             string `json:"$type"`
+	URI              string `json:"uri,omitempty"`
+	CID              string `json:"cid,omitempty"`
+	ValidationStatus string `json:"validationStatus,omitempty"`
+}
+
+type ApplyWritesResponse struct {
+	Commit  *CommitMeta          `json:"commit,omitempty"`
+	Results []*ApplyWritesResult `json:"results"`
+}
+
+type ApplyWritesHandlerOptions struct {
+	AccountsDatabase pds.AccountsDatabase
+	RecordsDatabase  pds.RecordsDatabase
+	KeysDatabase     pds.KeysDatabase
+	CommitsDatabase  pds.CommitsDatabase
+	BlocksDatabase   pds.BlocksDatabase
+	Authenticator    auth.Authenticator
+	Sequencer        *pds.Sequencer
+	// An optional `pds.BlobsDatabase` to record the blobs referenced by each record.
+	BlobsDatabase pds.BlobsDatabase
+}
+
+func ApplyWritesHandler(opts *ApplyWritesHandlerOptions) (http.Handler, error) {
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		logger := slog.LoggerWithRequest(req, nil)
+
+		if req.Method != ApplyWritesHandlerMethod {
+			logger.Error("Method not allowed", "method", req.Method)
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		auth_did, err := opts.Authenticator.GetDIDForRequest(req)
+
+		if err != nil {
+			logger.Error("Failed to authenticate request", "error", err)
+			http.Error(rsp, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		logger = logger.With("auth", auth_did)
+
+		var apply_req *ApplyWritesRequest
+
+		body := http.MaxBytesReader(rsp, req.Body, MAX_APPLY_WRITES_SIZE)
+		dec := json.NewDecoder(body)
+
+		err = dec.Decode(&apply_req)
+
+		if err != nil {
+			logger.Error("Failed to decode request", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if apply_req.Repo == "" {
+			logger.Error("Missing parameter", "parameter", "repo")
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("repo", apply_req.Repo)
+
+		writes := make([]*pds.Write, len(apply_req.Writes))
+
+		for i, w := range apply_req.Writes {
+
+			var action string
+
+			switch w.Type {
+			case APPLY_WRITES_CREATE:
+				action = pds.RECORD_WRITE_CREATE
+			case APPLY_WRITES_UPDATE:
+				action = pds.RECORD_WRITE_UPDATE
+			case APPLY_WRITES_DELETE:
+				action = pds.RECORD_WRITE_DELETE
+			default:
+				logger.Error("Invalid write type", "offset", i, "type", w.Type)
+				xrpc.Error(rsp, "InvalidRequest", fmt.Sprintf("Invalid write type at offset %d", i), http.StatusBadRequest)
+				return
+			}
+
+			if action != pds.RECORD_WRITE_DELETE && len(w.Value) == 0 {
+				logger.Error("Missing value", "offset", i)
+				xrpc.Error(rsp, "InvalidRequest", fmt.Sprintf("Missing value at offset %d", i), http.StatusBadRequest)
+				return
+			}
+
+			writes[i] = &pds.Write{
+				Action:     action,
+				Collection: w.Collection,
+				RKey:       w.RKey,
+				Value:      string(w.Value),
+				SwapRecord: w.SwapRecord,
+			}
+		}
+
+		ctx := req.Context()
+
+		acct, err := pds.GetAccountWithIdentifier(ctx, opts.AccountsDatabase, apply_req.Repo)
+
+		if err != nil {
+
+			if err == atproto.ErrNotFound {
+				logger.Error("Account not found")
+				http.Error(rsp, "Not found", http.StatusNotFound)
+			} else {
+				logger.Error("Failed to retrieve account", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		if acct.Deleted != 0 {
+			logger.Error("Account has been deleted")
+			http.Error(rsp, "Not found", http.StatusNotFound)
+			return
+		}
+
+		if acct.DID != auth_did {
+			logger.Error("Authenticated account does not match repo", "did", acct.DID)
+			http.Error(rsp, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		apply_opts := &pds.ApplyWritesOptions{
+			RecordsDatabase: opts.RecordsDatabase,
+			KeysDatabase:    opts.KeysDatabase,
+			CommitsDatabase: opts.CommitsDatabase,
+			BlocksDatabase:  opts.BlocksDatabase,
+			SwapCommit:      apply_req.SwapCommit,
+			Sequencer:       opts.Sequencer,
+			BlobsDatabase:   opts.BlobsDatabase,
+		}
+
+		results, commit, err := pds.ApplyWrites(ctx, apply_opts, acct.DID, writes)
+
+		if err != nil {
+
+			switch {
+			case errors.Is(err, atproto.ErrInvalidSwap):
+				logger.Error("Invalid swap", "error", err)
+				xrpc.Error(rsp, "InvalidSwap", err.Error(), http.StatusBadRequest)
+			case errors.Is(err, atproto.ErrInvalidWrite):
+				logger.Error("Invalid write", "error", err)
+				xrpc.Error(rsp, "InvalidRequest", err.Error(), http.StatusBadRequest)
+			default:
+				logger.Error("Failed to apply writes", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		apply_rsp := ApplyWritesResponse{
+			Commit: &CommitMeta{
+				CID: commit.CID,
+				Rev: commit.Rev,
+			},
+			Results: make([]*ApplyWritesResult, len(results)),
+		}
+
+		for i, r := range results {
+
+			var result *ApplyWritesResult
+
+			switch r.Action {
+			case pds.RECORD_WRITE_DELETE:
+
+				result = &ApplyWritesResult{
+					Type: "com.atproto.repo.applyWrites#deleteResult",
+				}
+
+			default:
+
+				result = &ApplyWritesResult{
+					Type:             fmt.Sprintf("com.atproto.repo.applyWrites#%sResult", r.Action),
+					URI:              r.URI,
+					CID:              r.CID,
+					ValidationStatus: "unknown",
+				}
+			}
+
+			apply_rsp.Results[i] = result
+		}
+
+		rsp.Header().Set("Content-type", "application/json")
+
+		enc := json.NewEncoder(rsp)
+		err = enc.Encode(apply_rsp)
+
+		if err != nil {
+			logger.Error("Failed to encode response", "error", err)
+			http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	return http.HandlerFunc(fn), nil
+}
@@ -0,0 +1,229 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/aaronland/go-http/v3/slog"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/auth"
+	"github.com/sfomuseum/go-atproto/http/xrpc"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+const CreateRecordHandlerURI string = "/xrpc/com.atproto.repo.createRecord"
+const CreateRecordHandlerMethod string = http.MethodPost
+
+// The maximum size (in bytes) of a createRecord request body.
+const MAX_CREATE_RECORD_SIZE int64 = 2 * 1024 * 1024
+
+type CreateRecordRequest struct {
+	Repo       string          `json:"repo"`
+	Collection string          `json:"collection"`
+	RKey       string          `json:"rkey,omitempty"`
+	Validate   *bool           `json:"validate,omitempty"`
+	Record     json.RawMessage `json:"record"`
+	SwapCommit string          `json:"swapCommit,omitempty"`
+}
+
+type CreateRecordResponse struct {
+	URI              string      `json:"uri"`
+	CID              string      `json:"cid"`
+	Commit           *CommitMeta `json:"commit,omitempty"`
+	ValidationStatus string      `json:"validationStatus,omitempty"`
+}
+
+type CreateRecordHandlerOptions struct {
+	AccountsDatabase pds.AccountsDatabase
+	RecordsDatabase  pds.RecordsDatabase
+	KeysDatabase     pds.KeysDatabase
+	CommitsDatabase  pds.CommitsDatabase
+	BlocksDatabase   pds.BlocksDatabase
+	Authenticator    auth.Authenticator
+	Sequencer        *pds.Sequencer
+	// An optional `pds.BlobsDatabase` to record the blobs referenced by each record.
+	BlobsDatabase pds.BlobsDatabase
+}
+
+func CreateRecordHandler(opts *CreateRecordHandlerOptions) (http.Handler, error) {
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		logger := slog.LoggerWithRequest(req, nil)
+
+		if req.Method != CreateRecordHandlerMethod {
+			logger.Error("Method not allowed", "method", req.Method)
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		auth_did, err := opts.Authenticator.GetDIDForRequest(req)
+
+		if err != nil {
+			logger.Error("Failed to authenticate request", "error", err)
+			http.Error(rsp, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		logger = logger.With("auth", auth_did)
+
+		var create_req *CreateRecordRequest
+
+		body := http.MaxBytesReader(rsp, req.Body, MAX_CREATE_RECORD_SIZE)
+		dec := json.NewDecoder(body)
+
+		err = dec.Decode(&create_req)
+
+		if err != nil {
+			logger.Error("Failed to decode request", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if create_req.Repo == "" {
+			logger.Error("Missing parameter", "parameter", "repo")
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("repo", create_req.Repo)
+
+		_, err = syntax.ParseNSID(create_req.Collection)
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "collection", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("collection", create_req.Collection)
+
+		if create_req.RKey != "" {
+
+			_, err = syntax.ParseRecordKey(create_req.RKey)
+
+			if err != nil {
+				logger.Error("Invalid parameter", "parameter", "rkey", "error", err)
+				http.Error(rsp, "Bad request", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if len(create_req.Record) == 0 {
+			logger.Error("Missing parameter", "parameter", "record")
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		ctx := req.Context()
+
+		acct, err := pds.GetAccountWithIdentifier(ctx, opts.AccountsDatabase, create_req.Repo)
+
+		if err != nil {
+
+			if err == atproto.ErrNotFound {
+				logger.Error("Account not found")
+				http.Error(rsp, "Not found", http.StatusNotFound)
+			} else {
+				logger.Error("Failed to retrieve account", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		if acct.Deleted != 0 {
+			logger.Error("Account has been deleted")
+			http.Error(rsp, "Not found", http.StatusNotFound)
+			return
+		}
+
+		if acct.DID != auth_did {
+			logger.Error("Authenticated account does not match repo", "did", acct.DID)
+			http.Error(rsp, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		// If no record key is specified pds.ApplyWrites will assign a new TID
+
+		if create_req.RKey != "" {
+
+			_, err := pds.GetRecord(ctx, opts.RecordsDatabase, acct.DID, create_req.Collection, create_req.RKey)
+
+			if err == nil {
+				logger.Error("Record already exists", "rkey", create_req.RKey)
+				http.Error(rsp, "Conflict", http.StatusConflict)
+				return
+			}
+
+			if err != atproto.ErrNotFound {
+				logger.Error("Failed to retrieve record", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		writes := []*pds.Write{
+			&pds.Write{
+				Action:     pds.RECORD_WRITE_CREATE,
+				Collection: create_req.Collection,
+				RKey:       create_req.RKey,
+				Value:      string(create_req.Record),
+			},
+		}
+
+		apply_opts := &pds.ApplyWritesOptions{
+			RecordsDatabase: opts.RecordsDatabase,
+			KeysDatabase:    opts.KeysDatabase,
+			CommitsDatabase: opts.CommitsDatabase,
+			BlocksDatabase:  opts.BlocksDatabase,
+			SwapCommit:      create_req.SwapCommit,
+			Sequencer:       opts.Sequencer,
+			BlobsDatabase:   opts.BlobsDatabase,
+		}
+
+		results, commit, err := pds.ApplyWrites(ctx, apply_opts, acct.DID, writes)
+
+		if err != nil {
+
+			switch {
+			case errors.Is(err, atproto.ErrInvalidSwap):
+				logger.Error("Invalid swap", "error", err)
+				xrpc.Error(rsp, "InvalidSwap", err.Error(), http.StatusBadRequest)
+			case errors.Is(err, atproto.ErrInvalidWrite):
+				logger.Error("Invalid write", "error", err)
+				xrpc.Error(rsp, "InvalidRequest", err.Error(), http.StatusBadRequest)
+			default:
+				logger.Error("Failed to apply writes", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		create_rsp := CreateRecordResponse{
+			URI: results[0].URI,
+			CID: results[0].CID,
+			Commit: &CommitMeta{
+				CID: commit.CID,
+				Rev: commit.Rev,
+			},
+			ValidationStatus: "unknown",
+		}
+
+		rsp.Header().Set("Content-type", "application/json")
+
+		enc := json.NewEncoder(rsp)
+		err = enc.Encode(create_rsp)
+
+		if err != nil {
+			logger.Error("Failed to encode response", "error", err)
+			http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	return http.HandlerFunc(fn), nil
+}
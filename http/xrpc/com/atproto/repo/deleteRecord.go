@@ -0,0 +1,229 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/aaronland/go-http/v3/slog"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/auth"
+	"github.com/sfomuseum/go-atproto/http/xrpc"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+const DeleteRecordHandlerURI string = "/xrpc/com.atproto.repo.deleteRecord"
+const DeleteRecordHandlerMethod string = http.MethodPost
+
+// The maximum size (in bytes) of a deleteRecord request body.
+const MAX_DELETE_RECORD_SIZE int64 = 64 * 1024
+
+type DeleteRecordRequest struct {
+	Repo       string `json:"repo"`
+	Collection string `json:"collection"`
+	RKey       string `json:"rkey"`
+	SwapRecord string `json:"swapRecord,omitempty"`
+	SwapCommit string `json:"swapCommit,omitempty"`
+}
+
+type DeleteRecordResponse struct {
+	Commit *CommitMeta `json:"commit,omitempty"`
+}
+
+type DeleteRecordHandlerOptions struct {
+	AccountsDatabase pds.AccountsDatabase
+	RecordsDatabase  pds.RecordsDatabase
+	KeysDatabase     pds.KeysDatabase
+	CommitsDatabase  pds.CommitsDatabase
+	BlocksDatabase   pds.BlocksDatabase
+	Authenticator    auth.Authenticator
+	Sequencer        *pds.Sequencer
+	// An optional `pds.BlobsDatabase` to record the blobs referenced by each record.
+	BlobsDatabase pds.BlobsDatabase
+}
+
+func DeleteRecordHandler(opts *DeleteRecordHandlerOptions) (http.Handler, error) {
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		logger := slog.LoggerWithRequest(req, nil)
+
+		if req.Method != DeleteRecordHandlerMethod {
+			logger.Error("Method not allowed", "method", req.Method)
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		auth_did, err := opts.Authenticator.GetDIDForRequest(req)
+
+		if err != nil {
+			logger.Error("Failed to authenticate request", "error", err)
+			http.Error(rsp, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		logger = logger.With("auth", auth_did)
+
+		var delete_req *DeleteRecordRequest
+
+		body := http.MaxBytesReader(rsp, req.Body, MAX_DELETE_RECORD_SIZE)
+		dec := json.NewDecoder(body)
+
+		err = dec.Decode(&delete_req)
+
+		if err != nil {
+			logger.Error("Failed to decode request", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if delete_req.Repo == "" {
+			logger.Error("Missing parameter", "parameter", "repo")
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("repo", delete_req.Repo)
+
+		_, err = syntax.ParseNSID(delete_req.Collection)
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "collection", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("collection", delete_req.Collection)
+
+		_, err = syntax.ParseRecordKey(delete_req.RKey)
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "rkey", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("rkey", delete_req.RKey)
+
+		ctx := req.Context()
+
+		acct, err := pds.GetAccountWithIdentifier(ctx, opts.AccountsDatabase, delete_req.Repo)
+
+		if err != nil {
+
+			if err == atproto.ErrNotFound {
+				logger.Error("Account not found")
+				http.Error(rsp, "Not found", http.StatusNotFound)
+			} else {
+				logger.Error("Failed to retrieve account", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		if acct.Deleted != 0 {
+			logger.Error("Account has been deleted")
+			http.Error(rsp, "Not found", http.StatusNotFound)
+			return
+		}
+
+		if acct.DID != auth_did {
+			logger.Error("Authenticated account does not match repo", "did", acct.DID)
+			http.Error(rsp, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		rec, err := pds.GetRecord(ctx, opts.RecordsDatabase, acct.DID, delete_req.Collection, delete_req.RKey)
+
+		if err != nil && err != atproto.ErrNotFound {
+			logger.Error("Failed to retrieve record", "error", err)
+			http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		err = pds.CheckSwapRecord(rec, delete_req.SwapRecord)
+
+		if err != nil {
+			logger.Error("Invalid swap", "error", err)
+			xrpc.Error(rsp, "InvalidSwap", err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		delete_rsp := DeleteRecordResponse{}
+
+		// Deleting a record that does not exist is not an error but does not produce a new commit
+
+		if rec == nil {
+
+			rsp.Header().Set("Content-type", "application/json")
+
+			enc := json.NewEncoder(rsp)
+			err = enc.Encode(delete_rsp)
+
+			if err != nil {
+				logger.Error("Failed to encode response", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		writes := []*pds.Write{
+			&pds.Write{
+				Action:     pds.RECORD_WRITE_DELETE,
+				Collection: delete_req.Collection,
+				RKey:       delete_req.RKey,
+				SwapRecord: delete_req.SwapRecord,
+			},
+		}
+
+		apply_opts := &pds.ApplyWritesOptions{
+			RecordsDatabase: opts.RecordsDatabase,
+			KeysDatabase:    opts.KeysDatabase,
+			CommitsDatabase: opts.CommitsDatabase,
+			BlocksDatabase:  opts.BlocksDatabase,
+			SwapCommit:      delete_req.SwapCommit,
+			Sequencer:       opts.Sequencer,
+			BlobsDatabase:   opts.BlobsDatabase,
+		}
+
+		_, commit, err := pds.ApplyWrites(ctx, apply_opts, acct.DID, writes)
+
+		if err != nil {
+
+			switch {
+			case errors.Is(err, atproto.ErrInvalidSwap):
+				logger.Error("Invalid swap", "error", err)
+				xrpc.Error(rsp, "InvalidSwap", err.Error(), http.StatusBadRequest)
+			case errors.Is(err, atproto.ErrInvalidWrite):
+				logger.Error("Invalid write", "error", err)
+				xrpc.Error(rsp, "InvalidRequest", err.Error(), http.StatusBadRequest)
+			default:
+				logger.Error("Failed to apply writes", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		delete_rsp.Commit = &CommitMeta{
+			CID: commit.CID,
+			Rev: commit.Rev,
+		}
+
+		rsp.Header().Set("Content-type", "application/json")
+
+		enc := json.NewEncoder(rsp)
+		err = enc.Encode(delete_rsp)
+
+		if err != nil {
+			logger.Error("Failed to encode response", "error", err)
+			http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	return http.HandlerFunc(fn), nil
+}
@@ -0,0 +1,139 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/aaronland/go-http/v3/sanitize"
+	"github.com/aaronland/go-http/v3/slog"
+	"github.com/did-method-plc/go-didplc"
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+const DescribeRepoHandlerURI string = "/xrpc/com.atproto.repo.describeRepo"
+const DescribeRepoHandlerMethod string = http.MethodGet
+
+type DescribeRepoResponse struct {
+	Handle          string      `json:"handle"`
+	DID             string      `json:"did"`
+	DIDDoc          *didplc.Doc `json:"didDoc"`
+	Collections     []string    `json:"collections"`
+	HandleIsCorrect bool        `json:"handleIsCorrect"`
+}
+
+type DescribeRepoHandlerOptions struct {
+	AccountsDatabase   pds.AccountsDatabase
+	OperationsDatabase pds.OperationsDatabase
+	RecordsDatabase    pds.RecordsDatabase
+}
+
+func DescribeRepoHandler(opts *DescribeRepoHandlerOptions) (http.Handler, error) {
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		logger := slog.LoggerWithRequest(req, nil)
+
+		if req.Method != DescribeRepoHandlerMethod {
+			logger.Error("Method not allowed", "method", req.Method)
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		repo, err := sanitize.GetString(req, "repo")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "repo", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if repo == "" {
+			logger.Error("Missing parameter", "parameter", "repo")
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("repo", repo)
+
+		ctx := req.Context()
+
+		acct, err := pds.GetAccountWithIdentifier(ctx, opts.AccountsDatabase, repo)
+
+		if err != nil {
+
+			if err == atproto.ErrNotFound {
+				logger.Error("Account not found")
+				http.Error(rsp, "Not found", http.StatusNotFound)
+			} else {
+				logger.Error("Failed to retrieve account", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		doc, err := pds.GetDIDDocument(ctx, opts.OperationsDatabase, acct.DID)
+
+		if err != nil {
+			logger.Error("Failed to derive DID document", "error", err)
+			http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		collections := make([]string, 0)
+
+		for collection, err := range pds.ListCollections(ctx, opts.RecordsDatabase, acct.DID) {
+
+			if err != nil {
+				logger.Error("Failed to list collections", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			collections = append(collections, collection)
+		}
+
+		// The handle is considered correct if the DID document declares it and it resolves (locally)
+		// back to the same DID. Since this PDS is authoritative for its own handles, via the
+		// com.atproto.identity.resolveHandle endpoint, the accounts database is used for the latter.
+
+		handle_is_correct := false
+
+		if slices.Contains(doc.AlsoKnownAs, fmt.Sprintf("at://%s", acct.Handle)) {
+
+			handle_acct, err := pds.GetAccountWithHandle(ctx, opts.AccountsDatabase, acct.Handle)
+
+			if err != nil && err != atproto.ErrNotFound {
+				logger.Error("Failed to resolve handle", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			handle_is_correct = handle_acct != nil && handle_acct.DID == acct.DID
+		}
+
+		describe_rsp := DescribeRepoResponse{
+			Handle:          acct.Handle,
+			DID:             acct.DID,
+			DIDDoc:          doc,
+			Collections:     collections,
+			HandleIsCorrect: handle_is_correct,
+		}
+
+		rsp.Header().Set("Content-type", "application/json")
+
+		enc := json.NewEncoder(rsp)
+		err = enc.Encode(describe_rsp)
+
+		if err != nil {
+			logger.Error("Failed to encode response", "error", err)
+			http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	return http.HandlerFunc(fn), nil
+}
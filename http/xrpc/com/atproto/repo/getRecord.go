@@ -8,6 +8,7 @@ import (
 	"github.com/aaronland/go-http/v3/sanitize"
 	"github.com/aaronland/go-http/v3/slog"
 	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/http/xrpc"
 	"github.com/sfomuseum/go-atproto/pds"
 )
 
@@ -19,13 +20,14 @@ type GetRecordResponse struct {
 	Record    *pds.Record      `json:"record"`
 	BlockURI  string           `json:"blockUri"`
 	Commit    string           `json:"commit"`
-	Proof     *json.RawMessage `json:"proof,omitempty"` // optional, omitted here
+	Proof     *json.RawMessage `json:"proof,omitempty"` // optional, omitted here (see com.atproto.sync.getRecord)
 	CreatedAt time.Time        `json:"createdAt"`
 	UpdatedAt time.Time        `json:"updatedAt"`
 }
 
 type GetRecordHandlerOptions struct {
 	RecordsDatabase pds.RecordsDatabase
+	CommitsDatabase pds.CommitsDatabase
 }
 
 func GetRecordHandler(opts *GetRecordHandlerOptions) (http.Handler, error) {
@@ -92,13 +94,28 @@ func GetRecordHandler(opts *GetRecordHandlerOptions) (http.Handler, error) {
 
 		ctx := req.Context()
 
+		commit, err := pds.GetLatestCommitForDID(ctx, opts.CommitsDatabase, repo)
+
+		if err != nil {
+
+			if err == atproto.ErrNotFound {
+				logger.Error("Repo not found")
+				xrpc.Error(rsp, "RepoNotFound", "Could not find repo for DID", http.StatusBadRequest)
+			} else {
+				logger.Error("Failed to retrieve latest commit", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
 		rec, err := pds.GetRecord(ctx, opts.RecordsDatabase, repo, collection, rkey)
 
 		if err != nil {
 
 			if err == atproto.ErrNotFound {
 				logger.Error("Record not found")
-				http.Error(rsp, "Not found", http.StatusNotFound)
+				xrpc.Error(rsp, "RecordNotFound", "Could not locate record", http.StatusBadRequest)
 			} else {
 				logger.Error("Failed to retrieve record", "error", err)
 				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
@@ -111,7 +128,7 @@ func GetRecordHandler(opts *GetRecordHandlerOptions) (http.Handler, error) {
 			CID:       rec.CID,
 			Record:    rec,
 			BlockURI:  rec.BlockURI(),
-			Commit:    rec.CID, // placeholder
+			Commit:    commit.CID,
 			CreatedAt: time.Unix(rec.Created, 0),
 			UpdatedAt: time.Unix(rec.LastModified, 0),
 		}
@@ -0,0 +1,113 @@
+package repo
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aaronland/go-http/v3/slog"
+	"github.com/bluesky-social/indigo/atproto/identity"
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/auth"
+	"github.com/sfomuseum/go-atproto/http/xrpc"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+const ImportRepoHandlerURI string = "/xrpc/com.atproto.repo.importRepo"
+const ImportRepoHandlerMethod string = http.MethodPost
+
+// The maximum size (in bytes) of an importRepo request body (CAR file).
+const MAX_IMPORT_REPO_SIZE int64 = 100 * 1024 * 1024
+
+type ImportRepoHandlerOptions struct {
+	AccountsDatabase pds.AccountsDatabase
+	RecordsDatabase  pds.RecordsDatabase
+	KeysDatabase     pds.KeysDatabase
+	CommitsDatabase  pds.CommitsDatabase
+	BlocksDatabase   pds.BlocksDatabase
+	Authenticator    auth.Authenticator
+	// An optional `pds.BlobsDatabase` to record the blobs referenced by each imported record.
+	BlobsDatabase pds.BlobsDatabase
+	// An optional `pds.Sequencer` to emit a "#sync" event to once a repository has been imported.
+	Sequencer *pds.Sequencer
+	// The `identity.Directory` used to resolve the signing key for imported repositories. If nil then
+	// `identity.DefaultDirectory` is used.
+	Directory identity.Directory
+}
+
+func ImportRepoHandler(opts *ImportRepoHandlerOptions) (http.Handler, error) {
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		logger := slog.LoggerWithRequest(req, nil)
+
+		if req.Method != ImportRepoHandlerMethod {
+			logger.Error("Method not allowed", "method", req.Method)
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		auth_did, err := opts.Authenticator.GetDIDForRequest(req)
+
+		if err != nil {
+			logger.Error("Failed to authenticate request", "error", err)
+			http.Error(rsp, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		logger = logger.With("auth", auth_did)
+
+		ctx := req.Context()
+
+		acct, err := pds.GetAccount(ctx, opts.AccountsDatabase, auth_did)
+
+		if err != nil {
+
+			if err == atproto.ErrNotFound {
+				logger.Error("Account not found")
+				http.Error(rsp, "Not found", http.StatusNotFound)
+			} else {
+				logger.Error("Failed to retrieve account", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		if acct.Deleted != 0 {
+			logger.Error("Account has been deleted")
+			http.Error(rsp, "Not found", http.StatusNotFound)
+			return
+		}
+
+		import_opts := &pds.ImportRepoOptions{
+			RecordsDatabase: opts.RecordsDatabase,
+			KeysDatabase:    opts.KeysDatabase,
+			CommitsDatabase: opts.CommitsDatabase,
+			BlocksDatabase:  opts.BlocksDatabase,
+			BlobsDatabase:   opts.BlobsDatabase,
+			Sequencer:       opts.Sequencer,
+			Directory:       opts.Directory,
+		}
+
+		body := http.MaxBytesReader(rsp, req.Body, MAX_IMPORT_REPO_SIZE)
+
+		commit, err := pds.ImportRepo(ctx, import_opts, acct.DID, body)
+
+		if err != nil {
+
+			if errors.Is(err, atproto.ErrInvalidWrite) {
+				logger.Error("Invalid repo", "error", err)
+				xrpc.Error(rsp, "InvalidRequest", err.Error(), http.StatusBadRequest)
+			} else {
+				logger.Error("Failed to import repo", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		logger.Info("Imported repo", "commit", commit.CID, "rev", commit.Rev)
+	}
+
+	return http.HandlerFunc(fn), nil
+}
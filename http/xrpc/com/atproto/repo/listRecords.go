@@ -0,0 +1,191 @@
+package repo
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/aaronland/go-http/v3/sanitize"
+	"github.com/aaronland/go-http/v3/slog"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+const ListRecordsHandlerURI string = "/xrpc/com.atproto.repo.listRecords"
+const ListRecordsHandlerMethod string = http.MethodGet
+
+// The default number of records to return if no limit is specified.
+const LIST_RECORDS_DEFAULT_LIMIT int = 50
+
+// The maximum number of records that can be returned in a single request.
+const LIST_RECORDS_MAX_LIMIT int = 100
+
+type ListRecordsRecord struct {
+	URI   string          `json:"uri"`
+	CID   string          `json:"cid"`
+	Value json.RawMessage `json:"value"`
+}
+
+type ListRecordsResponse struct {
+	Cursor  string               `json:"cursor,omitempty"`
+	Records []*ListRecordsRecord `json:"records"`
+}
+
+type ListRecordsHandlerOptions struct {
+	AccountsDatabase pds.AccountsDatabase
+	RecordsDatabase  pds.RecordsDatabase
+}
+
+func ListRecordsHandler(opts *ListRecordsHandlerOptions) (http.Handler, error) {
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		logger := slog.LoggerWithRequest(req, nil)
+
+		if req.Method != ListRecordsHandlerMethod {
+			logger.Error("Method not allowed", "method", req.Method)
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		repo, err := sanitize.GetString(req, "repo")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "repo", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if repo == "" {
+			logger.Error("Missing parameter", "parameter", "repo")
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("repo", repo)
+
+		collection, err := sanitize.GetString(req, "collection")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "collection", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		_, err = syntax.ParseNSID(collection)
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "collection", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("collection", collection)
+
+		limit := LIST_RECORDS_DEFAULT_LIMIT
+
+		str_limit, err := sanitize.GetString(req, "limit")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "limit", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if str_limit != "" {
+
+			v, err := strconv.Atoi(str_limit)
+
+			if err != nil || v < 1 || v > LIST_RECORDS_MAX_LIMIT {
+				logger.Error("Invalid parameter", "parameter", "limit", "value", str_limit)
+				http.Error(rsp, "Bad request", http.StatusBadRequest)
+				return
+			}
+
+			limit = v
+		}
+
+		cursor, err := sanitize.GetString(req, "cursor")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "cursor", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		reverse, err := sanitize.GetBool(req, "reverse")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "reverse", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		ctx := req.Context()
+
+		acct, err := pds.GetAccountWithIdentifier(ctx, opts.AccountsDatabase, repo)
+
+		if err != nil {
+
+			if err == atproto.ErrNotFound {
+				logger.Error("Account not found")
+				http.Error(rsp, "Not found", http.StatusNotFound)
+			} else {
+				logger.Error("Failed to retrieve account", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		list_opts := &pds.ListRecordsOptions{
+			Repo:       acct.DID,
+			Collection: collection,
+			Limit:      limit,
+			Cursor:     cursor,
+			Reverse:    reverse,
+		}
+
+		list_rsp := ListRecordsResponse{
+			Records: make([]*ListRecordsRecord, 0),
+		}
+
+		var last *pds.Record
+
+		for rec, err := range pds.ListRecords(ctx, opts.RecordsDatabase, list_opts) {
+
+			if err != nil {
+				logger.Error("Failed to list records", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			r := &ListRecordsRecord{
+				URI:   rec.URI(),
+				CID:   rec.CID,
+				Value: json.RawMessage(rec.Value),
+			}
+
+			list_rsp.Records = append(list_rsp.Records, r)
+			last = rec
+		}
+
+		if last != nil && len(list_rsp.Records) == limit {
+			list_rsp.Cursor = pds.ListRecordsCursor(list_opts, last)
+		}
+
+		rsp.Header().Set("Content-type", "application/json")
+
+		enc := json.NewEncoder(rsp)
+		err = enc.Encode(list_rsp)
+
+		if err != nil {
+			logger.Error("Failed to encode response", "error", err)
+			http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	return http.HandlerFunc(fn), nil
+}
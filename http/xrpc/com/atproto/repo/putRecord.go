@@ -1,21 +1,59 @@
 package repo
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/aaronland/go-http/v3/slog"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/auth"
+	"github.com/sfomuseum/go-atproto/http/xrpc"
 	"github.com/sfomuseum/go-atproto/pds"
 )
 
 const PutRecordHandlerURI string = "/xrpc/com.atproto.repo.putRecord"
-const PutRecordHandlerMethod string = http.MethodPut
+const PutRecordHandlerMethod string = http.MethodPost
+
+// The maximum size (in bytes) of a putRecord request body.
+const MAX_PUT_RECORD_SIZE int64 = 2 * 1024 * 1024
+
+type PutRecordRequest struct {
+	Repo       string          `json:"repo"`
+	Collection string          `json:"collection"`
+	RKey       string          `json:"rkey"`
+	Validate   *bool           `json:"validate,omitempty"`
+	Record     json.RawMessage `json:"record"`
+	SwapRecord string          `json:"swapRecord,omitempty"`
+	SwapCommit string          `json:"swapCommit,omitempty"`
+}
+
+type CommitMeta struct {
+	CID string `json:"cid"`
+	Rev string `json:"rev"`
+}
+
+type PutRecordResponse struct {
+	URI              string      `json:"uri"`
+	CID              string      `json:"cid"`
+	Commit           *CommitMeta `json:"commit,omitempty"`
+	ValidationStatus string      `json:"validationStatus,omitempty"`
+}
 
 type PutRecordHandlerOptions struct {
 	AccountsDatabase pds.AccountsDatabase
 	RecordsDatabase  pds.RecordsDatabase
+	KeysDatabase     pds.KeysDatabase
+	CommitsDatabase  pds.CommitsDatabase
+	BlocksDatabase   pds.BlocksDatabase
+	Authenticator    auth.Authenticator
+	Sequencer        *pds.Sequencer
+	// An optional `pds.BlobsDatabase` to record the blobs referenced by each record.
+	BlobsDatabase pds.BlobsDatabase
 }
 
-func PutRecordHandler() (http.Handler, error) {
+func PutRecordHandler(opts *PutRecordHandlerOptions) (http.Handler, error) {
 
 	fn := func(rsp http.ResponseWriter, req *http.Request) {
 
@@ -27,6 +65,154 @@ func PutRecordHandler() (http.Handler, error) {
 			return
 		}
 
+		auth_did, err := opts.Authenticator.GetDIDForRequest(req)
+
+		if err != nil {
+			logger.Error("Failed to authenticate request", "error", err)
+			http.Error(rsp, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		logger = logger.With("auth", auth_did)
+
+		var put_req *PutRecordRequest
+
+		body := http.MaxBytesReader(rsp, req.Body, MAX_PUT_RECORD_SIZE)
+		dec := json.NewDecoder(body)
+
+		err = dec.Decode(&put_req)
+
+		if err != nil {
+			logger.Error("Failed to decode request", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if put_req.Repo == "" {
+			logger.Error("Missing parameter", "parameter", "repo")
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("repo", put_req.Repo)
+
+		_, err = syntax.ParseNSID(put_req.Collection)
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "collection", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("collection", put_req.Collection)
+
+		_, err = syntax.ParseRecordKey(put_req.RKey)
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "rkey", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("rkey", put_req.RKey)
+
+		if len(put_req.Record) == 0 {
+			logger.Error("Missing parameter", "parameter", "record")
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		ctx := req.Context()
+
+		acct, err := pds.GetAccountWithIdentifier(ctx, opts.AccountsDatabase, put_req.Repo)
+
+		if err != nil {
+
+			if err == atproto.ErrNotFound {
+				logger.Error("Account not found")
+				http.Error(rsp, "Not found", http.StatusNotFound)
+			} else {
+				logger.Error("Failed to retrieve account", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		if acct.Deleted != 0 {
+			logger.Error("Account has been deleted")
+			http.Error(rsp, "Not found", http.StatusNotFound)
+			return
+		}
+
+		if acct.DID != auth_did {
+			logger.Error("Authenticated account does not match repo", "did", acct.DID)
+			http.Error(rsp, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		// Whether this is a create or an update (and whether the swap value is valid) is determined by pds.ApplyWrites
+		// while it holds the lock for the repository
+
+		writes := []*pds.Write{
+			&pds.Write{
+				Action:     pds.RECORD_WRITE_UPSERT,
+				Collection: put_req.Collection,
+				RKey:       put_req.RKey,
+				Value:      string(put_req.Record),
+				SwapRecord: put_req.SwapRecord,
+			},
+		}
+
+		apply_opts := &pds.ApplyWritesOptions{
+			RecordsDatabase: opts.RecordsDatabase,
+			KeysDatabase:    opts.KeysDatabase,
+			CommitsDatabase: opts.CommitsDatabase,
+			BlocksDatabase:  opts.BlocksDatabase,
+			SwapCommit:      put_req.SwapCommit,
+			Sequencer:       opts.Sequencer,
+			BlobsDatabase:   opts.BlobsDatabase,
+		}
+
+		results, commit, err := pds.ApplyWrites(ctx, apply_opts, acct.DID, writes)
+
+		if err != nil {
+
+			switch {
+			case errors.Is(err, atproto.ErrInvalidSwap):
+				logger.Error("Invalid swap", "error", err)
+				xrpc.Error(rsp, "InvalidSwap", err.Error(), http.StatusBadRequest)
+			case errors.Is(err, atproto.ErrInvalidWrite):
+				logger.Error("Invalid write", "error", err)
+				xrpc.Error(rsp, "InvalidRequest", err.Error(), http.StatusBadRequest)
+			default:
+				logger.Error("Failed to apply writes", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		put_rsp := PutRecordResponse{
+			URI: results[0].URI,
+			CID: results[0].CID,
+			Commit: &CommitMeta{
+				CID: commit.CID,
+				Rev: commit.Rev,
+			},
+			ValidationStatus: "unknown",
+		}
+
+		rsp.Header().Set("Content-type", "application/json")
+
+		enc := json.NewEncoder(rsp)
+		err = enc.Encode(put_rsp)
+
+		if err != nil {
+			logger.Error("Failed to encode response", "error", err)
+			http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			return
+		}
 	}
 
 	return http.HandlerFunc(fn), nil
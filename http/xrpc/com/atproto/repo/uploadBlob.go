@@ -0,0 +1,173 @@
+package repo
+
+// https://docs.bsky.app/docs/api/com-atproto-repo-upload-blob
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/aaronland/go-http/v3/slog"
+	"github.com/bluesky-social/indigo/atproto/data"
+	"github.com/ipfs/go-cid"
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/auth"
+	"github.com/sfomuseum/go-atproto/http/xrpc"
+	"github.com/sfomuseum/go-atproto/imaging"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+const UploadBlobHandlerURI string = "/xrpc/com.atproto.repo.uploadBlob"
+const UploadBlobHandlerMethod string = http.MethodPost
+
+type UploadBlobHandlerOptions struct {
+	AccountsDatabase pds.AccountsDatabase
+	BlobsDatabase    pds.BlobsDatabase
+	BlobStore        *pds.BlobStore
+	Authenticator    auth.Authenticator
+	// The maximum size (in bytes) of an uploaded blob. If zero or less then `pds.DEFAULT_MAX_BLOB_SIZE` is used.
+	MaxBlobSize int64
+	// If not nil uploaded images are normalized using these options. See `pds.UploadBlobOptions.Images`.
+	Images *imaging.Options
+}
+
+// AspectRatio is the width and height of a normalized image, in the form of an app.bsky.embed.defs#aspectRatio object.
+type AspectRatio struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+type UploadBlobResponse struct {
+	Blob data.Blob `json:"blob"`
+	// The aspect ratio of (normalized) images. This is not part of the com.atproto.repo.uploadBlob lexicon but is
+	// included so that clients can populate the "aspectRatio" property of image embeds without decoding images themselves.
+	AspectRatio *AspectRatio `json:"aspectRatio,omitempty"`
+}
+
+func UploadBlobHandler(opts *UploadBlobHandlerOptions) (http.Handler, error) {
+
+	max_size := opts.MaxBlobSize
+
+	if max_size <= 0 {
+		max_size = pds.DEFAULT_MAX_BLOB_SIZE
+	}
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		logger := slog.LoggerWithRequest(req, nil)
+
+		if req.Method != UploadBlobHandlerMethod {
+			logger.Error("Method not allowed", "method", req.Method)
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		auth_did, err := opts.Authenticator.GetDIDForRequest(req)
+
+		if err != nil {
+			logger.Error("Failed to authenticate request", "error", err)
+			http.Error(rsp, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		logger = logger.With("auth", auth_did)
+
+		if req.ContentLength > max_size {
+			logger.Error("Blob too large", "content_length", req.ContentLength)
+			xrpc.Error(rsp, "PayloadTooLarge", atproto.ErrBlobTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		ctx := req.Context()
+
+		acct, err := pds.GetAccount(ctx, opts.AccountsDatabase, auth_did)
+
+		if err != nil {
+
+			if err == atproto.ErrNotFound {
+				logger.Error("Account not found")
+				http.Error(rsp, "Not found", http.StatusNotFound)
+			} else {
+				logger.Error("Failed to retrieve account", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		if acct.Deleted != 0 {
+			logger.Error("Account has been deleted")
+			http.Error(rsp, "Not found", http.StatusNotFound)
+			return
+		}
+
+		upload_opts := &pds.UploadBlobOptions{
+			BlobsDatabase: opts.BlobsDatabase,
+			BlobStore:     opts.BlobStore,
+			MaxSize:       max_size,
+			Images:        opts.Images,
+		}
+
+		// pds.UploadBlob enforces the maximum size itself; the extra byte allows it to detect (and report) oversized blobs
+
+		body := http.MaxBytesReader(rsp, req.Body, max_size+1)
+
+		b, err := pds.UploadBlob(ctx, upload_opts, acct.DID, req.Header.Get("Content-Type"), body)
+
+		if err != nil {
+
+			switch {
+			case errors.Is(err, atproto.ErrBlobTooLarge):
+				logger.Error("Blob too large", "error", err)
+				xrpc.Error(rsp, "PayloadTooLarge", err.Error(), http.StatusRequestEntityTooLarge)
+			case errors.Is(err, atproto.ErrInvalidMediaType):
+				logger.Error("Invalid media type", "error", err)
+				xrpc.Error(rsp, "InvalidRequest", err.Error(), http.StatusBadRequest)
+			default:
+				logger.Error("Failed to upload blob", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		logger.Info("Uploaded blob", "cid", b.CID, "media_type", b.MediaType, "size", b.Size)
+
+		blob_cid, err := cid.Decode(b.CID)
+
+		if err != nil {
+			logger.Error("Invalid blob CID", "error", err)
+			http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		upload_rsp := UploadBlobResponse{
+			Blob: data.Blob{
+				Ref:      data.CIDLink(blob_cid),
+				MimeType: b.MediaType,
+				Size:     b.Size,
+			},
+		}
+
+		if b.Width > 0 && b.Height > 0 {
+
+			upload_rsp.AspectRatio = &AspectRatio{
+				Width:  b.Width,
+				Height: b.Height,
+			}
+		}
+
+		rsp.Header().Set("Content-type", "application/json")
+
+		enc := json.NewEncoder(rsp)
+		err = enc.Encode(upload_rsp)
+
+		if err != nil {
+			logger.Error("Failed to encode response", "error", err)
+			http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	return http.HandlerFunc(fn), nil
+}
@@ -0,0 +1,152 @@
+package sync
+
+// https://docs.bsky.app/docs/api/com-atproto-sync-get-blob
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aaronland/go-http/v3/sanitize"
+	"github.com/aaronland/go-http/v3/slog"
+	"github.com/ipfs/go-cid"
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/http/xrpc"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+const GetBlobHandlerURI string = "/xrpc/com.atproto.sync.getBlob"
+const GetBlobHandlerMethod string = http.MethodGet
+
+// The Content-Security-Policy header sent with blobs, which may be arbitrary (user-supplied) content, so that
+// they are never rendered as active content in the context of the PDS.
+const BLOB_CONTENT_SECURITY_POLICY string = "default-src 'none'; sandbox"
+
+type GetBlobHandlerOptions struct {
+	AccountsDatabase pds.AccountsDatabase
+	BlobsDatabase    pds.BlobsDatabase
+	BlobStore        *pds.BlobStore
+}
+
+func GetBlobHandler(opts *GetBlobHandlerOptions) (http.Handler, error) {
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		logger := slog.LoggerWithRequest(req, nil)
+
+		if req.Method != GetBlobHandlerMethod && req.Method != http.MethodHead {
+			logger.Error("Method not allowed", "method", req.Method)
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		did, err := sanitize.GetString(req, "did")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "did", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if did == "" {
+			logger.Error("Missing parameter", "parameter", "did")
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("did", did)
+
+		str_cid, err := sanitize.GetString(req, "cid")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "cid", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if str_cid == "" {
+			logger.Error("Missing parameter", "parameter", "cid")
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		blob_cid, err := cid.Decode(str_cid)
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "cid", "value", str_cid, "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("cid", blob_cid)
+
+		ctx := req.Context()
+
+		acct, err := pds.GetAccount(ctx, opts.AccountsDatabase, did)
+
+		if err != nil {
+
+			if err == atproto.ErrNotFound {
+				logger.Error("Account not found")
+				xrpc.Error(rsp, "RepoNotFound", "Could not find repo for DID", http.StatusBadRequest)
+			} else {
+				logger.Error("Failed to retrieve account", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		if inactiveRepoError(rsp, logger, acct) {
+			return
+		}
+
+		b, err := pds.GetBlob(ctx, opts.BlobsDatabase, acct.DID, blob_cid.String())
+
+		if err != nil {
+
+			if err == atproto.ErrNotFound {
+				logger.Error("Blob not found")
+				xrpc.Error(rsp, "BlobNotFound", fmt.Sprintf("Could not find blob %s", blob_cid), http.StatusBadRequest)
+			} else {
+				logger.Error("Failed to retrieve blob", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		r, err := opts.BlobStore.NewReader(ctx, acct.DID, b.CID)
+
+		if err != nil {
+
+			if err == atproto.ErrNotFound {
+				logger.Error("Blob data not found")
+				xrpc.Error(rsp, "BlobNotFound", fmt.Sprintf("Could not find blob %s", blob_cid), http.StatusBadRequest)
+			} else {
+				logger.Error("Failed to open blob", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		defer r.Close()
+
+		// The media type is always read from the blobs database since not all buckets preserve content types
+
+		rsp.Header().Set("Content-type", b.MediaType)
+		rsp.Header().Set("Content-Security-Policy", BLOB_CONTENT_SECURITY_POLICY)
+		rsp.Header().Set("X-Content-Type-Options", "nosniff")
+
+		// Blobs are content-addressed so the CID is a (strong) ETag
+
+		rsp.Header().Set("ETag", fmt.Sprintf(`"%s"`, b.CID))
+
+		// http.ServeContent takes care of Content-Length, range requests and conditional requests
+
+		http.ServeContent(rsp, req, "", time.Unix(b.Created, 0), r)
+	}
+
+	return http.HandlerFunc(fn), nil
+}
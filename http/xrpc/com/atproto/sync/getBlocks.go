@@ -0,0 +1,160 @@
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/aaronland/go-http/v3/sanitize"
+	"github.com/aaronland/go-http/v3/slog"
+	"github.com/ipfs/go-cid"
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/car"
+	"github.com/sfomuseum/go-atproto/http/xrpc"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+const GetBlocksHandlerURI string = "/xrpc/com.atproto.sync.getBlocks"
+const GetBlocksHandlerMethod string = http.MethodGet
+
+// The maximum number of blocks that can be requested in a single request.
+const GET_BLOCKS_MAX_CIDS int = 1000
+
+type GetBlocksHandlerOptions struct {
+	AccountsDatabase pds.AccountsDatabase
+	BlocksDatabase   pds.BlocksDatabase
+}
+
+func GetBlocksHandler(opts *GetBlocksHandlerOptions) (http.Handler, error) {
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		logger := slog.LoggerWithRequest(req, nil)
+
+		if req.Method != GetBlocksHandlerMethod {
+			logger.Error("Method not allowed", "method", req.Method)
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		did, err := sanitize.GetString(req, "did")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "did", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if did == "" {
+			logger.Error("Missing parameter", "parameter", "did")
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("did", did)
+
+		// The "cids" parameter is repeated (cids=...&cids=...) so it is read directly from the query string
+		// and each value is validated by parsing it as a CID
+
+		q := req.URL.Query()
+		str_cids := q["cids"]
+
+		if len(str_cids) == 0 {
+			logger.Error("Missing parameter", "parameter", "cids")
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if len(str_cids) > GET_BLOCKS_MAX_CIDS {
+			logger.Error("Too many CIDs", "count", len(str_cids))
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		cids := make([]cid.Cid, len(str_cids))
+
+		for i, str_cid := range str_cids {
+
+			c, err := cid.Decode(str_cid)
+
+			if err != nil {
+				logger.Error("Invalid parameter", "parameter", "cids", "value", str_cid, "error", err)
+				http.Error(rsp, "Bad request", http.StatusBadRequest)
+				return
+			}
+
+			cids[i] = c
+		}
+
+		ctx := req.Context()
+
+		acct, err := pds.GetAccount(ctx, opts.AccountsDatabase, did)
+
+		if err != nil {
+
+			if err == atproto.ErrNotFound {
+				logger.Error("Account not found")
+				xrpc.Error(rsp, "RepoNotFound", "Could not find repo for DID", http.StatusBadRequest)
+			} else {
+				logger.Error("Failed to retrieve account", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		if inactiveRepoError(rsp, logger, acct) {
+			return
+		}
+
+		// All the blocks are read, and written to a buffer, before anything is sent so that a missing
+		// block can be reported as an error rather than a truncated CAR file
+
+		var buf bytes.Buffer
+
+		car_wr, err := car.NewWriter(&buf)
+
+		if err != nil {
+			logger.Error("Failed to create CAR writer", "error", err)
+			http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		for _, c := range cids {
+
+			b, err := pds.GetBlock(ctx, opts.BlocksDatabase, acct.DID, c.String())
+
+			if err != nil {
+
+				if err == atproto.ErrNotFound {
+					logger.Error("Block not found", "cid", c)
+					xrpc.Error(rsp, "BlockNotFound", fmt.Sprintf("Could not find block %s", c), http.StatusBadRequest)
+				} else {
+					logger.Error("Failed to retrieve block", "cid", c, "error", err)
+					http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+				}
+
+				return
+			}
+
+			err = car_wr.WriteBlock(c, b.Data)
+
+			if err != nil {
+				logger.Error("Failed to write block", "cid", c, "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		rsp.Header().Set("Content-type", car.CONTENT_TYPE)
+
+		_, err = buf.WriteTo(rsp)
+
+		if err != nil {
+			logger.Error("Failed to write response", "error", err)
+			return
+		}
+	}
+
+	return http.HandlerFunc(fn), nil
+}
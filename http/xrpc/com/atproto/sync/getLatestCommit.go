@@ -0,0 +1,109 @@
+package sync
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aaronland/go-http/v3/sanitize"
+	"github.com/aaronland/go-http/v3/slog"
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/http/xrpc"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+const GetLatestCommitHandlerURI string = "/xrpc/com.atproto.sync.getLatestCommit"
+const GetLatestCommitHandlerMethod string = http.MethodGet
+
+type GetLatestCommitResponse struct {
+	CID string `json:"cid"`
+	Rev string `json:"rev"`
+}
+
+type GetLatestCommitHandlerOptions struct {
+	AccountsDatabase pds.AccountsDatabase
+	CommitsDatabase  pds.CommitsDatabase
+}
+
+func GetLatestCommitHandler(opts *GetLatestCommitHandlerOptions) (http.Handler, error) {
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		logger := slog.LoggerWithRequest(req, nil)
+
+		if req.Method != GetLatestCommitHandlerMethod {
+			logger.Error("Method not allowed", "method", req.Method)
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		did, err := sanitize.GetString(req, "did")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "did", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if did == "" {
+			logger.Error("Missing parameter", "parameter", "did")
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("did", did)
+
+		ctx := req.Context()
+
+		acct, err := pds.GetAccount(ctx, opts.AccountsDatabase, did)
+
+		if err != nil {
+
+			if err == atproto.ErrNotFound {
+				logger.Error("Account not found")
+				xrpc.Error(rsp, "RepoNotFound", "Could not find repo for DID", http.StatusBadRequest)
+			} else {
+				logger.Error("Failed to retrieve account", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		if inactiveRepoError(rsp, logger, acct) {
+			return
+		}
+
+		commit, err := pds.GetLatestCommitForDID(ctx, opts.CommitsDatabase, acct.DID)
+
+		if err != nil {
+
+			if err == atproto.ErrNotFound {
+				logger.Error("Repo has no commits")
+				xrpc.Error(rsp, "RepoNotFound", "Could not find root for DID", http.StatusBadRequest)
+			} else {
+				logger.Error("Failed to retrieve latest commit", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		commit_rsp := GetLatestCommitResponse{
+			CID: commit.CID,
+			Rev: commit.Rev,
+		}
+
+		rsp.Header().Set("Content-type", "application/json")
+
+		enc := json.NewEncoder(rsp)
+		err = enc.Encode(commit_rsp)
+
+		if err != nil {
+			logger.Error("Failed to encode response", "error", err)
+			http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	return http.HandlerFunc(fn), nil
+}
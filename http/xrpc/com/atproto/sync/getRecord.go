@@ -0,0 +1,140 @@
+package sync
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/aaronland/go-http/v3/sanitize"
+	"github.com/aaronland/go-http/v3/slog"
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/car"
+	"github.com/sfomuseum/go-atproto/http/xrpc"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+const GetRecordHandlerURI string = "/xrpc/com.atproto.sync.getRecord"
+const GetRecordHandlerMethod string = http.MethodGet
+
+type GetRecordHandlerOptions struct {
+	AccountsDatabase pds.AccountsDatabase
+	RecordsDatabase  pds.RecordsDatabase
+	CommitsDatabase  pds.CommitsDatabase
+}
+
+func GetRecordHandler(opts *GetRecordHandlerOptions) (http.Handler, error) {
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		logger := slog.LoggerWithRequest(req, nil)
+
+		if req.Method != GetRecordHandlerMethod {
+			logger.Error("Method not allowed", "method", req.Method)
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		did, err := sanitize.GetString(req, "did")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "did", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if did == "" {
+			logger.Error("Missing parameter", "parameter", "did")
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("did", did)
+
+		collection, err := sanitize.GetString(req, "collection")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "collection", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if collection == "" {
+			logger.Error("Missing parameter", "parameter", "collection")
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("collection", collection)
+
+		rkey, err := sanitize.GetString(req, "rkey")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "rkey", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if rkey == "" {
+			logger.Error("Missing parameter", "parameter", "rkey")
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("rkey", rkey)
+
+		ctx := req.Context()
+
+		acct, err := pds.GetAccount(ctx, opts.AccountsDatabase, did)
+
+		if err != nil {
+
+			if err == atproto.ErrNotFound {
+				logger.Error("Account not found")
+				xrpc.Error(rsp, "RepoNotFound", "Could not find repo for DID", http.StatusBadRequest)
+			} else {
+				logger.Error("Failed to retrieve account", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		if inactiveRepoError(rsp, logger, acct) {
+			return
+		}
+
+		export_opts := &pds.ExportRepoOptions{
+			RecordsDatabase: opts.RecordsDatabase,
+			CommitsDatabase: opts.CommitsDatabase,
+		}
+
+		// Proofs are small so they are buffered in order to be able to report errors using HTTP status codes
+
+		var buf bytes.Buffer
+
+		_, err = pds.ExportRecord(ctx, export_opts, acct.DID, collection, rkey, &buf)
+
+		if err != nil {
+
+			if err == atproto.ErrNotFound {
+				logger.Error("Record not found")
+				xrpc.Error(rsp, "RecordNotFound", "Could not locate record", http.StatusBadRequest)
+			} else {
+				logger.Error("Failed to export record", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		rsp.Header().Set("Content-type", car.CONTENT_TYPE)
+
+		_, err = buf.WriteTo(rsp)
+
+		if err != nil {
+			logger.Error("Failed to write response", "error", err)
+			return
+		}
+	}
+
+	return http.HandlerFunc(fn), nil
+}
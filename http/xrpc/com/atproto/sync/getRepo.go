@@ -0,0 +1,134 @@
+package sync
+
+import (
+	"net/http"
+
+	"github.com/aaronland/go-http/v3/sanitize"
+	"github.com/aaronland/go-http/v3/slog"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/car"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+const GetRepoHandlerURI string = "/xrpc/com.atproto.sync.getRepo"
+const GetRepoHandlerMethod string = http.MethodGet
+
+type GetRepoHandlerOptions struct {
+	AccountsDatabase pds.AccountsDatabase
+	RecordsDatabase  pds.RecordsDatabase
+	CommitsDatabase  pds.CommitsDatabase
+	BlocksDatabase   pds.BlocksDatabase
+}
+
+func GetRepoHandler(opts *GetRepoHandlerOptions) (http.Handler, error) {
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		logger := slog.LoggerWithRequest(req, nil)
+
+		if req.Method != GetRepoHandlerMethod {
+			logger.Error("Method not allowed", "method", req.Method)
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		did, err := sanitize.GetString(req, "did")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "did", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if did == "" {
+			logger.Error("Missing parameter", "parameter", "did")
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("did", did)
+
+		since, err := sanitize.GetString(req, "since")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "since", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if since != "" {
+
+			_, err := syntax.ParseTID(since)
+
+			if err != nil {
+				logger.Error("Invalid parameter", "parameter", "since", "value", since, "error", err)
+				http.Error(rsp, "Bad request", http.StatusBadRequest)
+				return
+			}
+
+			logger = logger.With("since", since)
+		}
+
+		ctx := req.Context()
+
+		acct, err := pds.GetAccount(ctx, opts.AccountsDatabase, did)
+
+		if err != nil {
+
+			if err == atproto.ErrNotFound {
+				logger.Error("Account not found")
+				http.Error(rsp, "Not found", http.StatusNotFound)
+			} else {
+				logger.Error("Failed to retrieve account", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		if acct.Deleted != 0 {
+			logger.Error("Account has been deleted")
+			http.Error(rsp, "Not found", http.StatusNotFound)
+			return
+		}
+
+		// Check for a commit explicitly, rather than relying on pds.ExportRepo, so that repositories
+		// without any commits can be reported as not found
+
+		_, err = pds.GetLatestCommitForDID(ctx, opts.CommitsDatabase, acct.DID)
+
+		if err != nil {
+
+			if err == atproto.ErrNotFound {
+				logger.Error("Repo has no commits")
+				http.Error(rsp, "Not found", http.StatusNotFound)
+			} else {
+				logger.Error("Failed to retrieve latest commit", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		export_opts := &pds.ExportRepoOptions{
+			RecordsDatabase: opts.RecordsDatabase,
+			CommitsDatabase: opts.CommitsDatabase,
+			BlocksDatabase:  opts.BlocksDatabase,
+		}
+
+		// Note that once the CAR header has been written it is no longer possible to report
+		// errors using HTTP status codes so they are only logged.
+
+		rsp.Header().Set("Content-type", car.CONTENT_TYPE)
+
+		_, err = pds.ExportRepoSince(ctx, export_opts, acct.DID, since, rsp)
+
+		if err != nil {
+			logger.Error("Failed to export repo", "error", err)
+			return
+		}
+	}
+
+	return http.HandlerFunc(fn), nil
+}
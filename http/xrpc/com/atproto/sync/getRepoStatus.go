@@ -0,0 +1,110 @@
+package sync
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aaronland/go-http/v3/sanitize"
+	"github.com/aaronland/go-http/v3/slog"
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/http/xrpc"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+const GetRepoStatusHandlerURI string = "/xrpc/com.atproto.sync.getRepoStatus"
+const GetRepoStatusHandlerMethod string = http.MethodGet
+
+type GetRepoStatusResponse struct {
+	DID    string `json:"did"`
+	Active bool   `json:"active"`
+	// If active is false, the reason the repo is not active.
+	Status string `json:"status,omitempty"`
+	// The rev of the current commit for the repo, if active.
+	Rev string `json:"rev,omitempty"`
+}
+
+type GetRepoStatusHandlerOptions struct {
+	AccountsDatabase pds.AccountsDatabase
+	CommitsDatabase  pds.CommitsDatabase
+}
+
+func GetRepoStatusHandler(opts *GetRepoStatusHandlerOptions) (http.Handler, error) {
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		logger := slog.LoggerWithRequest(req, nil)
+
+		if req.Method != GetRepoStatusHandlerMethod {
+			logger.Error("Method not allowed", "method", req.Method)
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		did, err := sanitize.GetString(req, "did")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "did", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if did == "" {
+			logger.Error("Missing parameter", "parameter", "did")
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("did", did)
+
+		ctx := req.Context()
+
+		acct, err := pds.GetAccount(ctx, opts.AccountsDatabase, did)
+
+		if err != nil {
+
+			if err == atproto.ErrNotFound {
+				logger.Error("Account not found")
+				xrpc.Error(rsp, "RepoNotFound", "Could not find repo for DID", http.StatusBadRequest)
+			} else {
+				logger.Error("Failed to retrieve account", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		status_rsp := GetRepoStatusResponse{
+			DID:    acct.DID,
+			Active: acct.IsActive(),
+			Status: acct.Status(),
+		}
+
+		if status_rsp.Active {
+
+			commit, err := pds.GetLatestCommitForDID(ctx, opts.CommitsDatabase, acct.DID)
+
+			if err != nil && err != atproto.ErrNotFound {
+				logger.Error("Failed to retrieve latest commit", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			if commit != nil {
+				status_rsp.Rev = commit.Rev
+			}
+		}
+
+		rsp.Header().Set("Content-type", "application/json")
+
+		enc := json.NewEncoder(rsp)
+		err = enc.Encode(status_rsp)
+
+		if err != nil {
+			logger.Error("Failed to encode response", "error", err)
+			http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	return http.HandlerFunc(fn), nil
+}
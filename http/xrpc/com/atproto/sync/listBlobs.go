@@ -0,0 +1,185 @@
+package sync
+
+// https://docs.bsky.app/docs/api/com-atproto-sync-list-blobs
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/aaronland/go-http/v3/sanitize"
+	"github.com/aaronland/go-http/v3/slog"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/http/xrpc"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+const ListBlobsHandlerURI string = "/xrpc/com.atproto.sync.listBlobs"
+const ListBlobsHandlerMethod string = http.MethodGet
+
+// The default number of blobs to return if no limit is specified.
+const LIST_BLOBS_DEFAULT_LIMIT int = 500
+
+// The maximum number of blobs that can be returned in a single request.
+const LIST_BLOBS_MAX_LIMIT int = 1000
+
+type ListBlobsResponse struct {
+	Cursor string   `json:"cursor,omitempty"`
+	CIDs   []string `json:"cids"`
+}
+
+type ListBlobsHandlerOptions struct {
+	AccountsDatabase pds.AccountsDatabase
+	BlobsDatabase    pds.BlobsDatabase
+}
+
+func ListBlobsHandler(opts *ListBlobsHandlerOptions) (http.Handler, error) {
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		logger := slog.LoggerWithRequest(req, nil)
+
+		if req.Method != ListBlobsHandlerMethod {
+			logger.Error("Method not allowed", "method", req.Method)
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		did, err := sanitize.GetString(req, "did")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "did", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if did == "" {
+			logger.Error("Missing parameter", "parameter", "did")
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		logger = logger.With("did", did)
+
+		limit := LIST_BLOBS_DEFAULT_LIMIT
+
+		str_limit, err := sanitize.GetString(req, "limit")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "limit", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if str_limit != "" {
+
+			v, err := strconv.Atoi(str_limit)
+
+			if err != nil || v < 1 || v > LIST_BLOBS_MAX_LIMIT {
+				logger.Error("Invalid parameter", "parameter", "limit", "value", str_limit)
+				http.Error(rsp, "Bad request", http.StatusBadRequest)
+				return
+			}
+
+			limit = v
+		}
+
+		cursor, err := sanitize.GetString(req, "cursor")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "cursor", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		since, err := sanitize.GetString(req, "since")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "since", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if since != "" {
+
+			_, err := syntax.ParseTID(since)
+
+			if err != nil {
+				logger.Error("Invalid parameter", "parameter", "since", "value", since, "error", err)
+				http.Error(rsp, "Bad request", http.StatusBadRequest)
+				return
+			}
+
+			logger = logger.With("since", since)
+		}
+
+		// Only blobs referenced by records written by commits after 'since' are listed
+
+		list_opts := &pds.ListBlobsOptions{
+			DID:    did,
+			Limit:  limit,
+			Cursor: cursor,
+			Since:  since,
+		}
+
+		ctx := req.Context()
+
+		acct, err := pds.GetAccount(ctx, opts.AccountsDatabase, did)
+
+		if err != nil {
+
+			if err == atproto.ErrNotFound {
+				logger.Error("Account not found")
+				xrpc.Error(rsp, "RepoNotFound", "Could not find repo for DID", http.StatusBadRequest)
+			} else {
+				logger.Error("Failed to retrieve account", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		if inactiveRepoError(rsp, logger, acct) {
+			return
+		}
+
+		list_opts.DID = acct.DID
+
+		cids := make([]string, 0)
+
+		for b, err := range pds.ListBlobs(ctx, opts.BlobsDatabase, list_opts) {
+
+			if err != nil {
+				logger.Error("Failed to list blobs", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			cids = append(cids, b.CID)
+		}
+
+		list_rsp := ListBlobsResponse{
+			CIDs: cids,
+		}
+
+		// Blobs are listed in order of their CIDs so the last CID in a full page is the cursor for the next page
+
+		if len(cids) == limit {
+			list_rsp.Cursor = cids[len(cids)-1]
+		}
+
+		rsp.Header().Set("Content-type", "application/json")
+
+		enc := json.NewEncoder(rsp)
+		err = enc.Encode(list_rsp)
+
+		if err != nil {
+			logger.Error("Failed to encode response", "error", err)
+			http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	return http.HandlerFunc(fn), nil
+}
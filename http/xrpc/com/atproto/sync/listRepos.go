@@ -0,0 +1,155 @@
+package sync
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/aaronland/go-http/v3/sanitize"
+	"github.com/aaronland/go-http/v3/slog"
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+const ListReposHandlerURI string = "/xrpc/com.atproto.sync.listRepos"
+const ListReposHandlerMethod string = http.MethodGet
+
+// The default number of repos to return if no limit is specified.
+const LIST_REPOS_DEFAULT_LIMIT int = 500
+
+// The maximum number of repos that can be returned in a single request.
+const LIST_REPOS_MAX_LIMIT int = 1000
+
+type ListReposRepo struct {
+	DID string `json:"did"`
+	// The CID of the current commit for the repo.
+	Head   string `json:"head"`
+	Rev    string `json:"rev"`
+	Active bool   `json:"active"`
+	// If active is false, the reason the repo is not active.
+	Status string `json:"status,omitempty"`
+}
+
+type ListReposResponse struct {
+	Cursor string           `json:"cursor,omitempty"`
+	Repos  []*ListReposRepo `json:"repos"`
+}
+
+type ListReposHandlerOptions struct {
+	AccountsDatabase pds.AccountsDatabase
+	CommitsDatabase  pds.CommitsDatabase
+}
+
+func ListReposHandler(opts *ListReposHandlerOptions) (http.Handler, error) {
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		logger := slog.LoggerWithRequest(req, nil)
+
+		if req.Method != ListReposHandlerMethod {
+			logger.Error("Method not allowed", "method", req.Method)
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		limit := LIST_REPOS_DEFAULT_LIMIT
+
+		str_limit, err := sanitize.GetString(req, "limit")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "limit", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if str_limit != "" {
+
+			v, err := strconv.Atoi(str_limit)
+
+			if err != nil || v < 1 || v > LIST_REPOS_MAX_LIMIT {
+				logger.Error("Invalid parameter", "parameter", "limit", "value", str_limit)
+				http.Error(rsp, "Bad request", http.StatusBadRequest)
+				return
+			}
+
+			limit = v
+		}
+
+		cursor, err := sanitize.GetString(req, "cursor")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "cursor", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		ctx := req.Context()
+
+		list_opts := &pds.ListAccountsOptions{
+			Limit:  limit,
+			Cursor: cursor,
+		}
+
+		repos := make([]*ListReposRepo, 0)
+		count := 0
+		last_did := ""
+
+		for acct, err := range pds.ListAccounts(ctx, opts.AccountsDatabase, list_opts) {
+
+			if err != nil {
+				logger.Error("Failed to list accounts", "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			count += 1
+			last_did = acct.DID
+
+			commit, err := pds.GetLatestCommitForDID(ctx, opts.CommitsDatabase, acct.DID)
+
+			if err != nil {
+
+				// Accounts created before repositories were initialized with an empty commit (see pds.CreateRepo)
+				// which have not written anything since do not have a repo
+				if err == atproto.ErrNotFound {
+					continue
+				}
+
+				logger.Error("Failed to retrieve latest commit", "did", acct.DID, "error", err)
+				http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			repos = append(repos, &ListReposRepo{
+				DID:    acct.DID,
+				Head:   commit.CID,
+				Rev:    commit.Rev,
+				Active: acct.IsActive(),
+				Status: acct.Status(),
+			})
+		}
+
+		list_rsp := ListReposResponse{
+			Repos: repos,
+		}
+
+		// Accounts are listed in order of their DIDs so the last DID in a full page is the cursor for the next page
+
+		if count == limit {
+			list_rsp.Cursor = last_did
+		}
+
+		rsp.Header().Set("Content-type", "application/json")
+
+		enc := json.NewEncoder(rsp)
+		err = enc.Encode(list_rsp)
+
+		if err != nil {
+			logger.Error("Failed to encode response", "error", err)
+			http.Error(rsp, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	return http.HandlerFunc(fn), nil
+}
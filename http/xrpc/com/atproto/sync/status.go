@@ -0,0 +1,24 @@
+package sync
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/sfomuseum/go-atproto/http/xrpc"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+// inactiveRepoError writes the lexicon error for 'acct' to 'rsp' if the account is not active and returns true.
+// If the account is active nothing is written and false is returned.
+func inactiveRepoError(rsp http.ResponseWriter, logger *slog.Logger, acct *pds.Account) bool {
+
+	if acct.IsActive() {
+		return false
+	}
+
+	// Accounts can only be inactive because they have been deleted, which are reported as not found
+
+	logger.Error("Account is not active", "status", acct.Status())
+	xrpc.Error(rsp, "RepoNotFound", "Could not find repo for DID", http.StatusBadRequest)
+	return true
+}
@@ -0,0 +1,229 @@
+package sync
+
+// https://atproto.com/specs/event-stream
+// https://atproto.com/specs/sync#firehose
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aaronland/go-http/v3/sanitize"
+	"github.com/aaronland/go-http/v3/slog"
+	"github.com/gorilla/websocket"
+	"github.com/sfomuseum/go-atproto/pds"
+)
+
+const SubscribeReposHandlerURI string = "/xrpc/com.atproto.sync.subscribeRepos"
+const SubscribeReposHandlerMethod string = http.MethodGet
+
+// The interval at which ping messages are sent to subscribers.
+const SUBSCRIBE_REPOS_PING_INTERVAL time.Duration = 30 * time.Second
+
+// The maximum amount of time to wait for a message to be written to a subscriber.
+const SUBSCRIBE_REPOS_WRITE_TIMEOUT time.Duration = 10 * time.Second
+
+type SubscribeReposHandlerOptions struct {
+	Sequencer *pds.Sequencer
+}
+
+func SubscribeReposHandler(opts *SubscribeReposHandlerOptions) (http.Handler, error) {
+
+	// Subscribers are (typically) relays rather than browsers so there is no origin to check
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(req *http.Request) bool {
+			return true
+		},
+	}
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+
+		logger := slog.LoggerWithRequest(req, nil)
+
+		if req.Method != SubscribeReposHandlerMethod {
+			logger.Error("Method not allowed", "method", req.Method)
+			http.Error(rsp, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var cursor int64
+
+		str_cursor, err := sanitize.GetString(req, "cursor")
+
+		if err != nil {
+			logger.Error("Invalid parameter", "parameter", "cursor", "error", err)
+			http.Error(rsp, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		if str_cursor != "" {
+
+			v, err := strconv.ParseInt(str_cursor, 10, 64)
+
+			if err != nil || v < 0 {
+				logger.Error("Invalid parameter", "parameter", "cursor", "value", str_cursor)
+				http.Error(rsp, "Bad request", http.StatusBadRequest)
+				return
+			}
+
+			cursor = v
+		}
+
+		logger = logger.With("cursor", cursor)
+
+		conn, err := upgrader.Upgrade(rsp, req, nil)
+
+		if err != nil {
+			// Upgrade will have already written an HTTP error to rsp
+			logger.Error("Failed to upgrade connection", "error", err)
+			return
+		}
+
+		defer conn.Close()
+
+		write := func(frame []byte) error {
+			conn.SetWriteDeadline(time.Now().Add(SUBSCRIBE_REPOS_WRITE_TIMEOUT))
+			return conn.WriteMessage(websocket.BinaryMessage, frame)
+		}
+
+		// Errors are sent as error frames after which the connection is closed, per the spec
+
+		write_error := func(name string, message string) {
+
+			frame, err := pds.NewErrorFrame(name, message)
+
+			if err != nil {
+				logger.Error("Failed to create error frame", "name", name, "error", err)
+				return
+			}
+
+			err = write(frame)
+
+			if err != nil {
+				logger.Error("Failed to write error frame", "name", name, "error", err)
+			}
+		}
+
+		ctx := req.Context()
+
+		sub, err := opts.Sequencer.Subscribe(ctx, cursor)
+
+		if err != nil {
+			logger.Error("Failed to subscribe", "error", err)
+			write_error("FutureCursor", err.Error())
+			return
+		}
+
+		defer sub.Cancel()
+
+		if sub.Outdated {
+
+			frame, err := pds.NewInfoFrame("OutdatedCursor", "Requested cursor exceeded limit. Possibly missing events")
+
+			if err != nil {
+				logger.Error("Failed to create info frame", "error", err)
+				return
+			}
+
+			err = write(frame)
+
+			if err != nil {
+				logger.Error("Failed to write info frame", "error", err)
+				return
+			}
+		}
+
+		// Subscribers are not expected to send anything but reading is necessary to process
+		// control messages (ping, pong, close) and to notice when the connection has gone away
+
+		done := make(chan bool)
+
+		go func() {
+
+			defer close(done)
+
+			for {
+				_, _, err := conn.ReadMessage()
+
+				if err != nil {
+					return
+				}
+			}
+		}()
+
+		// Events emitted while the backfill is being written are buffered in sub.Events. The backfill
+		// ends with the last event emitted before the subscription was created.
+
+		write_event := func(e *pds.Event) error {
+
+			frame, err := e.Frame()
+
+			if err != nil {
+				return fmt.Errorf("Failed to create frame for event %d, %w", e.Seq, err)
+			}
+
+			err = write(frame)
+
+			if err != nil {
+				return fmt.Errorf("Failed to write event %d, %w", e.Seq, err)
+			}
+
+			return nil
+		}
+
+		for e, err := range sub.Backfill(ctx) {
+
+			if err != nil {
+				logger.Error("Failed to read backfill", "error", err)
+				write_error("InternalError", "Failed to read events")
+				return
+			}
+
+			err = write_event(e)
+
+			if err != nil {
+				logger.Error("Failed to write backfill", "error", err)
+				return
+			}
+		}
+
+		ticker := time.NewTicker(SUBSCRIBE_REPOS_PING_INTERVAL)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				logger.Debug("Subscriber disconnected")
+				return
+			case <-ticker.C:
+
+				err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(SUBSCRIBE_REPOS_WRITE_TIMEOUT))
+
+				if err != nil {
+					logger.Error("Failed to send ping", "error", err)
+					return
+				}
+
+			case e, ok := <-sub.Events:
+
+				if !ok {
+					logger.Warn("Subscriber is too slow, closing connection")
+					write_error("ConsumerTooSlow", "Subscriber did not keep up with events")
+					return
+				}
+
+				err := write_event(e)
+
+				if err != nil {
+					logger.Error("Failed to write event", "error", err)
+					return
+				}
+			}
+		}
+	}
+
+	return http.HandlerFunc(fn), nil
+}
@@ -0,0 +1,29 @@
+package xrpc
+
+// https://atproto.com/specs/xrpc#error-responses
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ErrorResponse is the JSON body returned by XRPC methods for named (lexicon) errors.
+type ErrorResponse struct {
+	Error   string `json:"error"`
+	Message string `json:"message,omitempty"`
+}
+
+// Error writes an XRPC error response with the name 'name' and a human-readable 'message' to 'rsp'.
+func Error(rsp http.ResponseWriter, name string, message string, status int) {
+
+	err_rsp := ErrorResponse{
+		Error:   name,
+		Message: message,
+	}
+
+	rsp.Header().Set("Content-type", "application/json")
+	rsp.WriteHeader(status)
+
+	enc := json.NewEncoder(rsp)
+	enc.Encode(err_rsp)
+}
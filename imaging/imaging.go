@@ -0,0 +1,181 @@
+// Package imaging provides methods for normalizing images uploaded as blobs: removing (Exif, XMP, IPTC) metadata,
+// applying Exif orientation and optionally downsizing images to a maximum dimension or size.
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/sfomuseum/go-atproto"
+	_ "golang.org/x/image/webp"
+)
+
+const MEDIA_TYPE_JPEG string = "image/jpeg"
+const MEDIA_TYPE_PNG string = "image/png"
+const MEDIA_TYPE_WEBP string = "image/webp"
+
+// The default quality used to (re) encode JPEG images.
+const DEFAULT_JPEG_QUALITY int = 90
+
+// The lowest quality that JPEG images will be encoded with when reducing their size to `Options.MaxSize`.
+const MIN_JPEG_QUALITY int = 60
+
+// The smallest dimension an image will be downsized to when reducing its size to `Options.MaxSize`.
+const MIN_DIMENSION int = 64
+
+// The maximum number of pixels (width * height) in an image. Larger images are rejected before they are decoded
+// since decoding allocates memory in proportion to the number of pixels rather than the size of the image data.
+const MAX_PIXELS int64 = 64 * 1024 * 1024
+
+// Options defines the limits applied to images by `Normalize`.
+type Options struct {
+	// The maximum width or height of an image. If 0 images are not downsized to fit a dimension.
+	MaxDimension int
+	// The maximum size, in bytes, of an image. If 0 images are not downsized to fit a size.
+	MaxSize int64
+	// The quality used to (re) encode JPEG images. If 0 `DEFAULT_JPEG_QUALITY` is used.
+	JPEGQuality int
+}
+
+// Image is a normalized image.
+type Image struct {
+	Body      []byte
+	MediaType string
+	Width     int
+	Height    int
+}
+
+// IsSupported returns true if images with 'media_type' can be normalized.
+func IsSupported(media_type string) bool {
+
+	switch media_type {
+	case MEDIA_TYPE_JPEG, MEDIA_TYPE_PNG, MEDIA_TYPE_WEBP:
+		return true
+	default:
+		return false
+	}
+}
+
+// Normalize removes metadata from 'body', an image with 'media_type', and, if necessary, rotates it according to its
+// Exif orientation and downsizes it to fit 'opts'. Metadata is removed without decoding the image so images are only
+// re-encoded if they need to be rotated or downsized. Since there is no WebP encoder, WebP images which need to be
+// re-encoded are returned as JPEG images (or PNG images if they have transparency). If an image has more than
+// `MAX_PIXELS` pixels or can not be reduced to 'opts.MaxSize' an error wrapping `atproto.ErrBlobTooLarge` is returned.
+func Normalize(body []byte, media_type string, opts *Options) (*Image, error) {
+
+	var stripped []byte
+	orientation := 1
+
+	var err error
+
+	switch media_type {
+	case MEDIA_TYPE_JPEG:
+		stripped, orientation, err = stripJPEG(body)
+	case MEDIA_TYPE_PNG:
+		stripped, orientation, err = stripPNG(body)
+	case MEDIA_TYPE_WEBP:
+		stripped, orientation, err = stripWebP(body)
+	default:
+		return nil, fmt.Errorf("Unsupported media type, %s", media_type)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(stripped))
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode image config, %w", err)
+	}
+
+	if int64(cfg.Width)*int64(cfg.Height) > MAX_PIXELS {
+		return nil, fmt.Errorf("%w, image (%dx%d) exceeds maximum of %d pixels", atproto.ErrBlobTooLarge, cfg.Width, cfg.Height, MAX_PIXELS)
+	}
+
+	too_wide := opts.MaxDimension > 0 && max(cfg.Width, cfg.Height) > opts.MaxDimension
+	too_big := opts.MaxSize > 0 && int64(len(stripped)) > opts.MaxSize
+
+	if orientation == 1 && !too_wide && !too_big {
+
+		im := &Image{
+			Body:      stripped,
+			MediaType: media_type,
+			Width:     cfg.Width,
+			Height:    cfg.Height,
+		}
+
+		return im, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(stripped))
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode image, %w", err)
+	}
+
+	src = orient(src, orientation)
+
+	if opts.MaxDimension > 0 {
+		src = fit(src, opts.MaxDimension)
+	}
+
+	out_type := media_type
+
+	if out_type == MEDIA_TYPE_WEBP {
+
+		out_type = MEDIA_TYPE_JPEG
+
+		if !isOpaque(src) {
+			out_type = MEDIA_TYPE_PNG
+		}
+	}
+
+	quality := opts.JPEGQuality
+
+	if quality <= 0 {
+		quality = DEFAULT_JPEG_QUALITY
+	}
+
+	// Reduce the quality (for JPEG images) and then the dimensions of the image until it fits 'opts.MaxSize'
+
+	for {
+
+		out, err := encode(src, out_type, quality)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if opts.MaxSize <= 0 || int64(len(out)) <= opts.MaxSize {
+
+			b := src.Bounds()
+
+			im := &Image{
+				Body:      out,
+				MediaType: out_type,
+				Width:     b.Dx(),
+				Height:    b.Dy(),
+			}
+
+			return im, nil
+		}
+
+		if out_type == MEDIA_TYPE_JPEG && quality > MIN_JPEG_QUALITY {
+			quality = max(quality-10, MIN_JPEG_QUALITY)
+			continue
+		}
+
+		b := src.Bounds()
+		dim := max(b.Dx(), b.Dy()) * 3 / 4
+
+		if dim < MIN_DIMENSION {
+			return nil, fmt.Errorf("%w, unable to reduce image to %d bytes", atproto.ErrBlobTooLarge, opts.MaxSize)
+		}
+
+		src = fit(src, dim)
+	}
+}
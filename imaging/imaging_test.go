@@ -0,0 +1,411 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/sfomuseum/go-atproto"
+)
+
+// testTIFF returns Exif (TIFF) data, without the JPEG Exif header, whose first IFD contains an orientation tag.
+func testTIFF(order binary.ByteOrder, orientation uint16) []byte {
+
+	var buf bytes.Buffer
+
+	if order == binary.LittleEndian {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+
+	binary.Write(&buf, order, uint16(42))
+	binary.Write(&buf, order, uint32(8))
+
+	// One IFD entry: tag, type (SHORT), count and value (padded to 4 bytes)
+	binary.Write(&buf, order, uint16(1))
+	binary.Write(&buf, order, exif_orientation_tag)
+	binary.Write(&buf, order, uint16(3))
+	binary.Write(&buf, order, uint32(1))
+	binary.Write(&buf, order, orientation)
+	binary.Write(&buf, order, uint16(0))
+
+	// Offset of the next IFD
+	binary.Write(&buf, order, uint32(0))
+
+	return buf.Bytes()
+}
+
+func testImage(width int, height int) image.Image {
+
+	im := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			im.Set(x, y, color.RGBA{uint8(x * 10), uint8(y * 10), 128, 255})
+		}
+	}
+
+	return im
+}
+
+func testJPEG(t *testing.T, width int, height int, orientation uint16) []byte {
+
+	var buf bytes.Buffer
+
+	err := jpeg.Encode(&buf, testImage(width, height), nil)
+
+	if err != nil {
+		t.Fatalf("Failed to encode JPEG, %v", err)
+	}
+
+	body := buf.Bytes()
+
+	segment := func(marker byte, data []byte) []byte {
+		b := []byte{0xFF, marker}
+		b = binary.BigEndian.AppendUint16(b, uint16(len(data)+2))
+		return append(b, data...)
+	}
+
+	exif := append(bytes.Clone(exif_header), testTIFF(binary.BigEndian, orientation)...)
+
+	var out bytes.Buffer
+	out.Write(body[0:2])
+	out.Write(segment(jpeg_marker_app1, exif))
+	out.Write(segment(jpeg_marker_app13, []byte("Photoshop 3.0\x00")))
+	out.Write(segment(jpeg_marker_com, []byte("comment")))
+	out.Write(body[2:])
+
+	return out.Bytes()
+}
+
+func pngChunk(chunk_type string, data []byte) []byte {
+
+	b := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	b = append(b, chunk_type...)
+	b = append(b, data...)
+
+	crc := crc32.ChecksumIEEE(append([]byte(chunk_type), data...))
+	return binary.BigEndian.AppendUint32(b, crc)
+}
+
+func testPNG(t *testing.T, width int, height int, exif []byte) []byte {
+
+	var buf bytes.Buffer
+
+	err := png.Encode(&buf, testImage(width, height))
+
+	if err != nil {
+		t.Fatalf("Failed to encode PNG, %v", err)
+	}
+
+	body := buf.Bytes()
+
+	// The signature and IHDR chunk (13 bytes of data)
+	ihdr_end := len(png_signature) + 12 + 13
+
+	var out bytes.Buffer
+	out.Write(body[0:ihdr_end])
+	out.Write(pngChunk("tEXt", []byte("Comment\x00hello")))
+
+	if exif != nil {
+		out.Write(pngChunk("eXIf", exif))
+	}
+
+	out.Write(body[ihdr_end:])
+
+	return out.Bytes()
+}
+
+func webpChunk(fourcc string, data []byte) []byte {
+
+	b := append([]byte(fourcc), binary.LittleEndian.AppendUint32(nil, uint32(len(data)))...)
+	b = append(b, data...)
+
+	if len(data)%2 == 1 {
+		b = append(b, 0x00)
+	}
+
+	return b
+}
+
+// testWebP returns an extended format WebP container with EXIF and XMP chunks. The image data itself is not valid.
+func testWebP(exif []byte) []byte {
+
+	vp8x := make([]byte, 10)
+	vp8x[0] = webp_vp8x_flag_exif | webp_vp8x_flag_xmp
+
+	var chunks bytes.Buffer
+	chunks.Write(webpChunk("VP8X", vp8x))
+	chunks.Write(webpChunk("VP8L", []byte("image data")))
+	chunks.Write(webpChunk("EXIF", exif))
+	chunks.Write(webpChunk("XMP ", []byte("<x:xmpmeta/>")))
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(4+chunks.Len()))
+	buf.WriteString("WEBP")
+	chunks.WriteTo(&buf)
+
+	return buf.Bytes()
+}
+
+func TestExifOrientation(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		tiff        []byte
+		orientation int
+	}{
+		{"big endian", testTIFF(binary.BigEndian, 6), 6},
+		{"little endian", testTIFF(binary.LittleEndian, 8), 8},
+		{"out of range", testTIFF(binary.BigEndian, 9), 1},
+		{"zero", testTIFF(binary.BigEndian, 0), 1},
+		{"invalid byte order", append([]byte("XX"), testTIFF(binary.BigEndian, 6)[2:]...), 1},
+		{"truncated", testTIFF(binary.BigEndian, 6)[0:12], 1},
+		{"empty", []byte{}, 1},
+	}
+
+	for _, tt := range tests {
+
+		orientation := exifOrientation(tt.tiff)
+
+		if orientation != tt.orientation {
+			t.Fatalf("Expected orientation %d for %s, got %d", tt.orientation, tt.name, orientation)
+		}
+	}
+}
+
+func TestStripJPEG(t *testing.T) {
+
+	body := testJPEG(t, 4, 2, 6)
+
+	stripped, orientation, err := stripJPEG(body)
+
+	if err != nil {
+		t.Fatalf("Failed to strip JPEG, %v", err)
+	}
+
+	if orientation != 6 {
+		t.Fatalf("Expected orientation 6, got %d", orientation)
+	}
+
+	for _, v := range []string{"Exif", "Photoshop", "comment"} {
+
+		if bytes.Contains(stripped, []byte(v)) {
+			t.Fatalf("Expected %s to be removed", v)
+		}
+	}
+
+	_, err = jpeg.Decode(bytes.NewReader(stripped))
+
+	if err != nil {
+		t.Fatalf("Failed to decode stripped JPEG, %v", err)
+	}
+
+	for _, invalid := range [][]byte{[]byte("not a jpeg"), body[0:6]} {
+
+		_, _, err := stripJPEG(invalid)
+
+		if err == nil {
+			t.Fatalf("Expected an error for invalid JPEG")
+		}
+	}
+}
+
+func TestStripPNG(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		exif        []byte
+		orientation int
+	}{
+		{"no exif", nil, 1},
+		{"exif", testTIFF(binary.BigEndian, 6), 6},
+		{"exif with header", append(bytes.Clone(exif_header), testTIFF(binary.LittleEndian, 3)...), 3},
+	}
+
+	for _, tt := range tests {
+
+		body := testPNG(t, 4, 2, tt.exif)
+
+		stripped, orientation, err := stripPNG(body)
+
+		if err != nil {
+			t.Fatalf("Failed to strip PNG for %s, %v", tt.name, err)
+		}
+
+		if orientation != tt.orientation {
+			t.Fatalf("Expected orientation %d for %s, got %d", tt.orientation, tt.name, orientation)
+		}
+
+		for _, v := range []string{"eXIf", "tEXt"} {
+
+			if bytes.Contains(stripped, []byte(v)) {
+				t.Fatalf("Expected %s chunk to be removed for %s", v, tt.name)
+			}
+		}
+
+		_, err = png.Decode(bytes.NewReader(stripped))
+
+		if err != nil {
+			t.Fatalf("Failed to decode stripped PNG for %s, %v", tt.name, err)
+		}
+	}
+
+	body := testPNG(t, 4, 2, nil)
+
+	for _, invalid := range [][]byte{[]byte("not a png"), body[0 : len(body)-4], body[0 : len(body)-12]} {
+
+		_, _, err := stripPNG(invalid)
+
+		if err == nil {
+			t.Fatalf("Expected an error for invalid PNG")
+		}
+	}
+}
+
+func TestStripWebP(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		exif        []byte
+		orientation int
+	}{
+		{"exif", testTIFF(binary.LittleEndian, 6), 6},
+		{"exif with header", append(bytes.Clone(exif_header), testTIFF(binary.BigEndian, 5)...), 5},
+		{"odd length exif", append(testTIFF(binary.BigEndian, 7), 0x00), 7},
+	}
+
+	for _, tt := range tests {
+
+		stripped, orientation, err := stripWebP(testWebP(tt.exif))
+
+		if err != nil {
+			t.Fatalf("Failed to strip WebP for %s, %v", tt.name, err)
+		}
+
+		if orientation != tt.orientation {
+			t.Fatalf("Expected orientation %d for %s, got %d", tt.orientation, tt.name, orientation)
+		}
+
+		for _, v := range []string{"EXIF", "XMP "} {
+
+			if bytes.Contains(stripped, []byte(v)) {
+				t.Fatalf("Expected %s chunk to be removed for %s", v, tt.name)
+			}
+		}
+
+		riff_size := int(binary.LittleEndian.Uint32(stripped[4:8]))
+
+		if riff_size+8 != len(stripped) {
+			t.Fatalf("Expected RIFF size %d for %s, got %d", len(stripped)-8, tt.name, riff_size)
+		}
+
+		// The VP8X chunk is first and its flags are the first byte of its data
+
+		if string(stripped[12:16]) != "VP8X" {
+			t.Fatalf("Expected VP8X chunk for %s", tt.name)
+		}
+
+		if stripped[20]&(webp_vp8x_flag_exif|webp_vp8x_flag_xmp) != 0 {
+			t.Fatalf("Expected EXIF and XMP flags to be cleared for %s", tt.name)
+		}
+
+		if !bytes.Contains(stripped, []byte("image data")) {
+			t.Fatalf("Expected image data to be kept for %s", tt.name)
+		}
+	}
+
+	body := testWebP(testTIFF(binary.BigEndian, 1))
+
+	for _, invalid := range [][]byte{[]byte("not a webp"), body[0 : len(body)-4]} {
+
+		_, _, err := stripWebP(invalid)
+
+		if err == nil {
+			t.Fatalf("Expected an error for invalid WebP")
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		body       []byte
+		media_type string
+		opts       *Options
+		width      int
+		height     int
+		out_type   string
+	}{
+		{"jpeg", testJPEG(t, 4, 2, 1), MEDIA_TYPE_JPEG, &Options{}, 4, 2, MEDIA_TYPE_JPEG},
+		{"jpeg rotated", testJPEG(t, 4, 2, 6), MEDIA_TYPE_JPEG, &Options{}, 2, 4, MEDIA_TYPE_JPEG},
+		{"png", testPNG(t, 4, 2, nil), MEDIA_TYPE_PNG, &Options{}, 4, 2, MEDIA_TYPE_PNG},
+		{"png rotated", testPNG(t, 4, 2, testTIFF(binary.BigEndian, 8)), MEDIA_TYPE_PNG, &Options{}, 2, 4, MEDIA_TYPE_PNG},
+		{"png mirrored", testPNG(t, 4, 2, testTIFF(binary.BigEndian, 2)), MEDIA_TYPE_PNG, &Options{}, 4, 2, MEDIA_TYPE_PNG},
+		{"png downsized", testPNG(t, 128, 64, nil), MEDIA_TYPE_PNG, &Options{MaxDimension: 32}, 32, 16, MEDIA_TYPE_PNG},
+	}
+
+	for _, tt := range tests {
+
+		im, err := Normalize(tt.body, tt.media_type, tt.opts)
+
+		if err != nil {
+			t.Fatalf("Failed to normalize %s, %v", tt.name, err)
+		}
+
+		if im.Width != tt.width || im.Height != tt.height {
+			t.Fatalf("Expected %dx%d for %s, got %dx%d", tt.width, tt.height, tt.name, im.Width, im.Height)
+		}
+
+		if im.MediaType != tt.out_type {
+			t.Fatalf("Expected %s for %s, got %s", tt.out_type, tt.name, im.MediaType)
+		}
+
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(im.Body))
+
+		if err != nil {
+			t.Fatalf("Failed to decode normalized image for %s, %v", tt.name, err)
+		}
+
+		if cfg.Width != tt.width || cfg.Height != tt.height {
+			t.Fatalf("Expected normalized image to be %dx%d for %s, got %dx%d", tt.width, tt.height, tt.name, cfg.Width, cfg.Height)
+		}
+	}
+
+	_, err := Normalize(testPNG(t, 4, 2, nil), "image/gif", &Options{})
+
+	if err == nil {
+		t.Fatalf("Expected an error for unsupported media type")
+	}
+}
+
+func TestNormalizeMaxPixels(t *testing.T) {
+
+	// A PNG whose header declares more than MAX_PIXELS pixels but which has no image data
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:4], 100000)
+	binary.BigEndian.PutUint32(ihdr[4:8], 100000)
+	ihdr[8] = 8
+	ihdr[9] = 6
+
+	var body bytes.Buffer
+	body.Write(png_signature)
+	body.Write(pngChunk("IHDR", ihdr))
+	body.Write(pngChunk("IEND", nil))
+
+	_, err := Normalize(body.Bytes(), MEDIA_TYPE_PNG, &Options{})
+
+	if !errors.Is(err, atproto.ErrBlobTooLarge) {
+		t.Fatalf("Expected blob too large error, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package imaging
+
+// https://www.w3.org/Graphics/JPEG/itu-t81.pdf (B.1.1.2 Markers)
+// https://www.cipa.jp/std/documents/e/DC-X008-Translation-2019-E.pdf (Exif)
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+const jpeg_marker_soi byte = 0xD8
+const jpeg_marker_eoi byte = 0xD9
+const jpeg_marker_sos byte = 0xDA
+const jpeg_marker_app1 byte = 0xE1
+const jpeg_marker_app13 byte = 0xED
+const jpeg_marker_com byte = 0xFE
+
+const exif_orientation_tag uint16 = 0x0112
+
+var exif_header = []byte("Exif\x00\x00")
+
+// stripJPEG returns a copy of 'body' without APP1 (Exif, XMP), APP13 (IPTC) and COM (comment) segments along with the
+// Exif orientation of the image (1 if it is not defined). All other segments, including ICC colour profiles, are kept.
+func stripJPEG(body []byte) ([]byte, int, error) {
+
+	if len(body) < 4 || body[0] != 0xFF || body[1] != jpeg_marker_soi {
+		return nil, 0, fmt.Errorf("Invalid JPEG, missing SOI marker")
+	}
+
+	var buf bytes.Buffer
+	buf.Write(body[0:2])
+
+	orientation := 1
+	offset := 2
+
+	for offset < len(body) {
+
+		if body[offset] != 0xFF {
+			return nil, 0, fmt.Errorf("Invalid JPEG, expected marker at offset %d", offset)
+		}
+
+		// Markers may be preceded by any number of 0xFF fill bytes
+
+		for offset < len(body) && body[offset] == 0xFF {
+			offset += 1
+		}
+
+		if offset >= len(body) {
+			return nil, 0, fmt.Errorf("Invalid JPEG, truncated marker")
+		}
+
+		marker := body[offset]
+		start := offset - 1
+		offset += 1
+
+		// Everything from the start of scan onwards is entropy-coded image data which is copied as-is
+
+		if marker == jpeg_marker_sos || marker == jpeg_marker_eoi {
+			buf.Write(body[start:])
+			return buf.Bytes(), orientation, nil
+		}
+
+		// Standalone markers (TEM and RSTn) have no length
+
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			buf.Write(body[start:offset])
+			continue
+		}
+
+		if offset+2 > len(body) {
+			return nil, 0, fmt.Errorf("Invalid JPEG, truncated segment length")
+		}
+
+		length := int(binary.BigEndian.Uint16(body[offset : offset+2]))
+
+		if length < 2 || offset+length > len(body) {
+			return nil, 0, fmt.Errorf("Invalid JPEG, invalid segment length at offset %d", offset)
+		}
+
+		data := body[offset+2 : offset+length]
+		end := offset + length
+		offset = end
+
+		switch marker {
+		case jpeg_marker_app1:
+
+			if bytes.HasPrefix(data, exif_header) {
+				orientation = exifOrientation(data[len(exif_header):])
+			}
+
+			continue
+
+		case jpeg_marker_app13, jpeg_marker_com:
+			continue
+		}
+
+		buf.Write(body[start:end])
+	}
+
+	return nil, 0, fmt.Errorf("Invalid JPEG, missing image data")
+}
+
+// exifOrientation returns the value of the orientation tag in the first IFD of 'tiff' (Exif data without its header),
+// or 1 if the tag is not present or the data can not be parsed.
+func exifOrientation(tiff []byte) int {
+
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+
+	switch string(tiff[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd_offset := int(order.Uint32(tiff[4:8]))
+
+	if ifd_offset < 8 || ifd_offset+2 > len(tiff) {
+		return 1
+	}
+
+	count := int(order.Uint16(tiff[ifd_offset : ifd_offset+2]))
+
+	for i := 0; i < count; i++ {
+
+		entry := ifd_offset + 2 + (i * 12)
+
+		if entry+12 > len(tiff) {
+			return 1
+		}
+
+		if order.Uint16(tiff[entry:entry+2]) != exif_orientation_tag {
+			continue
+		}
+
+		v := int(order.Uint16(tiff[entry+8 : entry+10]))
+
+		if v < 1 || v > 8 {
+			return 1
+		}
+
+		return v
+	}
+
+	return 1
+}
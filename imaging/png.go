@@ -0,0 +1,67 @@
+package imaging
+
+// https://www.w3.org/TR/png-3/#5Chunk-layout
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+var png_signature = []byte("\x89PNG\r\n\x1a\n")
+
+// Ancillary chunks which may contain metadata (Exif, XMP, free-form text and timestamps) and are removed by `stripPNG`.
+var png_metadata_chunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+// stripPNG returns a copy of 'body' without any metadata chunks along with the Exif orientation of the image (1 if it
+// is not defined). Chunks are copied as-is so their CRCs remain valid.
+func stripPNG(body []byte) ([]byte, int, error) {
+
+	if !bytes.HasPrefix(body, png_signature) {
+		return nil, 0, fmt.Errorf("Invalid PNG, missing signature")
+	}
+
+	orientation := 1
+
+	var buf bytes.Buffer
+	buf.Write(png_signature)
+
+	offset := len(png_signature)
+
+	for offset+8 <= len(body) {
+
+		length := int(binary.BigEndian.Uint32(body[offset : offset+4]))
+		chunk_type := string(body[offset+4 : offset+8])
+
+		// Length, type, data and CRC
+		end := offset + 12 + length
+
+		if length < 0 || end > len(body) {
+			return nil, 0, fmt.Errorf("Invalid PNG, truncated %s chunk", chunk_type)
+		}
+
+		// The eXIf chunk should only contain TIFF data but some encoders include the JPEG Exif header
+
+		if chunk_type == "eXIf" {
+			orientation = exifOrientation(bytes.TrimPrefix(body[offset+8:offset+8+length], exif_header))
+		}
+
+		if !png_metadata_chunks[chunk_type] {
+			buf.Write(body[offset:end])
+		}
+
+		offset = end
+
+		if chunk_type == "IEND" {
+			return buf.Bytes(), orientation, nil
+		}
+	}
+
+	return nil, 0, fmt.Errorf("Invalid PNG, missing IEND chunk")
+}
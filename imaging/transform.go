@@ -0,0 +1,123 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+)
+
+// orient returns a copy of 'src' rotated and/or flipped according to the Exif 'orientation' so that it
+// displays correctly without that orientation.
+func orient(src image.Image, orientation int) image.Image {
+
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	b := src.Bounds()
+	w := b.Dx()
+	h := b.Dy()
+
+	dst_w := w
+	dst_h := h
+
+	// Orientations 5 to 8 are rotated by 90 degrees
+	if orientation >= 5 {
+		dst_w = h
+		dst_h = w
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dst_w, dst_h))
+
+	for y := 0; y < dst_h; y++ {
+		for x := 0; x < dst_w; x++ {
+
+			var sx, sy int
+
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+
+			dst.Set(x, y, src.At(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+
+	return dst
+}
+
+// fit returns a copy of 'src' scaled so that neither its width nor its height exceed 'max_dimension'. If
+// 'src' already fits it is returned as-is.
+func fit(src image.Image, max_dimension int) image.Image {
+
+	b := src.Bounds()
+	w := b.Dx()
+	h := b.Dy()
+
+	if w <= max_dimension && h <= max_dimension {
+		return src
+	}
+
+	dst_w := max_dimension
+	dst_h := max_dimension
+
+	if w > h {
+		dst_h = max(1, h*max_dimension/w)
+	} else {
+		dst_w = max(1, w*max_dimension/h)
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dst_w, dst_h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+
+	return dst
+}
+
+func isOpaque(im image.Image) bool {
+
+	o, ok := im.(interface{ Opaque() bool })
+
+	if !ok {
+		return false
+	}
+
+	return o.Opaque()
+}
+
+func encode(im image.Image, media_type string, quality int) ([]byte, error) {
+
+	var buf bytes.Buffer
+	var err error
+
+	switch media_type {
+	case MEDIA_TYPE_JPEG:
+		err = jpeg.Encode(&buf, im, &jpeg.Options{Quality: quality})
+	case MEDIA_TYPE_PNG:
+		enc := &png.Encoder{CompressionLevel: png.BestCompression}
+		err = enc.Encode(&buf, im)
+	default:
+		err = fmt.Errorf("Unsupported media type, %s", media_type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode image, %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,76 @@
+package imaging
+
+// https://developers.google.com/speed/webp/docs/riff_container
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+const webp_vp8x_flag_exif byte = 0x08
+const webp_vp8x_flag_xmp byte = 0x04
+
+// stripWebP returns a copy of 'body' without EXIF and XMP chunks, clearing the corresponding flags in the
+// VP8X (extended format) chunk and updating the size of the RIFF container, along with the Exif orientation
+// of the image (1 if it is not defined).
+func stripWebP(body []byte) ([]byte, int, error) {
+
+	if len(body) < 12 || string(body[0:4]) != "RIFF" || string(body[8:12]) != "WEBP" {
+		return nil, 0, fmt.Errorf("Invalid WebP, missing RIFF header")
+	}
+
+	riff_size := int(binary.LittleEndian.Uint32(body[4:8]))
+
+	if riff_size+8 > len(body) {
+		return nil, 0, fmt.Errorf("Invalid WebP, truncated RIFF container")
+	}
+
+	var chunks bytes.Buffer
+	offset := 12
+
+	orientation := 1
+
+	for offset+8 <= riff_size+8 {
+
+		fourcc := string(body[offset : offset+4])
+		length := int(binary.LittleEndian.Uint32(body[offset+4 : offset+8]))
+
+		// Chunks are padded to an even length
+		end := offset + 8 + length + (length % 2)
+
+		if end > len(body) {
+			return nil, 0, fmt.Errorf("Invalid WebP, truncated %s chunk", fourcc)
+		}
+
+		switch fourcc {
+		case "EXIF":
+			// The EXIF chunk should only contain TIFF data but some encoders include the JPEG Exif header
+			orientation = exifOrientation(bytes.TrimPrefix(body[offset+8:offset+8+length], exif_header))
+		case "XMP ":
+			// pass
+		case "VP8X":
+
+			chunk := bytes.Clone(body[offset:end])
+
+			if length > 0 {
+				chunk[8] &^= webp_vp8x_flag_exif | webp_vp8x_flag_xmp
+			}
+
+			chunks.Write(chunk)
+
+		default:
+			chunks.Write(body[offset:end])
+		}
+
+		offset = end
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(4+chunks.Len()))
+	buf.WriteString("WEBP")
+	chunks.WriteTo(&buf)
+
+	return buf.Bytes(), orientation, nil
+}
@@ -0,0 +1,398 @@
+package mst
+
+// https://atproto.com/specs/repository#mst-structure
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"iter"
+	"sort"
+
+	"github.com/ipfs/go-cid"
+	"github.com/sfomuseum/go-atproto/dagcbor"
+)
+
+// Entry is a key/value pair stored in a Merkle Search Tree.
+type Entry struct {
+	// The key (for atproto repositories "{COLLECTION}/{RKEY}").
+	Key string
+	// The CID of the value (record) associated with Key.
+	Value cid.Cid
+}
+
+// Tree is an immutable Merkle Search Tree derived from a set of entries. Because the shape of an MST
+// is entirely determined by its keys the same set of entries will always produce the same root CID.
+type Tree struct {
+	root  cid.Cid
+	order []cid.Cid
+	nodes map[cid.Cid][]byte
+	// The node each key is stored in
+	keys map[string]cid.Cid
+	// The parent of each (non-root) node
+	parents map[cid.Cid]cid.Cid
+}
+
+type leaf struct {
+	entry  *Entry
+	height int
+}
+
+// HeightForKey returns the layer of the tree that 'key' is stored at. This is derived by counting the
+// number of leading binary zeros in the SHA-256 hash of 'key' and dividing by two (a fanout of 4).
+func HeightForKey(key string) int {
+
+	height := 0
+	h := sha256.Sum256([]byte(key))
+
+	for _, b := range h {
+
+		if b == 0x00 {
+			height += 4
+			continue
+		}
+
+		switch {
+		case b&0xFC == 0x00:
+			height += 3
+		case b&0xF0 == 0x00:
+			height += 2
+		case b&0xC0 == 0x00:
+			height += 1
+		}
+
+		break
+	}
+
+	return height
+}
+
+// NewTree returns a new `Tree` for 'entries'. Keys must be unique.
+func NewTree(entries []*Entry) (*Tree, error) {
+
+	leaves := make([]*leaf, len(entries))
+	max_height := 0
+
+	for i, e := range entries {
+
+		if e.Key == "" {
+			return nil, fmt.Errorf("Invalid entry at offset %d, empty key", i)
+		}
+
+		if !e.Value.Defined() {
+			return nil, fmt.Errorf("Invalid entry for %s, undefined CID", e.Key)
+		}
+
+		h := HeightForKey(e.Key)
+
+		if h > max_height {
+			max_height = h
+		}
+
+		leaves[i] = &leaf{
+			entry:  e,
+			height: h,
+		}
+	}
+
+	sort.Slice(leaves, func(i, j int) bool {
+		return leaves[i].entry.Key < leaves[j].entry.Key
+	})
+
+	for i := 1; i < len(leaves); i++ {
+		if leaves[i].entry.Key == leaves[i-1].entry.Key {
+			return nil, fmt.Errorf("Duplicate key %s", leaves[i].entry.Key)
+		}
+	}
+
+	t := &Tree{
+		order:   make([]cid.Cid, 0),
+		nodes:   make(map[cid.Cid][]byte),
+		keys:    make(map[string]cid.Cid),
+		parents: make(map[cid.Cid]cid.Cid),
+	}
+
+	root, err := t.build(leaves, max_height)
+
+	if err != nil {
+		return nil, err
+	}
+
+	t.root = root
+	return t, nil
+}
+
+// Root returns the CID of the top-most node in the tree.
+func (t *Tree) Root() cid.Cid {
+	return t.root
+}
+
+// Node returns the DAG-CBOR encoded bytes for the node identified by 'c'.
+func (t *Tree) Node(c cid.Cid) ([]byte, bool) {
+	b, ok := t.nodes[c]
+	return b, ok
+}
+
+// Nodes returns an iterator of the CID and DAG-CBOR encoded bytes of every node in the tree, starting with the root node.
+func (t *Tree) Nodes() iter.Seq2[cid.Cid, []byte] {
+
+	return func(yield func(cid.Cid, []byte) bool) {
+
+		for i := len(t.order) - 1; i >= 0; i-- {
+
+			c := t.order[i]
+
+			if !yield(c, t.nodes[c]) {
+				return
+			}
+		}
+	}
+}
+
+// Path returns the CIDs of the nodes from the root of the tree to (and including) the node that 'key' is stored in.
+// This is the set of nodes needed to prove that 'key' is included in the tree. If 'key' is not in the tree then
+// false is returned.
+func (t *Tree) Path(key string) ([]cid.Cid, bool) {
+
+	c, exists := t.keys[key]
+
+	if !exists {
+		return nil, false
+	}
+
+	path := []cid.Cid{
+		c,
+	}
+
+	for {
+
+		parent, exists := t.parents[c]
+
+		if !exists {
+			break
+		}
+
+		path = append([]cid.Cid{parent}, path...)
+		c = parent
+	}
+
+	return path, true
+}
+
+// ReadEntries returns the entries stored in the tree whose root node is 'root' by decoding each node, starting with
+// the root, using 'get' to retrieve the DAG-CBOR encoded bytes for a node. Entries are returned in key order. Callers
+// which need to ensure that the nodes form a valid tree should compare the root of `NewTree(entries)` to 'root'.
+func ReadEntries(root cid.Cid, get func(cid.Cid) ([]byte, bool)) ([]*Entry, error) {
+
+	entries := make([]*Entry, 0)
+
+	err := readNode(root, get, &entries)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func readNode(c cid.Cid, get func(cid.Cid) ([]byte, bool), entries *[]*Entry) error {
+
+	b, exists := get(c)
+
+	if !exists {
+		return fmt.Errorf("Missing node %s", c)
+	}
+
+	var node map[string]any
+
+	err := dagcbor.Unmarshal(b, &node)
+
+	if err != nil {
+		return fmt.Errorf("Failed to decode node %s, %w", c, err)
+	}
+
+	left, ok := node["l"].(cid.Cid)
+
+	if ok {
+
+		err := readNode(left, get, entries)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	node_entries, ok := node["e"].([]any)
+
+	if !ok {
+		return fmt.Errorf("Invalid node %s, missing entries", c)
+	}
+
+	prev_key := ""
+
+	for i, v := range node_entries {
+
+		e, ok := v.(map[string]any)
+
+		if !ok {
+			return fmt.Errorf("Invalid node %s, entry at offset %d is not a map", c, i)
+		}
+
+		prefix, ok := e["p"].(int)
+
+		if !ok || prefix < 0 || prefix > len(prev_key) {
+			return fmt.Errorf("Invalid node %s, invalid prefix length for entry at offset %d", c, i)
+		}
+
+		suffix, ok := e["k"].([]byte)
+
+		if !ok {
+			return fmt.Errorf("Invalid node %s, invalid key for entry at offset %d", c, i)
+		}
+
+		value, ok := e["v"].(cid.Cid)
+
+		if !ok {
+			return fmt.Errorf("Invalid node %s, invalid value for entry at offset %d", c, i)
+		}
+
+		key := prev_key[:prefix] + string(suffix)
+
+		*entries = append(*entries, &Entry{
+			Key:   key,
+			Value: value,
+		})
+
+		prev_key = key
+
+		right, ok := e["t"].(cid.Cid)
+
+		if ok {
+
+			err := readNode(right, get, entries)
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// build encodes 'leaves' (which are assumed to be sorted) as a node at 'height' and returns its CID. Leaves
+// at a lower height are encoded, recursively, as child nodes pointed to by the "l" (left) or "t" (right of entry)
+// properties.
+func (t *Tree) build(leaves []*leaf, height int) (cid.Cid, error) {
+
+	var left any
+	entries := make([]any, 0)
+	keys := make([]string, 0)
+	children := make([]cid.Cid, 0)
+
+	prev_key := ""
+	pending := make([]*leaf, 0)
+
+	flush := func() error {
+
+		if len(pending) == 0 {
+			return nil
+		}
+
+		child, err := t.build(pending, height-1)
+
+		if err != nil {
+			return err
+		}
+
+		pending = make([]*leaf, 0)
+		children = append(children, child)
+
+		if len(entries) == 0 {
+			left = child
+		} else {
+			e := entries[len(entries)-1].(map[string]any)
+			e["t"] = child
+		}
+
+		return nil
+	}
+
+	for _, l := range leaves {
+
+		if l.height > height {
+			return cid.Undef, fmt.Errorf("Invalid tree, key %s (%d) is higher than node (%d)", l.entry.Key, l.height, height)
+		}
+
+		if l.height < height {
+			pending = append(pending, l)
+			continue
+		}
+
+		err := flush()
+
+		if err != nil {
+			return cid.Undef, err
+		}
+
+		key := l.entry.Key
+		prefix := commonPrefixLength(prev_key, key)
+
+		e := map[string]any{
+			"p": int64(prefix),
+			"k": []byte(key[prefix:]),
+			"v": l.entry.Value,
+			"t": nil,
+		}
+
+		entries = append(entries, e)
+		keys = append(keys, key)
+		prev_key = key
+	}
+
+	err := flush()
+
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	node := map[string]any{
+		"l": left,
+		"e": entries,
+	}
+
+	b, c, err := dagcbor.MarshalWithCID(node)
+
+	if err != nil {
+		return cid.Undef, fmt.Errorf("Failed to encode node, %w", err)
+	}
+
+	_, exists := t.nodes[c]
+
+	if !exists {
+		t.nodes[c] = b
+		t.order = append(t.order, c)
+	}
+
+	for _, k := range keys {
+		t.keys[k] = c
+	}
+
+	for _, child := range children {
+		t.parents[child] = c
+	}
+
+	return c, nil
+}
+
+func commonPrefixLength(a string, b string) int {
+
+	n := min(len(a), len(b))
+
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,211 @@
+package mst
+
+// Test vectors are from https://github.com/bluesky-social/atproto-interop-tests/tree/main/mst
+
+import (
+	"testing"
+
+	"github.com/ipfs/go-cid"
+)
+
+const test_value string = "bafyreie5cvv4h45feadgeuwhbcutmh6t2ceseocckahdoe6uat64zmz454"
+
+func testEntries(t *testing.T, keys ...string) []*Entry {
+
+	v, err := cid.Decode(test_value)
+
+	if err != nil {
+		t.Fatalf("Failed to decode CID, %v", err)
+	}
+
+	entries := make([]*Entry, len(keys))
+
+	for i, k := range keys {
+		entries[i] = &Entry{
+			Key:   k,
+			Value: v,
+		}
+	}
+
+	return entries
+}
+
+func TestHeightForKey(t *testing.T) {
+
+	tests := []struct {
+		key    string
+		height int
+	}{
+		{"", 0},
+		{"asdf", 0},
+		{"blue", 1},
+		{"2653ae71", 0},
+		{"88bfafc7", 2},
+		{"2a92d355", 4},
+		{"884976f5", 6},
+		{"app.bsky.feed.post/454397e440ec", 4},
+		{"app.bsky.feed.post/9adeb165882c", 8},
+	}
+
+	for _, tt := range tests {
+
+		h := HeightForKey(tt.key)
+
+		if h != tt.height {
+			t.Fatalf("Expected height %d for '%s', got %d", tt.height, tt.key, h)
+		}
+	}
+}
+
+func TestKnownRoots(t *testing.T) {
+
+	tests := []struct {
+		name string
+		keys []string
+		root string
+	}{
+		{
+			"empty",
+			[]string{},
+			"bafyreie5737gdxlw5i64vzichcalba3z2v5n6icifvx5xytvske7mr3hpm",
+		},
+		{
+			"single entry",
+			[]string{
+				"com.example.record/3jqfcqzm3fo2j",
+			},
+			"bafyreibj4lsc3aqnrvphp5xmrnfoorvru4wynt6lwidqbm2623a6tatzdu",
+		},
+		{
+			"single entry at height 2",
+			[]string{
+				"com.example.record/3jqfcqzm3fx2j",
+			},
+			"bafyreih7wfei65pxzhauoibu3ls7jgmkju4bspy4t2ha2qdjnzqvoy33ai",
+		},
+		{
+			"simple",
+			[]string{
+				"com.example.record/3jqfcqzm3fp2j",
+				"com.example.record/3jqfcqzm3fr2j",
+				"com.example.record/3jqfcqzm3fs2j",
+				"com.example.record/3jqfcqzm3ft2j",
+				"com.example.record/3jqfcqzm4fc2j",
+			},
+			"bafyreicmahysq4n6wfuxo522m6dpiy7z7qzym3dzs756t5n7nfdgccwq7m",
+		},
+	}
+
+	for _, tt := range tests {
+
+		t.Run(tt.name, func(t *testing.T) {
+
+			tree, err := NewTree(testEntries(t, tt.keys...))
+
+			if err != nil {
+				t.Fatalf("Failed to create tree, %v", err)
+			}
+
+			if tree.Root().String() != tt.root {
+				t.Fatalf("Expected root %s, got %s", tt.root, tree.Root())
+			}
+		})
+	}
+}
+
+func TestReadEntries(t *testing.T) {
+
+	keys := []string{
+		"com.example.record/3jqfcqzm3ft2j",
+		"com.example.record/3jqfcqzm3fp2j",
+		"com.example.record/3jqfcqzm4fc2j",
+		"com.example.record/3jqfcqzm3fx2j",
+		"com.example.record/3jqfcqzm3fr2j",
+		"com.example.record/3jqfcqzm3fs2j",
+		"com.example.record/3jqfcqzm3fo2j",
+	}
+
+	tree, err := NewTree(testEntries(t, keys...))
+
+	if err != nil {
+		t.Fatalf("Failed to create tree, %v", err)
+	}
+
+	entries, err := ReadEntries(tree.Root(), tree.Node)
+
+	if err != nil {
+		t.Fatalf("Failed to read entries, %v", err)
+	}
+
+	if len(entries) != len(keys) {
+		t.Fatalf("Expected %d entries, got %d", len(keys), len(entries))
+	}
+
+	for i := 1; i < len(entries); i++ {
+
+		if entries[i].Key <= entries[i-1].Key {
+			t.Fatalf("Entries are not in key order, %s after %s", entries[i].Key, entries[i-1].Key)
+		}
+	}
+
+	// The same entries always produce the same root
+
+	other, err := NewTree(entries)
+
+	if err != nil {
+		t.Fatalf("Failed to create tree from entries, %v", err)
+	}
+
+	if !other.Root().Equals(tree.Root()) {
+		t.Fatalf("Expected root %s, got %s", tree.Root(), other.Root())
+	}
+
+	for _, k := range keys {
+
+		path, ok := tree.Path(k)
+
+		if !ok {
+			t.Fatalf("Expected path for %s", k)
+		}
+
+		if !path[0].Equals(tree.Root()) {
+			t.Fatalf("Expected path for %s to start at the root", k)
+		}
+
+		for _, c := range path {
+
+			_, ok := tree.Node(c)
+
+			if !ok {
+				t.Fatalf("Missing node %s in path for %s", c, k)
+			}
+		}
+	}
+
+	_, ok := tree.Path("com.example.record/missing")
+
+	if ok {
+		t.Fatalf("Expected no path for missing key")
+	}
+}
+
+func TestNewTreeInvalid(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		entries []*Entry
+	}{
+		{"empty key", testEntries(t, "")},
+		{"duplicate key", testEntries(t, "com.example.record/3jqfcqzm3fo2j", "com.example.record/3jqfcqzm3fo2j")},
+		{"undefined value", []*Entry{{Key: "com.example.record/3jqfcqzm3fo2j"}}},
+	}
+
+	for _, tt := range tests {
+
+		_, err := NewTree(tt.entries)
+
+		if err == nil {
+			t.Fatalf("Expected an error for %s", tt.name)
+		}
+	}
+}
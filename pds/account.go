@@ -3,13 +3,22 @@ package pds
 import (
 	"context"
 	"fmt"
-	_ "log/slog"
+	"iter"
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/did-method-plc/go-didplc"
+	"github.com/ipfs/go-cid"
+	"github.com/sfomuseum/go-atproto"
 	"github.com/sfomuseum/go-atproto/plc"
 )
 
+// The account status value, as defined by the com.atproto.sync.getRepoStatus lexicon, for accounts which have been
+// deleted. This is the only inactive status that accounts can currently have; takedowns, suspensions and deactivations
+// are not supported.
+const ACCOUNT_STATUS_DELETED string = "deleted"
+
 type Account struct {
 	DID          string `json:"did"`
 	Handle       string `json:"handle"`
@@ -18,6 +27,21 @@ type Account struct {
 	LastModified int64  `json:"lastmodified"`
 }
 
+// IsActive returns true if the account has not been deleted.
+func (a *Account) IsActive() bool {
+	return a.Status() == ""
+}
+
+// Status returns `ACCOUNT_STATUS_DELETED` if the account has been deleted or an empty string if it is active.
+func (a *Account) Status() string {
+
+	if a.Deleted != 0 {
+		return ACCOUNT_STATUS_DELETED
+	}
+
+	return ""
+}
+
 type CreateAccountResponse struct {
 	Account   *Account
 	Key       *Key
@@ -28,6 +52,16 @@ type RemoveAccountResponse struct {
 	Operation *Operation
 }
 
+// CreateRepoOptions defines the databases used to create the initial commit for a new account.
+type CreateRepoOptions struct {
+	KeysDatabase    KeysDatabase
+	CommitsDatabase CommitsDatabase
+	// An optional `BlocksDatabase` to store the blocks (commit and empty tree node) for the initial commit.
+	BlocksDatabase BlocksDatabase
+	// An optional `Sequencer` to emit a "#commit" event to once the initial commit has been stored.
+	Sequencer *Sequencer
+}
+
 func CreateAccount(ctx context.Context, plc_cl *didplc.Client, service string, handle string) (*CreateAccountResponse, error) {
 
 	rsp, err := plc.NewDID(ctx, plc_cl, service, handle)
@@ -68,6 +102,85 @@ func CreateAccount(ctx context.Context, plc_cl *didplc.Client, service string, h
 	return acct_rsp, nil
 }
 
+// CreateRepo creates, signs and stores the initial commit, for an empty Merkle Search Tree, for the repository
+// belonging to 'did'. It should be called once the account's "atproto" key has been stored. If the repository
+// already has a commit then an error wrapping `atproto.ErrInvalidWrite` is returned.
+func CreateRepo(ctx context.Context, opts *CreateRepoOptions, did string) (*Commit, error) {
+
+	unlock := LockRepo(did)
+	defer unlock()
+
+	_, err := GetLatestCommitForDID(ctx, opts.CommitsDatabase, did)
+
+	if err == nil {
+		return nil, fmt.Errorf("%w, repository for %s already exists", atproto.ErrInvalidWrite, did)
+	}
+
+	if err != atproto.ErrNotFound {
+		return nil, fmt.Errorf("Failed to retrieve latest commit, %w", err)
+	}
+
+	tree, err := newTreeFromLeaves(make(map[string]cid.Cid))
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive tree, %w", err)
+	}
+
+	commit, err := newCommit(ctx, opts.KeysDatabase, did, tree, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	commit_cid, err := cid.Decode(commit.CID)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid commit CID, %w", err)
+	}
+
+	commit_body, err := commit.Bytes()
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode commit, %w", err)
+	}
+
+	blocks := []*repoBlock{
+		&repoBlock{cid: commit_cid, data: commit_body},
+	}
+
+	for node_cid, node_body := range tree.Nodes() {
+		blocks = append(blocks, &repoBlock{cid: node_cid, data: node_body})
+	}
+
+	if opts.BlocksDatabase != nil {
+
+		err := storeBlocks(ctx, opts.BlocksDatabase, did, commit.Rev, blocks)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to store blocks, %w", err)
+		}
+	}
+
+	err = AddCommit(ctx, opts.CommitsDatabase, commit)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to store commit, %w", err)
+	}
+
+	// At this point the commit has been stored so errors emitting events are logged rather than returned
+
+	if opts.Sequencer != nil {
+
+		err := emitCommitEvent(ctx, opts.Sequencer, commit, nil, blocks, []*RepoOp{})
+
+		if err != nil {
+			slog.Error("Failed to emit commit event", "did", did, "commit", commit.CID, "error", err)
+		}
+	}
+
+	return commit, nil
+}
+
 func GetAccount(ctx context.Context, db AccountsDatabase, did string) (*Account, error) {
 	return db.GetAccount(ctx, did)
 }
@@ -76,6 +189,20 @@ func GetAccountWithHandle(ctx context.Context, db AccountsDatabase, handle strin
 	return db.GetAccountWithHandle(ctx, handle)
 }
 
+// GetAccountWithIdentifier returns the account for 'id' which may be either a DID or a handle.
+func GetAccountWithIdentifier(ctx context.Context, db AccountsDatabase, id string) (*Account, error) {
+
+	if strings.HasPrefix(id, "did:") {
+		return GetAccount(ctx, db, id)
+	}
+
+	return GetAccountWithHandle(ctx, db, id)
+}
+
+func ListAccounts(ctx context.Context, db AccountsDatabase, opts *ListAccountsOptions) iter.Seq2[*Account, error] {
+	return db.ListAccounts(ctx, opts)
+}
+
 func AddAccount(ctx context.Context, db AccountsDatabase, account *Account) error {
 
 	now := time.Now()
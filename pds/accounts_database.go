@@ -11,12 +11,21 @@ import (
 	"github.com/aaronland/go-roster"
 )
 
+// ListAccountsOptions defines the criteria for listing accounts in an `AccountsDatabase`. Accounts
+// are always listed in ascending order of their DIDs.
+type ListAccountsOptions struct {
+	// The maximum number of accounts to return. If 0 all the accounts are returned.
+	Limit int
+	// Only return accounts whose DID is greater than this value.
+	Cursor string
+}
+
 type AccountsDatabase interface {
 	GetAccount(context.Context, string) (*Account, error)
 	GetAccountWithHandle(context.Context, string) (*Account, error)
 	AddAccount(context.Context, *Account) error
 	UpdateAccount(context.Context, *Account) error
-	ListAccounts(context.Context) iter.Seq2[*Account, error]
+	ListAccounts(context.Context, *ListAccountsOptions) iter.Seq2[*Account, error]
 	Close() error
 }
 
@@ -118,7 +118,7 @@ func (db *BlobAccountsDatabase) UpdateAccount(ctx context.Context, account *Acco
 	return db.writeAccount(ctx, account)
 }
 
-func (db *BlobAccountsDatabase) ListAccounts(ctx context.Context) iter.Seq2[*Account, error] {
+func (db *BlobAccountsDatabase) ListAccounts(ctx context.Context, opts *ListAccountsOptions) iter.Seq2[*Account, error] {
 
 	return func(yield func(*Account, error) bool) {
 		yield(nil, atproto.ErrNotImplemented)
@@ -44,7 +44,7 @@ func (db *NullAccountsDatabase) UpdateAccount(ctx context.Context, account *Acco
 
 }
 
-func (db *NullAccountsDatabase) ListAccounts(ctx context.Context) iter.Seq2[*Account, error] {
+func (db *NullAccountsDatabase) ListAccounts(ctx context.Context, opts *ListAccountsOptions) iter.Seq2[*Account, error] {
 
 	return func(yield func(*Account, error) bool) {
 		return
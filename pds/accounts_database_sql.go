@@ -166,13 +166,26 @@ func (db *SQLAccountsDatabase) UpdateAccount(ctx context.Context, account *Accou
 
 }
 
-func (db *SQLAccountsDatabase) ListAccounts(ctx context.Context) iter.Seq2[*Account, error] {
+func (db *SQLAccountsDatabase) ListAccounts(ctx context.Context, opts *ListAccountsOptions) iter.Seq2[*Account, error] {
 
 	return func(yield func(*Account, error) bool) {
 
-		q := "SELECT did, handle, created, deleted, lastmodified FROM accounts ORDER BY created DESC"
+		q := "SELECT did, handle, created, deleted, lastmodified FROM accounts"
+		args := make([]any, 0)
 
-		rows, err := db.conn.QueryContext(ctx, q)
+		if opts.Cursor != "" {
+			q = fmt.Sprintf("%s WHERE did > ?", q)
+			args = append(args, opts.Cursor)
+		}
+
+		q = fmt.Sprintf("%s ORDER BY did ASC", q)
+
+		if opts.Limit > 0 {
+			q = fmt.Sprintf("%s LIMIT ?", q)
+			args = append(args, opts.Limit)
+		}
+
+		rows, err := db.conn.QueryContext(ctx, q, args...)
 
 		if err != nil {
 			yield(nil, err)
@@ -1,10 +1,44 @@
 package pds
 
+import (
+	"context"
+	"iter"
+	"time"
+)
+
+// Blob is the metadata for a blob (for example an image) uploaded to a repository.
 type Blob struct {
-	CID          string `json:"cid"`
-	DID          string `json:"did"`
-	MediaType    string `json:"media_type"`
-	Size         int64  `json:"size"`
-	Created      int64  `json:"created"`
-	LastModified int64  `json:"lastmodified"`
+	// The CIDv1 (raw codec, sha-256) of the blob's data.
+	CID       string `json:"cid"`
+	DID       string `json:"did"`
+	MediaType string `json:"media_type"`
+	Size      int64  `json:"size"`
+	// The width and height, in pixels, of images which have been normalized (see `UploadBlobOptions.Images`).
+	Width        int   `json:"width,omitempty"`
+	Height       int   `json:"height,omitempty"`
+	Created      int64 `json:"created"`
+	LastModified int64 `json:"lastmodified"`
+}
+
+func GetBlob(ctx context.Context, db BlobsDatabase, did string, blob_cid string) (*Blob, error) {
+	return db.GetBlob(ctx, did, blob_cid)
+}
+
+func AddBlob(ctx context.Context, db BlobsDatabase, b *Blob) error {
+
+	now := time.Now()
+	ts := now.Unix()
+
+	b.Created = ts
+	b.LastModified = ts
+
+	return db.AddBlob(ctx, b)
+}
+
+func DeleteBlob(ctx context.Context, db BlobsDatabase, b *Blob) error {
+	return db.DeleteBlob(ctx, b)
+}
+
+func ListBlobs(ctx context.Context, db BlobsDatabase, opts *ListBlobsOptions) iter.Seq2[*Blob, error] {
+	return db.ListBlobs(ctx, opts)
 }
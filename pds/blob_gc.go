@@ -0,0 +1,142 @@
+package pds
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sfomuseum/go-atproto"
+)
+
+// The default amount of time that a blob which is not referenced by any records is retained before it is garbage collected.
+const DEFAULT_BLOB_GRACE_PERIOD time.Duration = 24 * time.Hour
+
+// GCBlobsOptions defines the storage and criteria used to garbage collect blobs.
+type GCBlobsOptions struct {
+	BlobsDatabase BlobsDatabase
+	BlobStore     *BlobStore
+	// Only garbage collect blobs for this DID. If empty blobs for all DIDs are garbage collected.
+	DID string
+	// The amount of time since a blob was uploaded, or last referenced by a record, before it is garbage collected.
+	GracePeriod time.Duration
+	// If true blobs are reported but not deleted.
+	DryRun bool
+}
+
+// GCBlobs deletes the data and metadata for blobs which are not referenced by any records and which have not been
+// uploaded (or referenced) within the grace period. Blob data in the blob store with no corresponding metadata in the
+// blobs database (for example from an upload which failed part way through) is also deleted once it is older than the
+// grace period. Each blob is checked again, while holding the lock for its repository, before it is deleted. It returns
+// the blobs that were deleted or, if 'opts.DryRun' is true, would have been deleted.
+func GCBlobs(ctx context.Context, opts *GCBlobsOptions) ([]*Blob, error) {
+
+	before := time.Now().Add(-opts.GracePeriod).Unix()
+
+	// Candidates are collected before anything is deleted since some databases (for example SQLite with a single
+	// connection) can not be written to while they are being read from
+
+	orphans := make([]*Blob, 0)
+
+	list_opts := &ListUnreferencedBlobsOptions{
+		DID:    opts.DID,
+		Before: before,
+	}
+
+	for b, err := range opts.BlobsDatabase.ListUnreferencedBlobs(ctx, list_opts) {
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to list unreferenced blobs, %w", err)
+		}
+
+		orphans = append(orphans, b)
+	}
+
+	for b, err := range opts.BlobStore.List(ctx, opts.DID) {
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to list stored blobs, %w", err)
+		}
+
+		if b.LastModified >= before {
+			continue
+		}
+
+		_, err := GetBlob(ctx, opts.BlobsDatabase, b.DID, b.CID)
+
+		if err == nil {
+			continue
+		}
+
+		if err != atproto.ErrNotFound {
+			return nil, fmt.Errorf("Failed to retrieve blob %s for %s, %w", b.CID, b.DID, err)
+		}
+
+		orphans = append(orphans, b)
+	}
+
+	if opts.DryRun {
+		return orphans, nil
+	}
+
+	deleted := make([]*Blob, 0)
+
+	for _, b := range orphans {
+
+		ok, err := gcBlob(ctx, opts, b, before)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			deleted = append(deleted, b)
+		}
+	}
+
+	return deleted, nil
+}
+
+// gcBlob deletes the data and metadata for 'b' while holding the lock for its repository (see `LockRepo`). Since
+// writes and uploads happen under the same lock the blob is first checked again to ensure that it has not been
+// referenced by a record, or uploaded again, since it was listed. It returns false if the blob was not deleted.
+func gcBlob(ctx context.Context, opts *GCBlobsOptions, b *Blob, before int64) (bool, error) {
+
+	unlock := LockRepo(b.DID)
+	defer unlock()
+
+	referenced, err := opts.BlobsDatabase.IsBlobReferenced(ctx, b.DID, b.CID)
+
+	if err != nil {
+		return false, fmt.Errorf("Failed to determine whether blob %s for %s is referenced, %w", b.CID, b.DID, err)
+	}
+
+	if referenced {
+		return false, nil
+	}
+
+	current, err := GetBlob(ctx, opts.BlobsDatabase, b.DID, b.CID)
+
+	if err != nil && err != atproto.ErrNotFound {
+		return false, fmt.Errorf("Failed to retrieve blob %s for %s, %w", b.CID, b.DID, err)
+	}
+
+	if current != nil && current.LastModified >= before {
+		return false, nil
+	}
+
+	// Data is deleted before metadata so that a failure leaves metadata which will be garbage collected again
+
+	err = opts.BlobStore.Delete(ctx, b.DID, b.CID)
+
+	if err != nil {
+		return false, fmt.Errorf("Failed to delete data for blob %s for %s, %w", b.CID, b.DID, err)
+	}
+
+	err = DeleteBlob(ctx, opts.BlobsDatabase, b)
+
+	if err != nil {
+		return false, fmt.Errorf("Failed to delete blob %s for %s, %w", b.CID, b.DID, err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,153 @@
+package pds
+
+import (
+	"context"
+	"iter"
+	"strings"
+	"testing"
+
+	_ "gocloud.dev/blob/memblob"
+)
+
+// referencingBlobsDatabase adds a reference to 'cid' after it has been listed as unreferenced, the same as a
+// record being written while blobs are being garbage collected.
+type referencingBlobsDatabase struct {
+	BlobsDatabase
+	cid string
+}
+
+func (db *referencingBlobsDatabase) ListUnreferencedBlobs(ctx context.Context, opts *ListUnreferencedBlobsOptions) iter.Seq2[*Blob, error] {
+
+	return func(yield func(*Blob, error) bool) {
+
+		blobs := make([]*Blob, 0)
+
+		for b, err := range db.BlobsDatabase.ListUnreferencedBlobs(ctx, opts) {
+
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			blobs = append(blobs, b)
+		}
+
+		for _, b := range blobs {
+
+			if b.CID == db.cid {
+
+				err := db.BlobsDatabase.SetBlobRefs(ctx, b.DID, "app.bsky.feed.post/3jzfcijpj2z2a", "3jzfcijpj2z2a", []string{b.CID})
+
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+			}
+
+			if !yield(b, nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestGCBlobs(t *testing.T) {
+
+	ctx := context.Background()
+
+	blobs_db, err := NewBlobsDatabase(ctx, "mem://")
+
+	if err != nil {
+		t.Fatalf("Failed to create blobs database, %v", err)
+	}
+
+	defer blobs_db.Close()
+
+	blob_store, err := NewBlobStore(ctx, "mem://")
+
+	if err != nil {
+		t.Fatalf("Failed to create blob store, %v", err)
+	}
+
+	defer blob_store.Close()
+
+	referenced := "bafkreihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku"
+	unreferenced := "bafkreie7q3iidccmpvszul7kudcvvuavuo7u6gzlbobczuk5nqk3b4akba"
+	referenced_later := "bafkreibme22gw2h7y2h7tg2fhqotaqjucnbc24deqo72b6mkl2egezxhvy"
+
+	for _, blob_cid := range []string{referenced, unreferenced, referenced_later} {
+
+		b := &Blob{
+			CID:          blob_cid,
+			DID:          test_did,
+			MediaType:    "text/plain",
+			Size:         1,
+			Created:      1,
+			LastModified: 1,
+		}
+
+		err := blobs_db.AddBlob(ctx, b)
+
+		if err != nil {
+			t.Fatalf("Failed to add blob, %v", err)
+		}
+
+		err = blob_store.Write(ctx, b, strings.NewReader("a"))
+
+		if err != nil {
+			t.Fatalf("Failed to write blob, %v", err)
+		}
+	}
+
+	err = blobs_db.SetBlobRefs(ctx, test_did, "app.bsky.feed.post/3jzfcijpj2z2b", "3jzfcijpj2z2b", []string{referenced})
+
+	if err != nil {
+		t.Fatalf("Failed to set blob refs, %v", err)
+	}
+
+	gc_opts := &GCBlobsOptions{
+		BlobsDatabase: &referencingBlobsDatabase{
+			BlobsDatabase: blobs_db,
+			cid:           referenced_later,
+		},
+		BlobStore: blob_store,
+	}
+
+	deleted, err := GCBlobs(ctx, gc_opts)
+
+	if err != nil {
+		t.Fatalf("Failed to garbage collect blobs, %v", err)
+	}
+
+	if len(deleted) != 1 || deleted[0].CID != unreferenced {
+		t.Fatalf("Expected only %s to be deleted, got %v", unreferenced, deleted)
+	}
+
+	tests := []struct {
+		cid    string
+		exists bool
+	}{
+		{referenced, true},
+		{unreferenced, false},
+		{referenced_later, true},
+	}
+
+	for _, tt := range tests {
+
+		exists, err := blob_store.Exists(ctx, test_did, tt.cid)
+
+		if err != nil {
+			t.Fatalf("Failed to determine whether %s exists, %v", tt.cid, err)
+		}
+
+		if exists != tt.exists {
+			t.Fatalf("Expected data for %s to exist: %t", tt.cid, tt.exists)
+		}
+
+		_, err = GetBlob(ctx, blobs_db, test_did, tt.cid)
+
+		if (err == nil) != tt.exists {
+			t.Fatalf("Expected metadata for %s to exist: %t, %v", tt.cid, tt.exists, err)
+		}
+	}
+}
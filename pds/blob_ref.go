@@ -0,0 +1,133 @@
+package pds
+
+// https://atproto.com/specs/data-model#blob-type
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sfomuseum/go-atproto"
+)
+
+// BlobRefs returns the (unique, sorted) CIDs of the blobs referenced by 'value', a record encoded as atproto data
+// model JSON. Both current ("$type": "blob") and legacy ("cid" and "mimeType" only) blob references are recognized.
+func BlobRefs(value string) ([]string, error) {
+
+	var v any
+
+	dec := json.NewDecoder(strings.NewReader(value))
+	dec.UseNumber()
+
+	err := dec.Decode(&v)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode record, %w", err)
+	}
+
+	seen := make(map[string]bool)
+	collectBlobRefs(v, seen)
+
+	cids := make([]string, 0, len(seen))
+
+	for blob_cid := range seen {
+		cids = append(cids, blob_cid)
+	}
+
+	sort.Strings(cids)
+	return cids, nil
+}
+
+func collectBlobRefs(v any, seen map[string]bool) {
+
+	switch v := v.(type) {
+	case map[string]any:
+
+		if v["$type"] == "blob" {
+
+			ref, _ := v["ref"].(map[string]any)
+			link, _ := ref["$link"].(string)
+
+			if link != "" {
+				seen[link] = true
+			}
+
+			return
+		}
+
+		if len(v) == 2 {
+
+			legacy_cid, cid_ok := v["cid"].(string)
+			_, mime_ok := v["mimeType"].(string)
+
+			if cid_ok && mime_ok {
+				seen[legacy_cid] = true
+				return
+			}
+		}
+
+		for _, child := range v {
+			collectBlobRefs(child, seen)
+		}
+
+	case []any:
+
+		for _, child := range v {
+			collectBlobRefs(child, seen)
+		}
+	}
+}
+
+// updateBlobRefs replaces the blob references for each record in 'writes', written by the commit with revision 'rev',
+// and sets the last modified time of each referenced blob to 'ts' so that blobs are not expired (see `GCBlobs`) while
+// they are in use. References to blobs which have not been uploaded are still recorded.
+func updateBlobRefs(ctx context.Context, db BlobsDatabase, did string, rev string, writes []*RecordWrite, ts int64) error {
+
+	for _, w := range writes {
+
+		var cids []string
+
+		if w.Action != RECORD_WRITE_DELETE {
+
+			refs, err := BlobRefs(w.Record.Value)
+
+			if err != nil {
+				return fmt.Errorf("Failed to derive blob refs for %s, %w", w.Record.Path(), err)
+			}
+
+			cids = refs
+		}
+
+		err := db.SetBlobRefs(ctx, did, w.Record.Path(), rev, cids)
+
+		if err != nil {
+			return fmt.Errorf("Failed to set blob refs for %s, %w", w.Record.Path(), err)
+		}
+
+		for _, blob_cid := range cids {
+
+			b, err := GetBlob(ctx, db, did, blob_cid)
+
+			if err != nil {
+
+				if err == atproto.ErrNotFound {
+					continue
+				}
+
+				return fmt.Errorf("Failed to retrieve blob %s, %w", blob_cid, err)
+			}
+
+			b.LastModified = ts
+
+			err = db.AddBlob(ctx, b)
+
+			if err != nil {
+				return fmt.Errorf("Failed to update blob %s, %w", blob_cid, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package pds
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBlobRefs(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		value    string
+		expected []string
+		ok       bool
+	}{
+		{
+			"no blobs",
+			`{"$type": "app.bsky.feed.post", "text": "hello"}`,
+			[]string{},
+			true,
+		},
+		{
+			"blob",
+			`{"$type": "app.bsky.actor.profile", "avatar": {"$type": "blob", "ref": {"$link": "bafkreiabc"}, "mimeType": "image/png", "size": 1024}}`,
+			[]string{"bafkreiabc"},
+			true,
+		},
+		{
+			"nested and duplicate blobs",
+			`{"embed": {"images": [{"image": {"$type": "blob", "ref": {"$link": "bafkreixyz"}, "mimeType": "image/jpeg", "size": 10}}, {"image": {"$type": "blob", "ref": {"$link": "bafkreiabc"}, "mimeType": "image/jpeg", "size": 10}}, {"image": {"$type": "blob", "ref": {"$link": "bafkreixyz"}, "mimeType": "image/jpeg", "size": 10}}]}}`,
+			[]string{"bafkreiabc", "bafkreixyz"},
+			true,
+		},
+		{
+			"legacy blob",
+			`{"avatar": {"cid": "bafkreilegacy", "mimeType": "image/png"}}`,
+			[]string{"bafkreilegacy"},
+			true,
+		},
+		{
+			"not a legacy blob",
+			`{"avatar": {"cid": "bafkreilegacy", "mimeType": "image/png", "size": 10}}`,
+			[]string{},
+			true,
+		},
+		{
+			"blob without a link",
+			`{"avatar": {"$type": "blob", "mimeType": "image/png", "size": 10}}`,
+			[]string{},
+			true,
+		},
+		{
+			"invalid json",
+			`{"avatar":`,
+			nil,
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+
+		cids, err := BlobRefs(tt.value)
+
+		if !tt.ok {
+
+			if err == nil {
+				t.Fatalf("Expected an error for %s", tt.name)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("Unexpected error for %s, %v", tt.name, err)
+		}
+
+		if !slices.Equal(cids, tt.expected) {
+			t.Fatalf("Expected %v for %s, got %v", tt.expected, tt.name, cids)
+		}
+	}
+}
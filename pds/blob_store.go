@@ -0,0 +1,194 @@
+package pds
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"iter"
+	"path/filepath"
+	"strings"
+
+	"github.com/aaronland/gocloud/blob/bucket"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/ipfs/go-cid"
+	"github.com/sfomuseum/go-atproto"
+	"gocloud.dev/blob"
+	"gocloud.dev/gcerrors"
+)
+
+// BlobStore stores the data for blobs in a gocloud.dev/blob.Bucket, keyed by "{DID}/{CID}". Metadata
+// about blobs is stored separately in a `BlobsDatabase`.
+type BlobStore struct {
+	bucket *blob.Bucket
+}
+
+// NewBlobStore returns a new `BlobStore` instance which stores blob data in the gocloud.dev/blob.Bucket
+// defined by 'uri' (for example "s3://{BUCKET}?region={REGION}" or "file:///usr/local/data/blobs").
+func NewBlobStore(ctx context.Context, uri string) (*BlobStore, error) {
+
+	b, err := bucket.OpenBucket(ctx, uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open bucket, %w", err)
+	}
+
+	s := &BlobStore{
+		bucket: b,
+	}
+
+	return s, nil
+}
+
+// Exists returns true if data for the blob identified by 'did' and 'blob_cid' has been stored.
+func (s *BlobStore) Exists(ctx context.Context, did string, blob_cid string) (bool, error) {
+	return s.bucket.Exists(ctx, s.blobKey(did, blob_cid))
+}
+
+// Write stores the data for 'b' read from 'r'. The data is assumed to have already been validated against the CID of 'b'.
+// Not all buckets preserve content types (for example "file://" buckets are opened without metadata) so the media type
+// for a blob should always be read from its `BlobsDatabase` record.
+func (s *BlobStore) Write(ctx context.Context, b *Blob, r io.Reader) error {
+
+	wr_opts := &blob.WriterOptions{
+		ContentType: b.MediaType,
+	}
+
+	wr, err := s.bucket.NewWriter(ctx, s.blobKey(b.DID, b.CID), wr_opts)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create writer for %s, %w", b.CID, err)
+	}
+
+	_, err = io.Copy(wr, r)
+
+	if err != nil {
+		wr.Close()
+		return fmt.Errorf("Failed to write %s, %w", b.CID, err)
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		return fmt.Errorf("Failed to close writer for %s, %w", b.CID, err)
+	}
+
+	return nil
+}
+
+// NewReader returns a reader for the data of the blob identified by 'did' and 'blob_cid'. If the blob has
+// not been stored `atproto.ErrNotFound` is returned.
+func (s *BlobStore) NewReader(ctx context.Context, did string, blob_cid string) (*blob.Reader, error) {
+	return s.NewRangeReader(ctx, did, blob_cid, 0, -1)
+}
+
+// NewRangeReader returns a reader for 'length' bytes, starting at 'offset', of the data of the blob identified by
+// 'did' and 'blob_cid'. If 'length' is negative the remainder of the data is read. If the blob has not been stored
+// `atproto.ErrNotFound` is returned.
+func (s *BlobStore) NewRangeReader(ctx context.Context, did string, blob_cid string, offset int64, length int64) (*blob.Reader, error) {
+
+	r, err := s.bucket.NewRangeReader(ctx, s.blobKey(did, blob_cid), offset, length, nil)
+
+	if err != nil {
+
+		if isBucketNotFound(err) {
+			return nil, atproto.ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Delete removes the data for the blob identified by 'did' and 'blob_cid'. Blobs which have not been stored are ignored.
+func (s *BlobStore) Delete(ctx context.Context, did string, blob_cid string) error {
+
+	err := s.bucket.Delete(ctx, s.blobKey(did, blob_cid))
+
+	if err != nil && !isBucketNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// List returns the blobs whose data has been stored for 'did', or for all DIDs if 'did' is empty. Only the CID, DID,
+// size and last modified time (of the data) are known so blobs are returned without a media type.
+func (s *BlobStore) List(ctx context.Context, did string) iter.Seq2[*Blob, error] {
+
+	return func(yield func(*Blob, error) bool) {
+
+		list_opts := &blob.ListOptions{}
+
+		if did != "" {
+			list_opts.Prefix = did + "/"
+		}
+
+		list_iter := s.bucket.List(list_opts)
+
+		for {
+
+			obj, err := list_iter.Next(ctx)
+
+			if err == io.EOF {
+				return
+			}
+
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if obj.IsDir {
+				continue
+			}
+
+			// Only keys of the form "{DID}/{CID}" are blobs; anything else (for example the metadata files written by
+			// `BlobBlobsDatabase` if it shares the same bucket) is ignored
+
+			parts := strings.Split(obj.Key, "/")
+
+			if len(parts) != 2 {
+				continue
+			}
+
+			obj_did := parts[0]
+			blob_cid := parts[1]
+
+			_, err = syntax.ParseDID(obj_did)
+
+			if err != nil {
+				continue
+			}
+
+			_, err = cid.Decode(blob_cid)
+
+			if err != nil {
+				continue
+			}
+
+			b := &Blob{
+				CID:          blob_cid,
+				DID:          obj_did,
+				Size:         obj.Size,
+				LastModified: obj.ModTime.Unix(),
+			}
+
+			if !yield(b, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (s *BlobStore) Close() error {
+	return s.bucket.Close()
+}
+
+func (s *BlobStore) blobKey(did string, blob_cid string) string {
+	return filepath.Join(did, blob_cid)
+}
+
+func isBucketNotFound(err error) bool {
+	return gcerrors.Code(err) == gcerrors.NotFound
+}
@@ -0,0 +1,131 @@
+package pds
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/aaronland/go-roster"
+)
+
+// ListBlobsOptions defines the criteria for listing blobs in a `BlobsDatabase`. Blobs are always listed
+// in ascending order of their CIDs.
+type ListBlobsOptions struct {
+	DID string
+	// The maximum number of blobs to return. If 0 all the matching blobs are returned.
+	Limit int
+	// Only return blobs whose CID is greater than this value.
+	Cursor string
+	// Only return blobs referenced by records written by commits whose revision is greater than this value.
+	Since string
+}
+
+// ListUnreferencedBlobsOptions defines the criteria for listing blobs which are not referenced by any records.
+type ListUnreferencedBlobsOptions struct {
+	// Only return blobs for this DID. If empty blobs for all DIDs are returned.
+	DID string
+	// Only return blobs last modified before this Unix timestamp.
+	Before int64
+}
+
+// BlobsDatabase stores the metadata (CID, media type, size) for blobs uploaded to repositories. The blob
+// data itself is stored by a `BlobStore`.
+type BlobsDatabase interface {
+	GetBlob(context.Context, string, string) (*Blob, error)
+	// AddBlob stores a blob. If the blob has already been stored only its last modified time is updated.
+	AddBlob(context.Context, *Blob) error
+	DeleteBlob(context.Context, *Blob) error
+	// ListBlobs returns the blobs which are referenced by one or more records in a repository.
+	ListBlobs(context.Context, *ListBlobsOptions) iter.Seq2[*Blob, error]
+	// SetBlobRefs replaces the CIDs of the blobs referenced by the record at 'path' ("{COLLECTION}/{RKEY}") in the
+	// repository for 'did' written by the commit with revision 'rev'. If there are no CIDs all the references for
+	// the record are removed.
+	SetBlobRefs(context.Context, string, string, string, []string) error
+	// IsBlobReferenced returns true if the blob with CID 'cid' is referenced by one or more records in the repository for 'did'.
+	IsBlobReferenced(context.Context, string, string) (bool, error)
+	ListUnreferencedBlobs(context.Context, *ListUnreferencedBlobsOptions) iter.Seq2[*Blob, error]
+	Close() error
+}
+
+var blobs_database_roster roster.Roster
+
+// BlobsDatabaseInitializationFunc is a function defined by individual blobs_database package and used to create
+// an instance of that blobs_database
+type BlobsDatabaseInitializationFunc func(ctx context.Context, uri string) (BlobsDatabase, error)
+
+// RegisterBlobsDatabase registers 'scheme' as a key pointing to 'init_func' in an internal lookup table
+// used to create new `BlobsDatabase` instances by the `NewBlobsDatabase` method.
+func RegisterBlobsDatabase(ctx context.Context, scheme string, init_func BlobsDatabaseInitializationFunc) error {
+
+	err := ensureBlobsDatabaseRoster()
+
+	if err != nil {
+		return err
+	}
+
+	return blobs_database_roster.Register(ctx, scheme, init_func)
+}
+
+func ensureBlobsDatabaseRoster() error {
+
+	if blobs_database_roster == nil {
+
+		r, err := roster.NewDefaultRoster()
+
+		if err != nil {
+			return err
+		}
+
+		blobs_database_roster = r
+	}
+
+	return nil
+}
+
+// NewBlobsDatabase returns a new `BlobsDatabase` instance configured by 'uri'. The value of 'uri' is parsed
+// as a `url.URL` and its scheme is used as the key for a corresponding `BlobsDatabaseInitializationFunc`
+// function used to instantiate the new `BlobsDatabase`. It is assumed that the scheme (and initialization
+// function) have been registered by the `RegisterBlobsDatabase` method.
+func NewBlobsDatabase(ctx context.Context, uri string) (BlobsDatabase, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := u.Scheme
+
+	i, err := blobs_database_roster.Driver(ctx, scheme)
+
+	if err != nil {
+		return nil, err
+	}
+
+	init_func := i.(BlobsDatabaseInitializationFunc)
+	return init_func(ctx, uri)
+}
+
+// Schemes returns the list of schemes that have been registered.
+func BlobsDatabaseSchemes() []string {
+
+	ctx := context.Background()
+	schemes := []string{}
+
+	err := ensureBlobsDatabaseRoster()
+
+	if err != nil {
+		return schemes
+	}
+
+	for _, dr := range blobs_database_roster.Drivers(ctx) {
+		scheme := fmt.Sprintf("%s://", strings.ToLower(dr))
+		schemes = append(schemes, scheme)
+	}
+
+	sort.Strings(schemes)
+	return schemes
+}
@@ -0,0 +1,447 @@
+package pds
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/aaronland/gocloud/blob/bucket"
+	"github.com/sfomuseum/go-atproto"
+	"gocloud.dev/blob"
+)
+
+// BlobBlobsDatabase implements the `BlobsDatabase` interface storing blob metadata as JSON files in a gocloud.dev/blob.Bucket.
+type BlobBlobsDatabase struct {
+	BlobsDatabase
+	bucket *blob.Bucket
+}
+
+// blobRefs is the JSON document stored for each record which references one or more blobs.
+type blobRefs struct {
+	Rev  string   `json:"rev"`
+	CIDs []string `json:"cids"`
+}
+
+var blob_blobs_register_mu = new(sync.RWMutex)
+var blob_blobs_register_map = map[string]bool{}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterBlobBlobsSchemes(ctx)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// RegisterBlobBlobsSchemes will explicitly register all the schemes associated with the gocloud.dev/blob.Bucket
+// implementations that have been imported.
+func RegisterBlobBlobsSchemes(ctx context.Context) error {
+
+	blob_blobs_register_mu.Lock()
+	defer blob_blobs_register_mu.Unlock()
+
+	for _, scheme := range blob.DefaultURLMux().BucketSchemes() {
+
+		_, exists := blob_blobs_register_map[scheme]
+
+		if exists {
+			continue
+		}
+
+		err := RegisterBlobsDatabase(ctx, scheme, NewBlobBlobsDatabase)
+
+		if err != nil {
+			return fmt.Errorf("Failed to register blob blobs database for '%s', %w", scheme, err)
+		}
+
+		blob_blobs_register_map[scheme] = true
+	}
+
+	return nil
+}
+
+func NewBlobBlobsDatabase(ctx context.Context, uri string) (BlobsDatabase, error) {
+
+	b, err := bucket.OpenBucket(ctx, uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	db := &BlobBlobsDatabase{
+		bucket: b,
+	}
+
+	return db, nil
+}
+
+func (db *BlobBlobsDatabase) GetBlob(ctx context.Context, did string, blob_cid string) (*Blob, error) {
+
+	path := db.blobPath(did, blob_cid)
+	return db.readBlob(ctx, path)
+}
+
+func (db *BlobBlobsDatabase) AddBlob(ctx context.Context, b *Blob) error {
+
+	current, err := db.GetBlob(ctx, b.DID, b.CID)
+
+	switch {
+	case err == nil:
+		current.LastModified = b.LastModified
+		return db.writeBlob(ctx, current)
+	case err == atproto.ErrNotFound:
+		return db.writeBlob(ctx, b)
+	default:
+		return err
+	}
+}
+
+func (db *BlobBlobsDatabase) DeleteBlob(ctx context.Context, b *Blob) error {
+
+	path := db.blobPath(b.DID, b.CID)
+	err := db.bucket.Delete(ctx, path)
+
+	if err != nil && !isBucketNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// ListBlobs returns the blobs matching 'opts'. Buckets are listed in ascending order of key, which for
+// blob metadata files is the CID of the blob, so blobs are read one page at a time until 'opts.Limit' is reached.
+// All the blob references for 'opts.DID' are read first so its cost is also proportional to the number of records
+// which reference blobs.
+func (db *BlobBlobsDatabase) ListBlobs(ctx context.Context, opts *ListBlobsOptions) iter.Seq2[*Blob, error] {
+
+	return func(yield func(*Blob, error) bool) {
+
+		refs_prefix := filepath.Join("blob_refs", opts.DID) + "/"
+
+		referenced, err := db.readAllRefs(ctx, refs_prefix)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		prefix := filepath.Join("blobs", opts.DID) + "/"
+
+		list_opts := &blob.ListOptions{
+			Prefix: prefix,
+		}
+
+		count := 0
+		page_token := blob.FirstPageToken
+
+		for len(page_token) > 0 {
+
+			objs, next_token, err := db.bucket.ListPage(ctx, page_token, 1000, list_opts)
+
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, obj := range objs {
+
+				if obj.IsDir || !strings.HasSuffix(obj.Key, ".json") {
+					continue
+				}
+
+				blob_cid := strings.TrimSuffix(strings.TrimPrefix(obj.Key, prefix), ".json")
+
+				if opts.Cursor != "" && blob_cid <= opts.Cursor {
+					continue
+				}
+
+				rev, ok := referenced[filepath.Join(opts.DID, blob_cid)]
+
+				if !ok || rev <= opts.Since {
+					continue
+				}
+
+				b, err := db.readBlob(ctx, obj.Key)
+
+				if !yield(b, err) {
+					return
+				}
+
+				count += 1
+
+				if opts.Limit > 0 && count >= opts.Limit {
+					return
+				}
+			}
+
+			page_token = next_token
+		}
+	}
+}
+
+// SetBlobRefs stores the CIDs of the blobs referenced by a record, and the revision of the commit which wrote it,
+// as a JSON file at "blob_refs/{DID}/{COLLECTION}/{RKEY}.json".
+func (db *BlobBlobsDatabase) SetBlobRefs(ctx context.Context, did string, path string, rev string, cids []string) error {
+
+	refs_path := db.refsPath(did, path)
+
+	if len(cids) == 0 {
+
+		err := db.bucket.Delete(ctx, refs_path)
+
+		if err != nil && !isBucketNotFound(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	wr, err := db.bucket.NewWriter(ctx, refs_path, nil)
+
+	if err != nil {
+		return err
+	}
+
+	refs := &blobRefs{
+		Rev:  rev,
+		CIDs: cids,
+	}
+
+	enc := json.NewEncoder(wr)
+	err = enc.Encode(refs)
+
+	if err != nil {
+		wr.Close()
+		return err
+	}
+
+	return wr.Close()
+}
+
+// IsBlobReferenced reads all the blob references for 'did' so its cost is proportional to the number of records which reference blobs.
+func (db *BlobBlobsDatabase) IsBlobReferenced(ctx context.Context, did string, blob_cid string) (bool, error) {
+
+	refs_prefix := filepath.Join("blob_refs", did) + "/"
+
+	referenced, err := db.readAllRefs(ctx, refs_prefix)
+
+	if err != nil {
+		return false, err
+	}
+
+	_, ok := referenced[filepath.Join(did, blob_cid)]
+	return ok, nil
+}
+
+// ListUnreferencedBlobs reads all the blob references (for 'opts.DID' or all DIDs) before listing blobs so its
+// cost is proportional to the number of records which reference blobs.
+func (db *BlobBlobsDatabase) ListUnreferencedBlobs(ctx context.Context, opts *ListUnreferencedBlobsOptions) iter.Seq2[*Blob, error] {
+
+	return func(yield func(*Blob, error) bool) {
+
+		refs_prefix := "blob_refs/"
+		blobs_prefix := "blobs/"
+
+		if opts.DID != "" {
+			refs_prefix = filepath.Join("blob_refs", opts.DID) + "/"
+			blobs_prefix = filepath.Join("blobs", opts.DID) + "/"
+		}
+
+		referenced, err := db.readAllRefs(ctx, refs_prefix)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		blobs_iter := db.bucket.List(&blob.ListOptions{Prefix: blobs_prefix})
+
+		for {
+
+			obj, err := blobs_iter.Next(ctx)
+
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if obj.IsDir || !strings.HasSuffix(obj.Key, ".json") {
+				continue
+			}
+
+			b, err := db.readBlob(ctx, obj.Key)
+
+			if err != nil {
+
+				if !yield(nil, err) {
+					return
+				}
+
+				continue
+			}
+
+			_, ok := referenced[filepath.Join(b.DID, b.CID)]
+
+			if ok || b.LastModified >= opts.Before {
+				continue
+			}
+
+			if !yield(b, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (db *BlobBlobsDatabase) Close() error {
+	return db.bucket.Close()
+}
+
+func (db *BlobBlobsDatabase) readBlob(ctx context.Context, path string) (*Blob, error) {
+
+	exists, err := db.bucket.Exists(ctx, path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, atproto.ErrNotFound
+	}
+
+	r, err := db.bucket.NewReader(ctx, path, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close()
+
+	var b *Blob
+
+	dec := json.NewDecoder(r)
+	err = dec.Decode(&b)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (db *BlobBlobsDatabase) writeBlob(ctx context.Context, b *Blob) error {
+
+	path := db.blobPath(b.DID, b.CID)
+
+	wr, err := db.bucket.NewWriter(ctx, path, nil)
+
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(wr)
+	err = enc.Encode(b)
+
+	if err != nil {
+		return err
+	}
+
+	return wr.Close()
+}
+
+func (db *BlobBlobsDatabase) readRefs(ctx context.Context, path string) (*blobRefs, error) {
+
+	r, err := db.bucket.NewReader(ctx, path, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close()
+
+	var refs *blobRefs
+
+	dec := json.NewDecoder(r)
+	err = dec.Decode(&refs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return refs, nil
+}
+
+// readAllRefs reads all the blob references under 'prefix' and returns a map of "{DID}/{CID}" keys and the most
+// recent revision of the commits which wrote records referencing that blob.
+func (db *BlobBlobsDatabase) readAllRefs(ctx context.Context, prefix string) (map[string]string, error) {
+
+	referenced := make(map[string]string)
+
+	refs_iter := db.bucket.List(&blob.ListOptions{Prefix: prefix})
+
+	for {
+
+		obj, err := refs_iter.Next(ctx)
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if obj.IsDir || !strings.HasSuffix(obj.Key, ".json") {
+			continue
+		}
+
+		parts := strings.Split(obj.Key, "/")
+
+		if len(parts) < 3 {
+			continue
+		}
+
+		did := parts[1]
+
+		refs, err := db.readRefs(ctx, obj.Key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, blob_cid := range refs.CIDs {
+
+			k := filepath.Join(did, blob_cid)
+
+			rev, ok := referenced[k]
+
+			if !ok || refs.Rev > rev {
+				referenced[k] = refs.Rev
+			}
+		}
+	}
+
+	return referenced, nil
+}
+
+func (db *BlobBlobsDatabase) refsPath(did string, path string) string {
+	fname := fmt.Sprintf("%s.json", path)
+	return filepath.Join("blob_refs", did, fname)
+}
+
+func (db *BlobBlobsDatabase) blobPath(did string, blob_cid string) string {
+	fname := fmt.Sprintf("%s.json", blob_cid)
+	path := filepath.Join("blobs", did, fname)
+	return path
+}
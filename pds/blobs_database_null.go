@@ -0,0 +1,59 @@
+package pds
+
+import (
+	"context"
+	"iter"
+
+	"github.com/sfomuseum/go-atproto"
+)
+
+type NullBlobsDatabase struct {
+	BlobsDatabase
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterBlobsDatabase(ctx, "null", NewNullBlobsDatabase)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func NewNullBlobsDatabase(ctx context.Context, uri string) (BlobsDatabase, error) {
+	db := &NullBlobsDatabase{}
+	return db, nil
+}
+
+func (db *NullBlobsDatabase) GetBlob(ctx context.Context, did string, blob_cid string) (*Blob, error) {
+	return nil, atproto.ErrNotFound
+}
+
+func (db *NullBlobsDatabase) AddBlob(ctx context.Context, b *Blob) error {
+	return nil
+}
+
+func (db *NullBlobsDatabase) DeleteBlob(ctx context.Context, b *Blob) error {
+	return nil
+}
+
+func (db *NullBlobsDatabase) ListBlobs(ctx context.Context, opts *ListBlobsOptions) iter.Seq2[*Blob, error] {
+	return func(yield func(*Blob, error) bool) {}
+}
+
+func (db *NullBlobsDatabase) SetBlobRefs(ctx context.Context, did string, path string, rev string, cids []string) error {
+	return nil
+}
+
+func (db *NullBlobsDatabase) IsBlobReferenced(ctx context.Context, did string, blob_cid string) (bool, error) {
+	return false, nil
+}
+
+func (db *NullBlobsDatabase) ListUnreferencedBlobs(ctx context.Context, opts *ListUnreferencedBlobsOptions) iter.Seq2[*Blob, error] {
+	return func(yield func(*Blob, error) bool) {}
+}
+
+func (db *NullBlobsDatabase) Close() error {
+	return nil
+}
@@ -0,0 +1,319 @@
+package pds
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"net/url"
+
+	"github.com/sfomuseum/go-atproto"
+)
+
+type SQLBlobsDatabase struct {
+	BlobsDatabase
+	conn   *sql.DB
+	engine string
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterBlobsDatabase(ctx, "sql", NewSQLBlobsDatabase)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func NewSQLBlobsDatabase(ctx context.Context, uri string) (BlobsDatabase, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	engine := u.Host
+	dsn := q.Get("dsn")
+
+	if engine == "" {
+		return nil, fmt.Errorf("Missing database engine")
+	}
+
+	if dsn == "" {
+		return nil, fmt.Errorf("Missing DSN string")
+	}
+
+	conn, err := sql.Open(engine, dsn)
+
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create database (%s) because %v", engine, err)
+	}
+
+	switch engine {
+	case "sqlite3":
+		conn.SetMaxOpenConns(1)
+	}
+
+	db := &SQLBlobsDatabase{
+		conn:   conn,
+		engine: engine,
+	}
+
+	return db, nil
+}
+
+func (db *SQLBlobsDatabase) GetBlob(ctx context.Context, did string, blob_cid string) (*Blob, error) {
+
+	q := "SELECT cid, did, media_type, size, width, height, created, lastmodified FROM blobs WHERE did = ? AND cid = ?"
+
+	row := db.conn.QueryRowContext(ctx, q, did, blob_cid)
+
+	b, err := db.scanBlob(row)
+
+	if err != nil {
+
+		if err == sql.ErrNoRows {
+			return nil, atproto.ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (db *SQLBlobsDatabase) AddBlob(ctx context.Context, b *Blob) error {
+
+	q := "INSERT INTO blobs (cid, did, media_type, size, width, height, created, lastmodified) VALUES (?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (did, cid) DO UPDATE SET lastmodified = excluded.lastmodified"
+
+	_, err := db.conn.ExecContext(ctx, q, b.CID, b.DID, b.MediaType, b.Size, b.Width, b.Height, b.Created, b.LastModified)
+	return err
+}
+
+func (db *SQLBlobsDatabase) DeleteBlob(ctx context.Context, b *Blob) error {
+
+	q := "DELETE FROM blobs WHERE did = ? AND cid = ?"
+
+	_, err := db.conn.ExecContext(ctx, q, b.DID, b.CID)
+	return err
+}
+
+func (db *SQLBlobsDatabase) ListBlobs(ctx context.Context, opts *ListBlobsOptions) iter.Seq2[*Blob, error] {
+
+	return func(yield func(*Blob, error) bool) {
+
+		q := "SELECT b.cid, b.did, b.media_type, b.size, b.width, b.height, b.created, b.lastmodified FROM blobs b WHERE b.did = ? AND EXISTS (SELECT 1 FROM blob_refs r WHERE r.did = b.did AND r.cid = b.cid AND r.rev > ?)"
+
+		args := []any{
+			opts.DID,
+			opts.Since,
+		}
+
+		if opts.Cursor != "" {
+			q = fmt.Sprintf("%s AND b.cid > ?", q)
+			args = append(args, opts.Cursor)
+		}
+
+		q = fmt.Sprintf("%s ORDER BY b.cid ASC", q)
+
+		if opts.Limit > 0 {
+			q = fmt.Sprintf("%s LIMIT ?", q)
+			args = append(args, opts.Limit)
+		}
+
+		rows, err := db.conn.QueryContext(ctx, q, args...)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+
+			b, err := db.scanBlob(rows)
+
+			if err != nil {
+
+				if !yield(nil, err) {
+					return
+				}
+
+				continue
+			}
+
+			if !yield(b, nil) {
+				return
+			}
+		}
+
+		err = rows.Close()
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		err = rows.Err()
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+	}
+}
+
+// SetBlobRefs replaces the blob references for a record inside a single database transaction.
+func (db *SQLBlobsDatabase) SetBlobRefs(ctx context.Context, did string, path string, rev string, cids []string) error {
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create transaction, %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM blob_refs WHERE did = ? AND path = ?", did, path)
+
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("Failed to remove blob refs for %s, %w", path, err)
+	}
+
+	for _, blob_cid := range cids {
+
+		_, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO blob_refs (did, path, cid, rev) VALUES (?, ?, ?, ?)", did, path, blob_cid, rev)
+
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("Failed to add blob ref %s for %s, %w", blob_cid, path, err)
+		}
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		return fmt.Errorf("Failed to commit transaction, %w", err)
+	}
+
+	return nil
+}
+
+func (db *SQLBlobsDatabase) IsBlobReferenced(ctx context.Context, did string, blob_cid string) (bool, error) {
+
+	q := "SELECT 1 FROM blob_refs WHERE did = ? AND cid = ? LIMIT 1"
+
+	var v int
+
+	err := db.conn.QueryRowContext(ctx, q, did, blob_cid).Scan(&v)
+
+	if err != nil {
+
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (db *SQLBlobsDatabase) ListUnreferencedBlobs(ctx context.Context, opts *ListUnreferencedBlobsOptions) iter.Seq2[*Blob, error] {
+
+	return func(yield func(*Blob, error) bool) {
+
+		q := "SELECT b.cid, b.did, b.media_type, b.size, b.width, b.height, b.created, b.lastmodified FROM blobs b WHERE b.lastmodified < ? AND NOT EXISTS (SELECT 1 FROM blob_refs r WHERE r.did = b.did AND r.cid = b.cid)"
+
+		args := []any{
+			opts.Before,
+		}
+
+		if opts.DID != "" {
+			q = fmt.Sprintf("%s AND b.did = ?", q)
+			args = append(args, opts.DID)
+		}
+
+		q = fmt.Sprintf("%s ORDER BY b.did ASC, b.cid ASC", q)
+
+		rows, err := db.conn.QueryContext(ctx, q, args...)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+
+			b, err := db.scanBlob(rows)
+
+			if err != nil {
+
+				if !yield(nil, err) {
+					return
+				}
+
+				continue
+			}
+
+			if !yield(b, nil) {
+				return
+			}
+		}
+
+		err = rows.Close()
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		err = rows.Err()
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+	}
+}
+
+func (db *SQLBlobsDatabase) Close() error {
+	return db.conn.Close()
+}
+
+func (db *SQLBlobsDatabase) scanBlob(row sqlRowScanner) (*Blob, error) {
+
+	var c string
+	var did string
+	var media_type string
+	var size int64
+	var width int
+	var height int
+	var created int64
+	var lastmodified int64
+
+	err := row.Scan(&c, &did, &media_type, &size, &width, &height, &created, &lastmodified)
+
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Blob{
+		CID:          c,
+		DID:          did,
+		MediaType:    media_type,
+		Size:         size,
+		Width:        width,
+		Height:       height,
+		Created:      created,
+		LastModified: lastmodified,
+	}
+
+	return b, nil
+}
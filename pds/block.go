@@ -0,0 +1,100 @@
+package pds
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"iter"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/sfomuseum/go-atproto/car"
+)
+
+// Block is a DAG-CBOR encoded block (commit, tree node or record) stored in a `BlocksDatabase`.
+type Block struct {
+	// The DID of the repository the block belongs to.
+	DID string `json:"did"`
+	CID string `json:"cid"`
+	// The revision of the commit the block was most recently written by.
+	Rev     string `json:"rev"`
+	Data    []byte `json:"data"`
+	Created int64  `json:"created"`
+}
+
+func GetBlock(ctx context.Context, db BlocksDatabase, did string, block_cid string) (*Block, error) {
+	return db.GetBlock(ctx, did, block_cid)
+}
+
+func ListBlocks(ctx context.Context, db BlocksDatabase, opts *ListBlocksOptions) iter.Seq2[*Block, error] {
+	return db.ListBlocks(ctx, opts)
+}
+
+func GetEarliestRevForDID(ctx context.Context, db BlocksDatabase, did string) (string, error) {
+	return db.GetEarliestRevForDID(ctx, did)
+}
+
+func AddBlocks(ctx context.Context, db BlocksDatabase, blocks []*Block) error {
+
+	now := time.Now()
+	ts := now.Unix()
+
+	for _, b := range blocks {
+		b.Created = ts
+	}
+
+	return db.AddBlocks(ctx, blocks)
+}
+
+func DeleteBlocks(ctx context.Context, db BlocksDatabase, did string, cids []string) error {
+	return db.DeleteBlocks(ctx, did, cids)
+}
+
+func DeleteBlocksForDID(ctx context.Context, db BlocksDatabase, did string) error {
+	return db.DeleteBlocksForDID(ctx, did)
+}
+
+// storeBlocks adds 'blocks' to 'db' as blocks belonging to the repository for 'did' written by the commit with revision 'rev'.
+func storeBlocks(ctx context.Context, db BlocksDatabase, did string, rev string, blocks []*repoBlock) error {
+
+	db_blocks := make([]*Block, len(blocks))
+
+	for i, b := range blocks {
+
+		db_blocks[i] = &Block{
+			DID:  did,
+			CID:  b.cid.String(),
+			Rev:  rev,
+			Data: b.data,
+		}
+	}
+
+	return AddBlocks(ctx, db, db_blocks)
+}
+
+// repoBlock is a DAG-CBOR encoded block (commit, tree node or record) in a repository.
+type repoBlock struct {
+	cid  cid.Cid
+	data []byte
+}
+
+// writeBlocks writes 'blocks' to 'wr' as a CARv1 file whose root is 'root'.
+func writeBlocks(wr io.Writer, root cid.Cid, blocks []*repoBlock) error {
+
+	car_wr, err := car.NewWriter(wr, root)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create CAR writer, %w", err)
+	}
+
+	for _, b := range blocks {
+
+		err := car_wr.WriteBlock(b.cid, b.data)
+
+		if err != nil {
+			return fmt.Errorf("Failed to write block %s, %w", b.cid, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package pds
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/aaronland/go-roster"
+)
+
+type ListBlocksOptions struct {
+	DID string
+	// Only return blocks written by commits whose revision is greater than this value.
+	Since string
+}
+
+// BlocksDatabase stores the DAG-CBOR encoded blocks (commits, tree nodes and records) for repositories
+// keyed by the DID of the repository and the CID of the block.
+type BlocksDatabase interface {
+	GetBlock(context.Context, string, string) (*Block, error)
+	// AddBlocks stores one or more blocks. Blocks which have already been stored are assigned the revision of the
+	// block being added, since they may have been unreachable (but not yet removed) before being written again.
+	AddBlocks(context.Context, []*Block) error
+	// ListBlocks returns the blocks for a repository in ascending order of the revision they were written at.
+	ListBlocks(context.Context, *ListBlocksOptions) iter.Seq2[*Block, error]
+	// GetEarliestRevForDID returns the earliest revision that blocks have been stored for in a repository.
+	GetEarliestRevForDID(context.Context, string) (string, error)
+	// DeleteBlocks removes the blocks identified by one or more CIDs from a repository.
+	DeleteBlocks(context.Context, string, []string) error
+	// DeleteBlocksForDID removes all the blocks for a repository.
+	DeleteBlocksForDID(context.Context, string) error
+	Close() error
+}
+
+var blocks_database_roster roster.Roster
+
+// BlocksDatabaseInitializationFunc is a function defined by individual blocks_database package and used to create
+// an instance of that blocks_database
+type BlocksDatabaseInitializationFunc func(ctx context.Context, uri string) (BlocksDatabase, error)
+
+// RegisterBlocksDatabase registers 'scheme' as a key pointing to 'init_func' in an internal lookup table
+// used to create new `BlocksDatabase` instances by the `NewBlocksDatabase` method.
+func RegisterBlocksDatabase(ctx context.Context, scheme string, init_func BlocksDatabaseInitializationFunc) error {
+
+	err := ensureBlocksDatabaseRoster()
+
+	if err != nil {
+		return err
+	}
+
+	return blocks_database_roster.Register(ctx, scheme, init_func)
+}
+
+func ensureBlocksDatabaseRoster() error {
+
+	if blocks_database_roster == nil {
+
+		r, err := roster.NewDefaultRoster()
+
+		if err != nil {
+			return err
+		}
+
+		blocks_database_roster = r
+	}
+
+	return nil
+}
+
+// NewBlocksDatabase returns a new `BlocksDatabase` instance configured by 'uri'. The value of 'uri' is parsed
+// as a `url.URL` and its scheme is used as the key for a corresponding `BlocksDatabaseInitializationFunc`
+// function used to instantiate the new `BlocksDatabase`. It is assumed that the scheme (and initialization
+// function) have been registered by the `RegisterBlocksDatabase` method.
+func NewBlocksDatabase(ctx context.Context, uri string) (BlocksDatabase, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := u.Scheme
+
+	i, err := blocks_database_roster.Driver(ctx, scheme)
+
+	if err != nil {
+		return nil, err
+	}
+
+	init_func := i.(BlocksDatabaseInitializationFunc)
+	return init_func(ctx, uri)
+}
+
+// Schemes returns the list of schemes that have been registered.
+func BlocksDatabaseSchemes() []string {
+
+	ctx := context.Background()
+	schemes := []string{}
+
+	err := ensureBlocksDatabaseRoster()
+
+	if err != nil {
+		return schemes
+	}
+
+	for _, dr := range blocks_database_roster.Drivers(ctx) {
+		scheme := fmt.Sprintf("%s://", strings.ToLower(dr))
+		schemes = append(schemes, scheme)
+	}
+
+	sort.Strings(schemes)
+	return schemes
+}
@@ -0,0 +1,58 @@
+package pds
+
+import (
+	"context"
+	"iter"
+
+	"github.com/sfomuseum/go-atproto"
+)
+
+type NullBlocksDatabase struct {
+	BlocksDatabase
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterBlocksDatabase(ctx, "null", NewNullBlocksDatabase)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func NewNullBlocksDatabase(ctx context.Context, uri string) (BlocksDatabase, error) {
+	db := &NullBlocksDatabase{}
+	return db, nil
+}
+
+func (db *NullBlocksDatabase) GetBlock(ctx context.Context, did string, block_cid string) (*Block, error) {
+	return nil, atproto.ErrNotFound
+}
+
+func (db *NullBlocksDatabase) AddBlocks(ctx context.Context, blocks []*Block) error {
+	return nil
+}
+
+func (db *NullBlocksDatabase) ListBlocks(ctx context.Context, opts *ListBlocksOptions) iter.Seq2[*Block, error] {
+
+	return func(yield func(*Block, error) bool) {
+		return
+	}
+}
+
+func (db *NullBlocksDatabase) GetEarliestRevForDID(ctx context.Context, did string) (string, error) {
+	return "", atproto.ErrNotFound
+}
+
+func (db *NullBlocksDatabase) DeleteBlocks(ctx context.Context, did string, cids []string) error {
+	return nil
+}
+
+func (db *NullBlocksDatabase) DeleteBlocksForDID(ctx context.Context, did string) error {
+	return nil
+}
+
+func (db *NullBlocksDatabase) Close() error {
+	return nil
+}
@@ -0,0 +1,264 @@
+package pds
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"net/url"
+
+	"github.com/sfomuseum/go-atproto"
+)
+
+type SQLBlocksDatabase struct {
+	BlocksDatabase
+	conn   *sql.DB
+	engine string
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterBlocksDatabase(ctx, "sql", NewSQLBlocksDatabase)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func NewSQLBlocksDatabase(ctx context.Context, uri string) (BlocksDatabase, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	engine := u.Host
+	dsn := q.Get("dsn")
+
+	if engine == "" {
+		return nil, fmt.Errorf("Missing database engine")
+	}
+
+	if dsn == "" {
+		return nil, fmt.Errorf("Missing DSN string")
+	}
+
+	conn, err := sql.Open(engine, dsn)
+
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create database (%s) because %v", engine, err)
+	}
+
+	switch engine {
+	case "sqlite3":
+		conn.SetMaxOpenConns(1)
+	}
+
+	db := &SQLBlocksDatabase{
+		conn:   conn,
+		engine: engine,
+	}
+
+	return db, nil
+}
+
+func (db *SQLBlocksDatabase) GetBlock(ctx context.Context, did string, block_cid string) (*Block, error) {
+
+	q := "SELECT did, cid, rev, data, created FROM blocks WHERE did = ? AND cid = ?"
+
+	row := db.conn.QueryRowContext(ctx, q, did, block_cid)
+
+	b, err := db.scanBlock(row)
+
+	if err != nil {
+
+		if err == sql.ErrNoRows {
+			return nil, atproto.ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (db *SQLBlocksDatabase) AddBlocks(ctx context.Context, blocks []*Block) error {
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create transaction, %w", err)
+	}
+
+	defer tx.Rollback()
+
+	// Blocks which have already been stored are updated with the revision they are being written at. Only blocks
+	// which are new to a commit are added so a block which has already been stored is either a record with the same
+	// CID as another record or a stale block (see `staleBlocks`) which failed to be removed and is reachable again.
+	// In both cases it needs to be included in the blocks written since any earlier revision.
+
+	q := "INSERT INTO blocks (did, cid, rev, data, created) VALUES (?, ?, ?, ?, ?) ON CONFLICT (did, cid) DO UPDATE SET rev = excluded.rev"
+
+	for _, b := range blocks {
+
+		_, err := tx.ExecContext(ctx, q, b.DID, b.CID, b.Rev, b.Data, b.Created)
+
+		if err != nil {
+			return fmt.Errorf("Failed to add block %s, %w", b.CID, err)
+		}
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		return fmt.Errorf("Failed to commit transaction, %w", err)
+	}
+
+	return nil
+}
+
+func (db *SQLBlocksDatabase) ListBlocks(ctx context.Context, opts *ListBlocksOptions) iter.Seq2[*Block, error] {
+
+	return func(yield func(*Block, error) bool) {
+
+		q := "SELECT did, cid, rev, data, created FROM blocks WHERE did = ? AND rev > ? ORDER BY rev ASC"
+
+		rows, err := db.conn.QueryContext(ctx, q, opts.DID, opts.Since)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+
+			b, err := db.scanBlock(rows)
+
+			if err != nil {
+
+				if !yield(nil, err) {
+					return
+				}
+
+				continue
+			}
+
+			if !yield(b, nil) {
+				return
+			}
+		}
+
+		err = rows.Close()
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		err = rows.Err()
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+	}
+}
+
+func (db *SQLBlocksDatabase) GetEarliestRevForDID(ctx context.Context, did string) (string, error) {
+
+	q := "SELECT MIN(rev) FROM blocks WHERE did = ? AND rev IS NOT NULL AND rev != ''"
+
+	row := db.conn.QueryRowContext(ctx, q, did)
+
+	var rev sql.NullString
+
+	err := row.Scan(&rev)
+
+	if err != nil {
+		return "", err
+	}
+
+	if !rev.Valid {
+		return "", atproto.ErrNotFound
+	}
+
+	return rev.String, nil
+}
+
+func (db *SQLBlocksDatabase) DeleteBlocks(ctx context.Context, did string, cids []string) error {
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create transaction, %w", err)
+	}
+
+	defer tx.Rollback()
+
+	q := "DELETE FROM blocks WHERE did = ? AND cid = ?"
+
+	for _, c := range cids {
+
+		_, err := tx.ExecContext(ctx, q, did, c)
+
+		if err != nil {
+			return fmt.Errorf("Failed to delete block %s, %w", c, err)
+		}
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		return fmt.Errorf("Failed to commit transaction, %w", err)
+	}
+
+	return nil
+}
+
+func (db *SQLBlocksDatabase) DeleteBlocksForDID(ctx context.Context, did string) error {
+
+	q := "DELETE FROM blocks WHERE did = ?"
+
+	_, err := db.conn.ExecContext(ctx, q, did)
+
+	if err != nil {
+		return fmt.Errorf("Failed to delete blocks, %w", err)
+	}
+
+	return nil
+}
+
+func (db *SQLBlocksDatabase) scanBlock(row sqlRowScanner) (*Block, error) {
+
+	var did string
+	var c string
+	var rev sql.NullString
+	var data []byte
+	var created int64
+
+	err := row.Scan(&did, &c, &rev, &data, &created)
+
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Block{
+		DID:     did,
+		CID:     c,
+		Rev:     rev.String,
+		Data:    data,
+		Created: created,
+	}
+
+	return b, nil
+}
+
+func (db *SQLBlocksDatabase) Close() error {
+	return db.conn.Close()
+}
@@ -0,0 +1,109 @@
+package pds
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// testSQLSchema creates the tables defined in the SQLite schema file 'name' using 'conn'.
+func testSQLSchema(t *testing.T, conn *sql.DB, name string) {
+
+	schema, err := os.ReadFile(filepath.Join("..", "schema", "sqlite3", name))
+
+	if err != nil {
+		t.Fatalf("Failed to read schema %s, %v", name, err)
+	}
+
+	_, err = conn.ExecContext(context.Background(), string(schema))
+
+	if err != nil {
+		t.Fatalf("Failed to create tables for %s, %v", name, err)
+	}
+}
+
+func TestSQLBlocksDatabaseAddBlocks(t *testing.T) {
+
+	ctx := context.Background()
+
+	dsn := filepath.Join(t.TempDir(), "blocks.db")
+
+	db, err := NewBlocksDatabase(ctx, "sql://sqlite3?dsn="+dsn)
+
+	if err != nil {
+		t.Fatalf("Failed to create blocks database, %v", err)
+	}
+
+	defer db.Close()
+
+	testSQLSchema(t, db.(*SQLBlocksDatabase).conn, "blocks.sql")
+
+	node := "bafyreie5737gdxlw5i64vzichcalba3z2v5n6icifvx5xytvske7mr3hpm"
+	record := "bafyreie5cvv4h45feadgeuwhbcutmh6t2ceseocckahdoe6uat64zmz454"
+
+	add := func(rev string, cids ...string) {
+
+		blocks := make([]*Block, len(cids))
+
+		for i, c := range cids {
+			blocks[i] = &Block{
+				DID:  test_did,
+				CID:  c,
+				Rev:  rev,
+				Data: []byte{0xa0},
+			}
+		}
+
+		err := AddBlocks(ctx, db, blocks)
+
+		if err != nil {
+			t.Fatalf("Failed to add blocks, %v", err)
+		}
+	}
+
+	since := func(rev string) []string {
+
+		cids := make([]string, 0)
+
+		for b, err := range ListBlocks(ctx, db, &ListBlocksOptions{DID: test_did, Since: rev}) {
+
+			if err != nil {
+				t.Fatalf("Failed to list blocks, %v", err)
+			}
+
+			cids = append(cids, b.CID)
+		}
+
+		return cids
+	}
+
+	add("3jzfcijpj2z2a", node, record)
+	add("3jzfcijpj2z2b", node)
+
+	// A block written again (for example a stale block which was not removed and is reachable again) is included
+	// in the blocks written since any earlier revision
+
+	cids := since("3jzfcijpj2z2a")
+
+	if len(cids) != 1 || cids[0] != node {
+		t.Fatalf("Expected only %s since 3jzfcijpj2z2a, got %v", node, cids)
+	}
+
+	b, err := GetBlock(ctx, db, test_did, node)
+
+	if err != nil {
+		t.Fatalf("Failed to retrieve block, %v", err)
+	}
+
+	if b.Rev != "3jzfcijpj2z2b" {
+		t.Fatalf("Expected block revision to be updated, got %s", b.Rev)
+	}
+
+	if len(since("")) != 2 {
+		t.Fatalf("Expected 2 blocks")
+	}
+}
@@ -0,0 +1,371 @@
+package pds
+
+// https://atproto.com/specs/repository#commit-objects
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	at_crypto "github.com/bluesky-social/indigo/atproto/crypto"
+	"github.com/bluesky-social/indigo/atproto/identity"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/ipfs/go-cid"
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/dagcbor"
+	"github.com/sfomuseum/go-atproto/mst"
+)
+
+// The current version of the atproto repository format.
+const REPO_VERSION int64 = 3
+
+// Commit is a signed atproto repository commit for an account.
+type Commit struct {
+	// The CID of the signed commit object.
+	CID string `json:"cid"`
+	// The DID of the account (repository).
+	DID     string `json:"did"`
+	Version int64  `json:"version"`
+	// The CID of the root node of the repository's Merkle Search Tree.
+	Data string `json:"data"`
+	// The revision (a TID) of the commit.
+	Rev string `json:"rev"`
+	// The CID of the previous commit, if present.
+	Prev         string `json:"prev,omitempty"`
+	Signature    []byte `json:"sig"`
+	Created      int64  `json:"created"`
+	LastModified int64  `json:"lastmodified"`
+}
+
+// UnsignedBytes returns the DAG-CBOR encoding of 'c' without a signature. These are the bytes that get signed.
+func (c *Commit) UnsignedBytes() ([]byte, error) {
+
+	obj, err := c.asMap(false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return dagcbor.Marshal(obj)
+}
+
+// Bytes returns the DAG-CBOR encoding of the signed commit.
+func (c *Commit) Bytes() ([]byte, error) {
+
+	if len(c.Signature) == 0 {
+		return nil, fmt.Errorf("Commit is not signed")
+	}
+
+	obj, err := c.asMap(true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return dagcbor.Marshal(obj)
+}
+
+// Sign signs 'c' with 'key' and assigns the resulting signature and the CID of the signed commit.
+func (c *Commit) Sign(key at_crypto.PrivateKey) error {
+
+	unsigned, err := c.UnsignedBytes()
+
+	if err != nil {
+		return fmt.Errorf("Failed to encode unsigned commit, %w", err)
+	}
+
+	sig, err := key.HashAndSign(unsigned)
+
+	if err != nil {
+		return fmt.Errorf("Failed to sign commit, %w", err)
+	}
+
+	c.Signature = sig
+
+	b, err := c.Bytes()
+
+	if err != nil {
+		return fmt.Errorf("Failed to encode signed commit, %w", err)
+	}
+
+	commit_cid, err := dagcbor.CID(b)
+
+	if err != nil {
+		return err
+	}
+
+	c.CID = commit_cid.String()
+	return nil
+}
+
+// Verify ensures that the signature for 'c' was produced by 'key'.
+func (c *Commit) Verify(key at_crypto.PublicKey) error {
+
+	unsigned, err := c.UnsignedBytes()
+
+	if err != nil {
+		return fmt.Errorf("Failed to encode unsigned commit, %w", err)
+	}
+
+	return key.HashAndVerify(unsigned, c.Signature)
+}
+
+func (c *Commit) asMap(signed bool) (map[string]any, error) {
+
+	data_cid, err := cid.Decode(c.Data)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid data CID, %w", err)
+	}
+
+	var prev any
+
+	if c.Prev != "" {
+
+		prev_cid, err := cid.Decode(c.Prev)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid prev CID, %w", err)
+		}
+
+		prev = prev_cid
+	}
+
+	obj := map[string]any{
+		"did":     c.DID,
+		"version": c.Version,
+		"data":    data_cid,
+		"rev":     c.Rev,
+		"prev":    prev,
+	}
+
+	if signed {
+		obj["sig"] = c.Signature
+	}
+
+	return obj, nil
+}
+
+// DeriveTree returns a new Merkle Search Tree for all the records associated with 'did'.
+func DeriveTree(ctx context.Context, db RecordsDatabase, did string) (*mst.Tree, error) {
+
+	leaves, err := deriveLeaves(ctx, db, did)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newTreeFromLeaves(leaves)
+}
+
+// deriveLeaves returns a map of "{COLLECTION}/{RKEY}" paths and record CIDs for all the records associated with 'did'.
+// This reads every record for 'did' so it is O(N) in the size of the repository.
+func deriveLeaves(ctx context.Context, db RecordsDatabase, did string) (map[string]cid.Cid, error) {
+
+	leaves := make(map[string]cid.Cid)
+
+	list_opts := &ListRecordsOptions{
+		Repo: did,
+	}
+
+	for rec, err := range db.ListRecords(ctx, list_opts) {
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to list records, %w", err)
+		}
+
+		rec_cid, err := cid.Decode(rec.CID)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CID for %s, %w", rec.Path(), err)
+		}
+
+		leaves[rec.Path()] = rec_cid
+	}
+
+	return leaves, nil
+}
+
+func newTreeFromLeaves(leaves map[string]cid.Cid) (*mst.Tree, error) {
+
+	entries := make([]*mst.Entry, 0)
+
+	for k, v := range leaves {
+
+		e := &mst.Entry{
+			Key:   k,
+			Value: v,
+		}
+
+		entries = append(entries, e)
+	}
+
+	return mst.NewTree(entries)
+}
+
+// newCommit returns a new commit for 'tree', following 'prev' (which may be nil), signed by the account's
+// "atproto" key. The commit is not stored.
+func newCommit(ctx context.Context, keys_db KeysDatabase, did string, tree *mst.Tree, prev *Commit) (*Commit, error) {
+
+	k, err := keys_db.GetKey(ctx, did, "atproto")
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to retrieve atproto key, %w", err)
+	}
+
+	pr_key, err := k.PrivateKeyK256()
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive private key, %w", err)
+	}
+
+	prev_rev := ""
+	prev_cid := ""
+
+	if prev != nil {
+		prev_rev = prev.Rev
+		prev_cid = prev.CID
+	}
+
+	rev, err := NewTIDAfter(prev_rev)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive revision, %w", err)
+	}
+
+	c := &Commit{
+		DID:     did,
+		Version: REPO_VERSION,
+		Data:    tree.Root().String(),
+		Rev:     rev.String(),
+		Prev:    prev_cid,
+	}
+
+	err = c.Sign(pr_key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// DecodeCommit decodes the DAG-CBOR encoded signed commit object in 'b'.
+func DecodeCommit(b []byte) (*Commit, error) {
+
+	var obj map[string]any
+
+	err := dagcbor.Unmarshal(b, &obj)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode commit, %w", err)
+	}
+
+	did, ok := obj["did"].(string)
+
+	if !ok {
+		return nil, fmt.Errorf("Invalid commit, missing did")
+	}
+
+	version, ok := obj["version"].(int)
+
+	if !ok || int64(version) != REPO_VERSION {
+		return nil, fmt.Errorf("Invalid commit, unsupported version (%v)", obj["version"])
+	}
+
+	data, ok := obj["data"].(cid.Cid)
+
+	if !ok {
+		return nil, fmt.Errorf("Invalid commit, missing data")
+	}
+
+	rev, ok := obj["rev"].(string)
+
+	if !ok {
+		return nil, fmt.Errorf("Invalid commit, missing rev")
+	}
+
+	sig, ok := obj["sig"].([]byte)
+
+	if !ok {
+		return nil, fmt.Errorf("Invalid commit, missing sig")
+	}
+
+	c := &Commit{
+		DID:       did,
+		Version:   int64(version),
+		Data:      data.String(),
+		Rev:       rev,
+		Signature: sig,
+	}
+
+	prev, ok := obj["prev"].(cid.Cid)
+
+	if ok {
+		c.Prev = prev.String()
+	}
+
+	commit_cid, err := dagcbor.CID(b)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.CID = commit_cid.String()
+	return c, nil
+}
+
+// VerifyCommitForDID ensures that 'c' was signed by the current signing key for its DID, as resolved by 'dir'. If 'dir'
+// is nil then `identity.DefaultDirectory` is used. If the signature is not valid an error wrapping `atproto.ErrInvalidSignature`
+// is returned.
+func VerifyCommitForDID(ctx context.Context, dir identity.Directory, c *Commit) error {
+
+	if dir == nil {
+		dir = identity.DefaultDirectory()
+	}
+
+	did, err := syntax.ParseDID(c.DID)
+
+	if err != nil {
+		return fmt.Errorf("Invalid DID, %w", err)
+	}
+
+	id, err := dir.LookupDID(ctx, did)
+
+	if err != nil {
+		return fmt.Errorf("Failed to resolve %s, %w", did, err)
+	}
+
+	pub_key, err := id.PublicKey()
+
+	if err != nil {
+		return fmt.Errorf("Failed to derive public key for %s, %w", did, err)
+	}
+
+	err = c.Verify(pub_key)
+
+	if err != nil {
+		return fmt.Errorf("%w, %w", atproto.ErrInvalidSignature, err)
+	}
+
+	return nil
+}
+
+func GetCommit(ctx context.Context, db CommitsDatabase, commit_cid string) (*Commit, error) {
+	return db.GetCommit(ctx, commit_cid)
+}
+
+func GetLatestCommitForDID(ctx context.Context, db CommitsDatabase, did string) (*Commit, error) {
+	return db.GetLatestCommitForDID(ctx, did)
+}
+
+func AddCommit(ctx context.Context, db CommitsDatabase, c *Commit) error {
+
+	now := time.Now()
+	ts := now.Unix()
+
+	c.Created = ts
+	c.LastModified = ts
+
+	return db.AddCommit(ctx, c)
+}
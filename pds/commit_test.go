@@ -0,0 +1,208 @@
+package pds
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	at_crypto "github.com/bluesky-social/indigo/atproto/crypto"
+	"github.com/bluesky-social/indigo/atproto/identity"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/mst"
+)
+
+const test_did string = "did:plc:ewvi7nxzyoun6zhxrhs64oiz"
+
+func testCommit(t *testing.T, key at_crypto.PrivateKey) *Commit {
+
+	tree, err := mst.NewTree([]*mst.Entry{})
+
+	if err != nil {
+		t.Fatalf("Failed to create tree, %v", err)
+	}
+
+	c := &Commit{
+		DID:     test_did,
+		Version: REPO_VERSION,
+		Data:    tree.Root().String(),
+		Rev:     "3jzfcijpj2z2a",
+	}
+
+	err = c.Sign(key)
+
+	if err != nil {
+		t.Fatalf("Failed to sign commit, %v", err)
+	}
+
+	return c
+}
+
+func testKey(t *testing.T) *at_crypto.PrivateKeyK256 {
+
+	key, err := at_crypto.GeneratePrivateKeyK256()
+
+	if err != nil {
+		t.Fatalf("Failed to generate key, %v", err)
+	}
+
+	return key
+}
+
+func TestCommitSignVerify(t *testing.T) {
+
+	key := testKey(t)
+	c := testCommit(t, key)
+
+	if c.Data != "bafyreie5737gdxlw5i64vzichcalba3z2v5n6icifvx5xytvske7mr3hpm" {
+		t.Fatalf("Unexpected data CID for empty tree, %s", c.Data)
+	}
+
+	pub_key, err := key.PublicKey()
+
+	if err != nil {
+		t.Fatalf("Failed to derive public key, %v", err)
+	}
+
+	err = c.Verify(pub_key)
+
+	if err != nil {
+		t.Fatalf("Failed to verify commit, %v", err)
+	}
+
+	other_pub_key, err := testKey(t).PublicKey()
+
+	if err != nil {
+		t.Fatalf("Failed to derive public key, %v", err)
+	}
+
+	err = c.Verify(other_pub_key)
+
+	if err == nil {
+		t.Fatalf("Expected commit to fail verification with a different key")
+	}
+
+	// Any change to the commit invalidates the signature
+
+	c.Rev = "3jzfcijpj2z2b"
+
+	err = c.Verify(pub_key)
+
+	if err == nil {
+		t.Fatalf("Expected modified commit to fail verification")
+	}
+}
+
+func TestDecodeCommit(t *testing.T) {
+
+	key := testKey(t)
+	c := testCommit(t, key)
+
+	b, err := c.Bytes()
+
+	if err != nil {
+		t.Fatalf("Failed to encode commit, %v", err)
+	}
+
+	decoded, err := DecodeCommit(b)
+
+	if err != nil {
+		t.Fatalf("Failed to decode commit, %v", err)
+	}
+
+	if decoded.CID != c.CID || decoded.DID != c.DID || decoded.Data != c.Data || decoded.Rev != c.Rev || decoded.Prev != "" {
+		t.Fatalf("Decoded commit does not match, %v", decoded)
+	}
+
+	if !bytes.Equal(decoded.Signature, c.Signature) {
+		t.Fatalf("Decoded signature does not match")
+	}
+
+	// Commits which follow a previous commit include its CID
+
+	next := &Commit{
+		DID:     c.DID,
+		Version: REPO_VERSION,
+		Data:    c.Data,
+		Rev:     "3jzfcijpj2z2b",
+		Prev:    c.CID,
+	}
+
+	err = next.Sign(key)
+
+	if err != nil {
+		t.Fatalf("Failed to sign commit, %v", err)
+	}
+
+	b, err = next.Bytes()
+
+	if err != nil {
+		t.Fatalf("Failed to encode commit, %v", err)
+	}
+
+	decoded, err = DecodeCommit(b)
+
+	if err != nil {
+		t.Fatalf("Failed to decode commit, %v", err)
+	}
+
+	if decoded.Prev != c.CID || decoded.CID != next.CID {
+		t.Fatalf("Decoded commit does not match, %v", decoded)
+	}
+
+	_, err = DecodeCommit([]byte("not a commit"))
+
+	if err == nil {
+		t.Fatalf("Expected an error decoding invalid data")
+	}
+
+	unsigned, err := c.UnsignedBytes()
+
+	if err != nil {
+		t.Fatalf("Failed to encode unsigned commit, %v", err)
+	}
+
+	_, err = DecodeCommit(unsigned)
+
+	if err == nil {
+		t.Fatalf("Expected an error decoding an unsigned commit")
+	}
+}
+
+func TestVerifyCommitForDID(t *testing.T) {
+
+	ctx := context.Background()
+
+	key := testKey(t)
+
+	pub_key, err := key.PublicKey()
+
+	if err != nil {
+		t.Fatalf("Failed to derive public key, %v", err)
+	}
+
+	dir := identity.NewMockDirectory()
+
+	dir.Insert(identity.Identity{
+		DID: syntax.DID(test_did),
+		Keys: map[string]identity.VerificationMethod{
+			"atproto": {
+				Type:               "Multikey",
+				PublicKeyMultibase: pub_key.Multibase(),
+			},
+		},
+	})
+
+	err = VerifyCommitForDID(ctx, &dir, testCommit(t, key))
+
+	if err != nil {
+		t.Fatalf("Failed to verify commit, %v", err)
+	}
+
+	err = VerifyCommitForDID(ctx, &dir, testCommit(t, testKey(t)))
+
+	if !errors.Is(err, atproto.ErrInvalidSignature) {
+		t.Fatalf("Expected invalid signature error, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package pds
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/aaronland/go-roster"
+)
+
+type ListCommitsOptions struct {
+	DID string
+}
+
+type CommitsDatabase interface {
+	GetCommit(context.Context, string) (*Commit, error)
+	GetLatestCommitForDID(context.Context, string) (*Commit, error)
+	AddCommit(context.Context, *Commit) error
+	ListCommits(context.Context, *ListCommitsOptions) iter.Seq2[*Commit, error]
+	Close() error
+}
+
+var commit_database_roster roster.Roster
+
+// CommitsDatabaseInitializationFunc is a function defined by individual commit_database package and used to create
+// an instance of that commit_database
+type CommitsDatabaseInitializationFunc func(ctx context.Context, uri string) (CommitsDatabase, error)
+
+// RegisterCommitsDatabase registers 'scheme' as a key pointing to 'init_func' in an internal lookup table
+// used to create new `CommitsDatabase` instances by the `NewCommitsDatabase` method.
+func RegisterCommitsDatabase(ctx context.Context, scheme string, init_func CommitsDatabaseInitializationFunc) error {
+
+	err := ensureCommitsDatabaseRoster()
+
+	if err != nil {
+		return err
+	}
+
+	return commit_database_roster.Register(ctx, scheme, init_func)
+}
+
+func ensureCommitsDatabaseRoster() error {
+
+	if commit_database_roster == nil {
+
+		r, err := roster.NewDefaultRoster()
+
+		if err != nil {
+			return err
+		}
+
+		commit_database_roster = r
+	}
+
+	return nil
+}
+
+// NewCommitsDatabase returns a new `CommitsDatabase` instance configured by 'uri'. The value of 'uri' is parsed
+// as a `url.URL` and its scheme is used as the key for a corresponding `CommitsDatabaseInitializationFunc`
+// function used to instantiate the new `CommitsDatabase`. It is assumed that the scheme (and initialization
+// function) have been registered by the `RegisterCommitsDatabase` method.
+func NewCommitsDatabase(ctx context.Context, uri string) (CommitsDatabase, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := u.Scheme
+
+	i, err := commit_database_roster.Driver(ctx, scheme)
+
+	if err != nil {
+		return nil, err
+	}
+
+	init_func := i.(CommitsDatabaseInitializationFunc)
+	return init_func(ctx, uri)
+}
+
+// Schemes returns the list of schemes that have been registered.
+func CommitsDatabaseSchemes() []string {
+
+	ctx := context.Background()
+	schemes := []string{}
+
+	err := ensureCommitsDatabaseRoster()
+
+	if err != nil {
+		return schemes
+	}
+
+	for _, dr := range commit_database_roster.Drivers(ctx) {
+		scheme := fmt.Sprintf("%s://", strings.ToLower(dr))
+		schemes = append(schemes, scheme)
+	}
+
+	sort.Strings(schemes)
+	return schemes
+}
@@ -0,0 +1,47 @@
+package pds
+
+import (
+	"context"
+	"iter"
+
+	"github.com/sfomuseum/go-atproto"
+)
+
+type NullCommitsDatabase struct {
+	CommitsDatabase
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterCommitsDatabase(ctx, "null", NewNullCommitsDatabase)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func NewNullCommitsDatabase(ctx context.Context, uri string) (CommitsDatabase, error) {
+	db := &NullCommitsDatabase{}
+	return db, nil
+}
+
+func (db *NullCommitsDatabase) GetCommit(ctx context.Context, commit_cid string) (*Commit, error) {
+	return nil, atproto.ErrNotFound
+}
+
+func (db *NullCommitsDatabase) GetLatestCommitForDID(ctx context.Context, did string) (*Commit, error) {
+	return nil, atproto.ErrNotFound
+}
+
+func (db *NullCommitsDatabase) AddCommit(ctx context.Context, c *Commit) error {
+	return nil
+}
+
+func (db *NullCommitsDatabase) ListCommits(ctx context.Context, opts *ListCommitsOptions) iter.Seq2[*Commit, error] {
+	return func(yield func(*Commit, error) bool) {}
+}
+
+func (db *NullCommitsDatabase) Close() error {
+	return nil
+}
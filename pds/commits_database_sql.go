@@ -0,0 +1,206 @@
+package pds
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"net/url"
+
+	"github.com/sfomuseum/go-atproto"
+)
+
+type SQLCommitsDatabase struct {
+	CommitsDatabase
+	conn   *sql.DB
+	engine string
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterCommitsDatabase(ctx, "sql", NewSQLCommitsDatabase)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func NewSQLCommitsDatabase(ctx context.Context, uri string) (CommitsDatabase, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	engine := u.Host
+	dsn := q.Get("dsn")
+
+	if engine == "" {
+		return nil, fmt.Errorf("Missing database engine")
+	}
+
+	if dsn == "" {
+		return nil, fmt.Errorf("Missing DSN string")
+	}
+
+	conn, err := sql.Open(engine, dsn)
+
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create database (%s) because %v", engine, err)
+	}
+
+	switch engine {
+	case "sqlite3":
+		conn.SetMaxOpenConns(1)
+	}
+
+	db := &SQLCommitsDatabase{
+		conn:   conn,
+		engine: engine,
+	}
+
+	return db, nil
+}
+
+func (db *SQLCommitsDatabase) GetCommit(ctx context.Context, commit_cid string) (*Commit, error) {
+
+	q := "SELECT cid, did, version, data, rev, prev, sig, created, lastmodified FROM commits WHERE cid = ?"
+	return db.getCommit(ctx, q, commit_cid)
+}
+
+func (db *SQLCommitsDatabase) GetLatestCommitForDID(ctx context.Context, did string) (*Commit, error) {
+
+	q := "SELECT cid, did, version, data, rev, prev, sig, created, lastmodified FROM commits WHERE did = ? ORDER BY rev DESC LIMIT 1"
+	return db.getCommit(ctx, q, did)
+}
+
+func (db *SQLCommitsDatabase) getCommit(ctx context.Context, q string, args ...interface{}) (*Commit, error) {
+
+	row := db.conn.QueryRowContext(ctx, q, args...)
+
+	c, err := db.scanCommit(row)
+
+	if err != nil {
+
+		if err == sql.ErrNoRows {
+			return nil, atproto.ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (db *SQLCommitsDatabase) AddCommit(ctx context.Context, c *Commit) error {
+
+	q := "INSERT INTO commits (cid, did, version, data, rev, prev, sig, created, lastmodified) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+
+	_, err := db.conn.ExecContext(ctx, q, c.CID, c.DID, c.Version, c.Data, c.Rev, c.Prev, c.Signature, c.Created, c.LastModified)
+
+	if err != nil {
+		return fmt.Errorf("Failed to add commit, %w", err)
+	}
+
+	return nil
+}
+
+func (db *SQLCommitsDatabase) ListCommits(ctx context.Context, opts *ListCommitsOptions) iter.Seq2[*Commit, error] {
+
+	return func(yield func(*Commit, error) bool) {
+
+		q := "SELECT cid, did, version, data, rev, prev, sig, created, lastmodified FROM commits WHERE did = ? ORDER BY rev DESC"
+
+		rows, err := db.conn.QueryContext(ctx, q, opts.DID)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+
+			c, err := db.scanCommit(rows)
+
+			if err != nil {
+
+				if !yield(nil, err) {
+					return
+				}
+
+				continue
+			}
+
+			if !yield(c, nil) {
+				return
+			}
+		}
+
+		err = rows.Close()
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		err = rows.Err()
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+	}
+}
+
+func (db *SQLCommitsDatabase) Close() error {
+	return db.conn.Close()
+}
+
+// sqlRowScanner is the interface shared by `sql.Row` and `sql.Rows` for scanning columns.
+type sqlRowScanner interface {
+	Scan(...any) error
+}
+
+// sqlExecer is the interface shared by `sql.DB` and `sql.Tx` for executing statements.
+type sqlExecer interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+}
+
+func (db *SQLCommitsDatabase) scanCommit(row sqlRowScanner) (*Commit, error) {
+
+	var commit_cid string
+	var did string
+	var version int64
+	var data string
+	var rev string
+	var prev string
+	var sig []byte
+	var created int64
+	var lastmod int64
+
+	err := row.Scan(&commit_cid, &did, &version, &data, &rev, &prev, &sig, &created, &lastmod)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Commit{
+		CID:          commit_cid,
+		DID:          did,
+		Version:      version,
+		Data:         data,
+		Rev:          rev,
+		Prev:         prev,
+		Signature:    sig,
+		Created:      created,
+		LastModified: lastmod,
+	}
+
+	return c, nil
+}
@@ -0,0 +1,269 @@
+package pds
+
+// https://atproto.com/specs/event-stream
+// https://atproto.com/specs/sync#firehose
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/ipfs/go-cid"
+	"github.com/sfomuseum/go-atproto/dagcbor"
+)
+
+const EVENT_COMMIT string = "#commit"
+const EVENT_SYNC string = "#sync"
+const EVENT_IDENTITY string = "#identity"
+const EVENT_ACCOUNT string = "#account"
+const EVENT_INFO string = "#info"
+
+// Event is a repository or account event emitted to (com.atproto.sync.subscribeRepos) subscribers.
+type Event struct {
+	// The sequence number of the event, assigned when the event is sequenced.
+	Seq  int64  `json:"seq"`
+	Type string `json:"type"`
+	DID  string `json:"did"`
+	// The DAG-CBOR encoded body of the event, excluding the sequence number.
+	Body    []byte `json:"body"`
+	Created int64  `json:"created"`
+}
+
+// RepoOp is an individual record operation included in a commit event.
+type RepoOp struct {
+	Action string
+	// The "{COLLECTION}/{RKEY}" path of the record.
+	Path string
+	// The CID of the new record. Undefined for delete operations.
+	CID cid.Cid
+	// The CID of the previous record. Undefined for create operations.
+	Prev cid.Cid
+}
+
+// Frame returns the DAG-CBOR encoded header and body (including its sequence number) for 'e'.
+func (e *Event) Frame() ([]byte, error) {
+
+	var body map[string]any
+
+	err := dagcbor.Unmarshal(e.Body, &body)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode event body, %w", err)
+	}
+
+	body["seq"] = e.Seq
+
+	return NewMessageFrame(e.Type, body)
+}
+
+// NewMessageFrame returns the DAG-CBOR encoded header and body for a message of type 't'.
+func NewMessageFrame(t string, body any) ([]byte, error) {
+
+	header := map[string]any{
+		"op": int64(1),
+		"t":  t,
+	}
+
+	return encodeFrame(header, body)
+}
+
+// NewInfoFrame returns the DAG-CBOR encoded header and body for an informational message named 'name'.
+func NewInfoFrame(name string, message string) ([]byte, error) {
+
+	body := map[string]any{
+		"name": name,
+	}
+
+	if message != "" {
+		body["message"] = message
+	}
+
+	return NewMessageFrame(EVENT_INFO, body)
+}
+
+// NewErrorFrame returns the DAG-CBOR encoded header and body for an error named 'name'.
+func NewErrorFrame(name string, message string) ([]byte, error) {
+
+	header := map[string]any{
+		"op": int64(-1),
+	}
+
+	body := map[string]any{
+		"error": name,
+	}
+
+	if message != "" {
+		body["message"] = message
+	}
+
+	return encodeFrame(header, body)
+}
+
+// NewIdentityEvent returns a new (unsequenced) "#identity" event for 'did'. If 'handle' is not empty
+// it is included in the event.
+func NewIdentityEvent(did string, handle string) (*Event, error) {
+
+	body := map[string]any{
+		"did":  did,
+		"time": syntax.DatetimeNow().String(),
+	}
+
+	if handle != "" {
+		body["handle"] = handle
+	}
+
+	return newEvent(EVENT_IDENTITY, did, body)
+}
+
+// NewAccountEvent returns a new (unsequenced) "#account" event for 'did'. If 'active' is false then
+// 'status' should be one of the status values defined by the com.atproto.sync.subscribeRepos lexicon
+// (for example "deleted" or "deactivated").
+func NewAccountEvent(did string, active bool, status string) (*Event, error) {
+
+	body := map[string]any{
+		"did":    did,
+		"time":   syntax.DatetimeNow().String(),
+		"active": active,
+	}
+
+	if !active && status != "" {
+		body["status"] = status
+	}
+
+	return newEvent(EVENT_ACCOUNT, did, body)
+}
+
+// newCommitEvent returns a new (unsequenced) "#commit" event for 'commit', which follows 'prev' (which may be nil).
+// 'blocks' are the CAR-encoded blocks (commit, new tree nodes and new records) added by the commit.
+func newCommitEvent(commit *Commit, prev *Commit, ops []*RepoOp, blocks []byte) (*Event, error) {
+
+	commit_cid, err := cid.Decode(commit.CID)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid commit CID, %w", err)
+	}
+
+	event_ops := make([]any, len(ops))
+
+	for i, op := range ops {
+
+		var op_cid any
+
+		if op.CID.Defined() {
+			op_cid = op.CID
+		}
+
+		event_op := map[string]any{
+			"action": op.Action,
+			"path":   op.Path,
+			"cid":    op_cid,
+		}
+
+		if op.Prev.Defined() {
+			event_op["prev"] = op.Prev
+		}
+
+		event_ops[i] = event_op
+	}
+
+	var since any
+
+	body := map[string]any{
+		"rebase": false,
+		"tooBig": false,
+		"repo":   commit.DID,
+		"commit": commit_cid,
+		"rev":    commit.Rev,
+		"blocks": blocks,
+		"ops":    event_ops,
+		"blobs":  []any{},
+		"time":   syntax.DatetimeNow().String(),
+	}
+
+	if prev != nil {
+
+		since = prev.Rev
+
+		prev_data, err := cid.Decode(prev.Data)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid data CID for previous commit, %w", err)
+		}
+
+		body["prevData"] = prev_data
+	}
+
+	body["since"] = since
+
+	return newEvent(EVENT_COMMIT, commit.DID, body)
+}
+
+// newSyncEvent returns a new (unsequenced) "#sync" event for 'commit'. This is used to tell subscribers that the
+// repository has been replaced and that its current state can not be derived from previous "#commit" events.
+// 'blocks' is the CAR-encoded commit block.
+func newSyncEvent(commit *Commit, blocks []byte) (*Event, error) {
+
+	body := map[string]any{
+		"did":    commit.DID,
+		"rev":    commit.Rev,
+		"blocks": blocks,
+		"time":   syntax.DatetimeNow().String(),
+	}
+
+	return newEvent(EVENT_SYNC, commit.DID, body)
+}
+
+func AddEvent(ctx context.Context, db EventsDatabase, e *Event) error {
+	return db.AddEvent(ctx, e)
+}
+
+func GetLatestSequence(ctx context.Context, db EventsDatabase) (int64, error) {
+	return db.GetLatestSequence(ctx)
+}
+
+func ListEvents(ctx context.Context, db EventsDatabase, opts *ListEventsOptions) iter.Seq2[*Event, error] {
+	return db.ListEvents(ctx, opts)
+}
+
+func PruneEvents(ctx context.Context, db EventsDatabase, before int64) error {
+	return db.PruneEvents(ctx, before)
+}
+
+func newEvent(t string, did string, body map[string]any) (*Event, error) {
+
+	b, err := dagcbor.Marshal(body)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode event body, %w", err)
+	}
+
+	now := time.Now()
+
+	e := &Event{
+		Type:    t,
+		DID:     did,
+		Body:    b,
+		Created: now.Unix(),
+	}
+
+	return e, nil
+}
+
+func encodeFrame(header map[string]any, body any) ([]byte, error) {
+
+	h, err := dagcbor.Marshal(header)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode frame header, %w", err)
+	}
+
+	b, err := dagcbor.Marshal(body)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode frame body, %w", err)
+	}
+
+	return append(h, b...), nil
+}
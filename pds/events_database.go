@@ -0,0 +1,116 @@
+package pds
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/aaronland/go-roster"
+)
+
+// ListEventsOptions defines the criteria for listing events in an `EventsDatabase`. Events are always
+// listed in ascending order of their sequence numbers.
+type ListEventsOptions struct {
+	// Only events with a sequence number greater than Cursor are listed.
+	Cursor int64
+	// If greater than zero only events with a sequence number less than or equal to Until are listed.
+	Until int64
+	// If greater than zero the maximum number of events to list.
+	Limit int
+}
+
+// EventsDatabase is an ordered (by sequence number) log of repository and account events.
+type EventsDatabase interface {
+	// AddEvent stores an event and assigns it the next sequence number. Sequence numbers are assigned atomically,
+	// are monotonically increasing and are never reused (even if the events they were assigned to are pruned).
+	AddEvent(context.Context, *Event) error
+	// GetLatestSequence returns the sequence number of the most recently added event, or zero if there are none.
+	GetLatestSequence(context.Context) (int64, error)
+	ListEvents(context.Context, *ListEventsOptions) iter.Seq2[*Event, error]
+	// PruneEvents removes events created before a Unix timestamp.
+	PruneEvents(context.Context, int64) error
+	Close() error
+}
+
+var events_database_roster roster.Roster
+
+// EventsDatabaseInitializationFunc is a function defined by individual events_database package and used to create
+// an instance of that events_database
+type EventsDatabaseInitializationFunc func(ctx context.Context, uri string) (EventsDatabase, error)
+
+// RegisterEventsDatabase registers 'scheme' as a key pointing to 'init_func' in an internal lookup table
+// used to create new `EventsDatabase` instances by the `NewEventsDatabase` method.
+func RegisterEventsDatabase(ctx context.Context, scheme string, init_func EventsDatabaseInitializationFunc) error {
+
+	err := ensureEventsDatabaseRoster()
+
+	if err != nil {
+		return err
+	}
+
+	return events_database_roster.Register(ctx, scheme, init_func)
+}
+
+func ensureEventsDatabaseRoster() error {
+
+	if events_database_roster == nil {
+
+		r, err := roster.NewDefaultRoster()
+
+		if err != nil {
+			return err
+		}
+
+		events_database_roster = r
+	}
+
+	return nil
+}
+
+// NewEventsDatabase returns a new `EventsDatabase` instance configured by 'uri'. The value of 'uri' is parsed
+// as a `url.URL` and its scheme is used as the key for a corresponding `EventsDatabaseInitializationFunc`
+// function used to instantiate the new `EventsDatabase`. It is assumed that the scheme (and initialization
+// function) have been registered by the `RegisterEventsDatabase` method.
+func NewEventsDatabase(ctx context.Context, uri string) (EventsDatabase, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := u.Scheme
+
+	i, err := events_database_roster.Driver(ctx, scheme)
+
+	if err != nil {
+		return nil, err
+	}
+
+	init_func := i.(EventsDatabaseInitializationFunc)
+	return init_func(ctx, uri)
+}
+
+// Schemes returns the list of schemes that have been registered.
+func EventsDatabaseSchemes() []string {
+
+	ctx := context.Background()
+	schemes := []string{}
+
+	err := ensureEventsDatabaseRoster()
+
+	if err != nil {
+		return schemes
+	}
+
+	for _, dr := range events_database_roster.Drivers(ctx) {
+		scheme := fmt.Sprintf("%s://", strings.ToLower(dr))
+		schemes = append(schemes, scheme)
+	}
+
+	sort.Strings(schemes)
+	return schemes
+}
@@ -0,0 +1,60 @@
+package pds
+
+import (
+	"context"
+	"iter"
+	"sync/atomic"
+	"time"
+)
+
+// NullEventsDatabase does not store events but does assign them sequence numbers, which start at
+// one each time a new instance is created.
+type NullEventsDatabase struct {
+	EventsDatabase
+	seq *atomic.Int64
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterEventsDatabase(ctx, "null", NewNullEventsDatabase)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func NewNullEventsDatabase(ctx context.Context, uri string) (EventsDatabase, error) {
+
+	db := &NullEventsDatabase{
+		seq: new(atomic.Int64),
+	}
+
+	return db, nil
+}
+
+func (db *NullEventsDatabase) AddEvent(ctx context.Context, e *Event) error {
+
+	if e.Created == 0 {
+		e.Created = time.Now().Unix()
+	}
+
+	e.Seq = db.seq.Add(1)
+	return nil
+}
+
+func (db *NullEventsDatabase) GetLatestSequence(ctx context.Context) (int64, error) {
+	return db.seq.Load(), nil
+}
+
+func (db *NullEventsDatabase) ListEvents(ctx context.Context, opts *ListEventsOptions) iter.Seq2[*Event, error] {
+	return func(yield func(*Event, error) bool) {}
+}
+
+func (db *NullEventsDatabase) PruneEvents(ctx context.Context, before int64) error {
+	return nil
+}
+
+func (db *NullEventsDatabase) Close() error {
+	return nil
+}
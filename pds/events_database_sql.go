@@ -0,0 +1,221 @@
+package pds
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type SQLEventsDatabase struct {
+	EventsDatabase
+	conn   *sql.DB
+	engine string
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterEventsDatabase(ctx, "sql", NewSQLEventsDatabase)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func NewSQLEventsDatabase(ctx context.Context, uri string) (EventsDatabase, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	engine := u.Host
+	dsn := q.Get("dsn")
+
+	if engine == "" {
+		return nil, fmt.Errorf("Missing database engine")
+	}
+
+	if dsn == "" {
+		return nil, fmt.Errorf("Missing DSN string")
+	}
+
+	conn, err := sql.Open(engine, dsn)
+
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create database (%s) because %v", engine, err)
+	}
+
+	switch engine {
+	case "sqlite3":
+		conn.SetMaxOpenConns(1)
+	}
+
+	db := &SQLEventsDatabase{
+		conn:   conn,
+		engine: engine,
+	}
+
+	return db, nil
+}
+
+func (db *SQLEventsDatabase) AddEvent(ctx context.Context, e *Event) error {
+
+	if e.Created == 0 {
+		e.Created = time.Now().Unix()
+	}
+
+	// The sequence number is assigned by the database (the "seq" column is an autoincrementing primary key)
+
+	q := "INSERT INTO events (type, did, body, created) VALUES (?, ?, ?, ?)"
+
+	rsp, err := db.conn.ExecContext(ctx, q, e.Type, e.DID, e.Body, e.Created)
+
+	if err != nil {
+		return fmt.Errorf("Failed to add event, %w", err)
+	}
+
+	seq, err := rsp.LastInsertId()
+
+	if err != nil {
+		return fmt.Errorf("Failed to determine sequence number for event, %w", err)
+	}
+
+	e.Seq = seq
+	return nil
+}
+
+func (db *SQLEventsDatabase) GetLatestSequence(ctx context.Context) (int64, error) {
+
+	var seq sql.NullInt64
+
+	q := "SELECT MAX(seq) FROM events"
+
+	row := db.conn.QueryRowContext(ctx, q)
+	err := row.Scan(&seq)
+
+	if err != nil {
+		return 0, fmt.Errorf("Failed to determine latest sequence number, %w", err)
+	}
+
+	return seq.Int64, nil
+}
+
+func (db *SQLEventsDatabase) ListEvents(ctx context.Context, opts *ListEventsOptions) iter.Seq2[*Event, error] {
+
+	return func(yield func(*Event, error) bool) {
+
+		where := []string{
+			"seq > ?",
+		}
+
+		args := []any{
+			opts.Cursor,
+		}
+
+		if opts.Until > 0 {
+			where = append(where, "seq <= ?")
+			args = append(args, opts.Until)
+		}
+
+		q := fmt.Sprintf("SELECT seq, type, did, body, created FROM events WHERE %s ORDER BY seq ASC", strings.Join(where, " AND "))
+
+		if opts.Limit > 0 {
+			q = fmt.Sprintf("%s LIMIT ?", q)
+			args = append(args, opts.Limit)
+		}
+
+		rows, err := db.conn.QueryContext(ctx, q, args...)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+
+			e, err := db.scanEvent(rows)
+
+			if err != nil {
+
+				if !yield(nil, err) {
+					return
+				}
+
+				continue
+			}
+
+			if !yield(e, nil) {
+				return
+			}
+		}
+
+		err = rows.Close()
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		err = rows.Err()
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+	}
+}
+
+func (db *SQLEventsDatabase) PruneEvents(ctx context.Context, before int64) error {
+
+	// The most recent event is always retained so that its sequence number can still be
+	// reported by GetLatestSequence if every other event has been pruned.
+
+	q := "DELETE FROM events WHERE created < ? AND seq < (SELECT MAX(seq) FROM events)"
+
+	_, err := db.conn.ExecContext(ctx, q, before)
+
+	if err != nil {
+		return fmt.Errorf("Failed to prune events, %w", err)
+	}
+
+	return nil
+}
+
+func (db *SQLEventsDatabase) Close() error {
+	return db.conn.Close()
+}
+
+func (db *SQLEventsDatabase) scanEvent(row sqlRowScanner) (*Event, error) {
+
+	var seq int64
+	var t string
+	var did string
+	var body []byte
+	var created int64
+
+	err := row.Scan(&seq, &t, &did, &body, &created)
+
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Event{
+		Seq:     seq,
+		Type:    t,
+		DID:     did,
+		Body:    body,
+		Created: created,
+	}
+
+	return e, nil
+}
@@ -0,0 +1,348 @@
+package pds
+
+// https://atproto.com/specs/repository#car-file-serialization
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-cid"
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/dagcbor"
+)
+
+// ExportRepoOptions defines the databases used to export a repository.
+type ExportRepoOptions struct {
+	RecordsDatabase RecordsDatabase
+	CommitsDatabase CommitsDatabase
+	// An optional `BlocksDatabase` used to derive the blocks written since a given revision. Required by `ExportRepoSince`.
+	BlocksDatabase BlocksDatabase
+}
+
+// ExportRepo writes the repository for 'did' to 'wr' as a CARv1 file whose root is the latest signed commit for 'did'.
+// The CAR file contains the commit, the nodes of the repository's Merkle Search Tree and all of the repository's records.
+// It returns the commit that was exported.
+func ExportRepo(ctx context.Context, opts *ExportRepoOptions, did string, wr io.Writer) (*Commit, error) {
+
+	commit, blocks, err := repoBlocks(ctx, opts, did)
+
+	if err != nil {
+		return nil, err
+	}
+
+	commit_cid, err := cid.Decode(commit.CID)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid commit CID, %w", err)
+	}
+
+	err = writeBlocks(wr, commit_cid, blocks)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return commit, nil
+}
+
+// ExportRepoSince writes the blocks for the repository for 'did' which were written by commits after the revision 'since'
+// to 'wr' as a CARv1 file whose root is the latest signed commit for 'did'. The latest commit is always included. If the
+// blocks database does not have a complete history of the blocks written after 'since' (for example because the blocks
+// database was added after 'since') then the entire repository is written, as with `ExportRepo`. If 'since' is empty the
+// entire repository is written. Tree nodes and records which are no longer reachable from the latest commit are removed
+// from the blocks database by `ApplyWrites` so they are never included. It returns the commit that was exported.
+func ExportRepoSince(ctx context.Context, opts *ExportRepoOptions, did string, since string, wr io.Writer) (*Commit, error) {
+
+	if since == "" {
+		return ExportRepo(ctx, opts, did, wr)
+	}
+
+	if opts.BlocksDatabase == nil {
+		return nil, fmt.Errorf("Missing blocks database")
+	}
+
+	commit, blocks, complete, err := diffBlocks(ctx, opts, did, since)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !complete {
+		return ExportRepo(ctx, opts, did, wr)
+	}
+
+	commit_cid, err := cid.Decode(commit.CID)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid commit CID, %w", err)
+	}
+
+	err = writeBlocks(wr, commit_cid, blocks)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return commit, nil
+}
+
+// ExportRecord writes a proof that the record identified by 'collection' and 'rkey' is included in the repository
+// for 'did' to 'wr' as a CARv1 file whose root is the latest signed commit for 'did'. The CAR file contains the commit,
+// the nodes of the repository's Merkle Search Tree from its root to the node containing the record and the record itself.
+// It returns the commit the proof was derived from. If the record does not exist `atproto.ErrNotFound` is returned.
+func ExportRecord(ctx context.Context, opts *ExportRepoOptions, did string, collection string, rkey string, wr io.Writer) (*Commit, error) {
+
+	commit, blocks, err := recordBlocks(ctx, opts, did, collection, rkey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	commit_cid, err := cid.Decode(commit.CID)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid commit CID, %w", err)
+	}
+
+	err = writeBlocks(wr, commit_cid, blocks)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return commit, nil
+}
+
+// diffBlocks returns the latest commit for 'did' and the blocks written by commits after the revision 'since', starting
+// with the latest commit itself. If the blocks database does not have blocks for all the commits after 'since' then false
+// is returned.
+func diffBlocks(ctx context.Context, opts *ExportRepoOptions, did string, since string) (*Commit, []*repoBlock, bool, error) {
+
+	unlock := LockRepo(did)
+	defer unlock()
+
+	// Blocks are only tracked from the point the blocks database was added so if the earliest revision that blocks
+	// have been stored for is after 'since' then the diff would be incomplete
+
+	earliest, err := GetEarliestRevForDID(ctx, opts.BlocksDatabase, did)
+
+	if err != nil {
+
+		if err == atproto.ErrNotFound {
+			return nil, nil, false, nil
+		}
+
+		return nil, nil, false, fmt.Errorf("Failed to retrieve earliest revision, %w", err)
+	}
+
+	if earliest > since {
+		return nil, nil, false, nil
+	}
+
+	commit, err := GetLatestCommitForDID(ctx, opts.CommitsDatabase, did)
+
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("Failed to retrieve latest commit, %w", err)
+	}
+
+	commit_body, err := commit.Bytes()
+
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("Failed to encode commit, %w", err)
+	}
+
+	commit_cid, err := cid.Decode(commit.CID)
+
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("Invalid commit CID, %w", err)
+	}
+
+	blocks := []*repoBlock{
+		&repoBlock{cid: commit_cid, data: commit_body},
+	}
+
+	list_opts := &ListBlocksOptions{
+		DID:   did,
+		Since: since,
+	}
+
+	for b, err := range ListBlocks(ctx, opts.BlocksDatabase, list_opts) {
+
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("Failed to list blocks, %w", err)
+		}
+
+		if b.CID == commit.CID {
+			continue
+		}
+
+		block_cid, err := cid.Decode(b.CID)
+
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("Invalid CID for block %s, %w", b.CID, err)
+		}
+
+		blocks = append(blocks, &repoBlock{cid: block_cid, data: b.Data})
+	}
+
+	return commit, blocks, true, nil
+}
+
+// recordBlocks returns the latest commit for 'did' and the blocks (commit, tree nodes and record) needed to prove the
+// inclusion of the record identified by 'collection' and 'rkey' in that commit.
+func recordBlocks(ctx context.Context, opts *ExportRepoOptions, did string, collection string, rkey string) (*Commit, []*repoBlock, error) {
+
+	unlock := LockRepo(did)
+	defer unlock()
+
+	rec, err := GetRecord(ctx, opts.RecordsDatabase, did, collection, rkey)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	commit, err := GetLatestCommitForDID(ctx, opts.CommitsDatabase, did)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to retrieve latest commit, %w", err)
+	}
+
+	commit_body, err := commit.Bytes()
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to encode commit, %w", err)
+	}
+
+	commit_cid, err := cid.Decode(commit.CID)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Invalid commit CID, %w", err)
+	}
+
+	rec_body, err := rec.Bytes()
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to encode record %s, %w", rec.Path(), err)
+	}
+
+	rec_cid, err := dagcbor.CID(rec_body)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to derive CID for record %s, %w", rec.Path(), err)
+	}
+
+	tree, err := DeriveTree(ctx, opts.RecordsDatabase, did)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to derive tree, %w", err)
+	}
+
+	if tree.Root().String() != commit.Data {
+		return nil, nil, fmt.Errorf("Records for %s do not match latest commit (%s)", did, commit.CID)
+	}
+
+	path, exists := tree.Path(rec.Path())
+
+	if !exists {
+		return nil, nil, fmt.Errorf("Record %s is not included in tree", rec.Path())
+	}
+
+	blocks := []*repoBlock{
+		&repoBlock{cid: commit_cid, data: commit_body},
+	}
+
+	for _, node_cid := range path {
+		node_body, _ := tree.Node(node_cid)
+		blocks = append(blocks, &repoBlock{cid: node_cid, data: node_body})
+	}
+
+	blocks = append(blocks, &repoBlock{cid: rec_cid, data: rec_body})
+	return commit, blocks, nil
+}
+
+// repoBlocks returns the latest commit for 'did' and all the blocks (commit, tree nodes and records) for that commit.
+// Blocks are gathered while holding the lock for the repository so that records and the commit are consistent but
+// are written afterwards so that slow readers do not block writes to the repository.
+func repoBlocks(ctx context.Context, opts *ExportRepoOptions, did string) (*Commit, []*repoBlock, error) {
+
+	unlock := LockRepo(did)
+	defer unlock()
+
+	commit, err := GetLatestCommitForDID(ctx, opts.CommitsDatabase, did)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to retrieve latest commit, %w", err)
+	}
+
+	commit_body, err := commit.Bytes()
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to encode commit, %w", err)
+	}
+
+	commit_cid, err := cid.Decode(commit.CID)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Invalid commit CID, %w", err)
+	}
+
+	blocks := []*repoBlock{
+		&repoBlock{cid: commit_cid, data: commit_body},
+	}
+
+	record_blocks := make([]*repoBlock, 0)
+	leaves := make(map[string]cid.Cid)
+
+	// Records with identical values share the same block
+	seen := make(map[cid.Cid]bool)
+
+	list_opts := &ListRecordsOptions{
+		Repo: did,
+	}
+
+	for rec, err := range ListRecords(ctx, opts.RecordsDatabase, list_opts) {
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to list records, %w", err)
+		}
+
+		body, err := rec.Bytes()
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to encode record %s, %w", rec.Path(), err)
+		}
+
+		rec_cid, err := dagcbor.CID(body)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to derive CID for record %s, %w", rec.Path(), err)
+		}
+
+		leaves[rec.Path()] = rec_cid
+
+		if seen[rec_cid] {
+			continue
+		}
+
+		seen[rec_cid] = true
+		record_blocks = append(record_blocks, &repoBlock{cid: rec_cid, data: body})
+	}
+
+	tree, err := newTreeFromLeaves(leaves)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to derive tree, %w", err)
+	}
+
+	if tree.Root().String() != commit.Data {
+		return nil, nil, fmt.Errorf("Records for %s do not match latest commit (%s)", did, commit.CID)
+	}
+
+	for node_cid, node_body := range tree.Nodes() {
+		blocks = append(blocks, &repoBlock{cid: node_cid, data: node_body})
+	}
+
+	blocks = append(blocks, record_blocks...)
+	return commit, blocks, nil
+}
@@ -0,0 +1,338 @@
+package pds
+
+// https://atproto.com/specs/repository#car-file-serialization
+// https://docs.bsky.app/docs/api/com-atproto-repo-import-repo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/identity"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/ipfs/go-cid"
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/car"
+	"github.com/sfomuseum/go-atproto/dagcbor"
+	"github.com/sfomuseum/go-atproto/mst"
+)
+
+// ImportRepoOptions defines the databases used to import a repository.
+type ImportRepoOptions struct {
+	RecordsDatabase RecordsDatabase
+	KeysDatabase    KeysDatabase
+	CommitsDatabase CommitsDatabase
+	// An optional `BlocksDatabase` to store the blocks (commit, tree nodes and records) for the imported repository.
+	BlocksDatabase BlocksDatabase
+	// An optional `BlobsDatabase` to record the blobs referenced by each imported record.
+	BlobsDatabase BlobsDatabase
+	// An optional `Sequencer` to emit a "#sync" event to once the repository has been imported.
+	Sequencer *Sequencer
+	// The `identity.Directory` used to resolve the signing key for the repository's DID. If nil then
+	// `identity.DefaultDirectory` is used.
+	Directory identity.Directory
+}
+
+// ImportRepo reads a CARv1 file, whose root is a signed commit for 'did', from 'r' and adds all of the records in that
+// commit to the (empty) repository for 'did'. The commit's signature is verified using the current signing key published
+// in the DID document for 'did'. Records keep their original record keys and CIDs. Once the records have been written a
+// new commit, signed by the account's "atproto" key, is created and returned. If the CAR file is invalid or the repository
+// already contains records an error wrapping `atproto.ErrInvalidWrite` is returned. ImportRepo acquires the lock for the
+// repository (see `LockRepo`) so callers must not hold it. If a `Sequencer` is configured a "#sync" event is emitted
+// for the new commit.
+func ImportRepo(ctx context.Context, opts *ImportRepoOptions, did string, r io.Reader) (*Commit, error) {
+
+	car_r, err := car.NewReader(r)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w, failed to read CAR file, %w", atproto.ErrInvalidWrite, err)
+	}
+
+	if len(car_r.Roots) != 1 {
+		return nil, fmt.Errorf("%w, CAR file must have exactly one root", atproto.ErrInvalidWrite)
+	}
+
+	root := car_r.Roots[0]
+	blocks := make(map[cid.Cid][]byte)
+
+	for {
+
+		c, data, err := car_r.NextBlock()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w, failed to read block, %w", atproto.ErrInvalidWrite, err)
+		}
+
+		blocks[c] = data
+	}
+
+	commit_body, exists := blocks[root]
+
+	if !exists {
+		return nil, fmt.Errorf("%w, missing commit block %s", atproto.ErrInvalidWrite, root)
+	}
+
+	imported, err := DecodeCommit(commit_body)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w, %w", atproto.ErrInvalidWrite, err)
+	}
+
+	if imported.DID != did {
+		return nil, fmt.Errorf("%w, commit is for %s not %s", atproto.ErrInvalidWrite, imported.DID, did)
+	}
+
+	err = VerifyCommitForDID(ctx, opts.Directory, imported)
+
+	if err != nil {
+
+		if errors.Is(err, atproto.ErrInvalidSignature) {
+			return nil, fmt.Errorf("%w, %w", atproto.ErrInvalidWrite, err)
+		}
+
+		return nil, err
+	}
+
+	data_cid, err := cid.Decode(imported.Data)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w, invalid data CID, %w", atproto.ErrInvalidWrite, err)
+	}
+
+	get_node := func(c cid.Cid) ([]byte, bool) {
+		b, ok := blocks[c]
+		return b, ok
+	}
+
+	entries, err := mst.ReadEntries(data_cid, get_node)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w, failed to read tree, %w", atproto.ErrInvalidWrite, err)
+	}
+
+	// Rebuilding the tree from its entries ensures that the nodes in the CAR file are a valid (canonical) tree
+
+	tree, err := mst.NewTree(entries)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w, invalid tree, %w", atproto.ErrInvalidWrite, err)
+	}
+
+	if !tree.Root().Equals(data_cid) {
+		return nil, fmt.Errorf("%w, tree root %s does not match commit data %s", atproto.ErrInvalidWrite, tree.Root(), data_cid)
+	}
+
+	now := time.Now()
+	ts := now.Unix()
+
+	record_writes := make([]*RecordWrite, len(entries))
+	record_blocks := make([]*repoBlock, 0)
+
+	// Records with identical values share the same block
+	seen := make(map[cid.Cid]bool)
+
+	for i, e := range entries {
+
+		collection, rkey, err := syntax.ParseRepoPath(e.Key)
+
+		if err != nil {
+			return nil, fmt.Errorf("%w, invalid record path %s, %w", atproto.ErrInvalidWrite, e.Key, err)
+		}
+
+		body, exists := blocks[e.Value]
+
+		if !exists {
+			return nil, fmt.Errorf("%w, missing block %s for record %s", atproto.ErrInvalidWrite, e.Value, e.Key)
+		}
+
+		value, err := dagcbor.ToJSON(body)
+
+		if err != nil {
+			return nil, fmt.Errorf("%w, invalid record %s, %w", atproto.ErrInvalidWrite, e.Key, err)
+		}
+
+		rec := &Record{
+			CID:          e.Value.String(),
+			DID:          did,
+			Collection:   collection.String(),
+			RKey:         rkey.String(),
+			Value:        string(value),
+			Created:      ts,
+			LastModified: ts,
+		}
+
+		// Ensure that the record's value, once converted to atproto data model JSON, still produces the original CID
+
+		err = ensureCID(rec)
+
+		if err != nil {
+			return nil, fmt.Errorf("%w, invalid record %s, %w", atproto.ErrInvalidWrite, e.Key, err)
+		}
+
+		record_writes[i] = &RecordWrite{
+			Action: RECORD_WRITE_CREATE,
+			Record: rec,
+		}
+
+		if seen[e.Value] {
+			continue
+		}
+
+		seen[e.Value] = true
+		record_blocks = append(record_blocks, &repoBlock{cid: e.Value, data: body})
+	}
+
+	unlock := LockRepo(did)
+	defer unlock()
+
+	leaves, err := deriveLeaves(ctx, opts.RecordsDatabase, did)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(leaves) > 0 {
+		return nil, fmt.Errorf("%w, repository for %s already has records", atproto.ErrInvalidWrite, did)
+	}
+
+	prev, err := GetLatestCommitForDID(ctx, opts.CommitsDatabase, did)
+
+	if err != nil && err != atproto.ErrNotFound {
+		return nil, fmt.Errorf("Failed to retrieve previous commit, %w", err)
+	}
+
+	// Create (sign) the commit before writing anything so that a missing key, or similar, does
+	// not leave records which are not reflected in a commit
+
+	commit, err := newCommit(ctx, opts.KeysDatabase, did, tree, prev)
+
+	if err != nil {
+		return nil, err
+	}
+
+	commit_cid, err := cid.Decode(commit.CID)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid commit CID, %w", err)
+	}
+
+	new_commit_body, err := commit.Bytes()
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode commit, %w", err)
+	}
+
+	commit_block := &repoBlock{cid: commit_cid, data: new_commit_body}
+
+	if opts.BlocksDatabase != nil {
+
+		repo_blocks := []*repoBlock{
+			commit_block,
+		}
+
+		for node_cid, node_body := range tree.Nodes() {
+			repo_blocks = append(repo_blocks, &repoBlock{cid: node_cid, data: node_body})
+		}
+
+		repo_blocks = append(repo_blocks, record_blocks...)
+
+		err = storeBlocks(ctx, opts.BlocksDatabase, did, commit.Rev, repo_blocks)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to store blocks, %w", err)
+		}
+	}
+
+	if opts.BlobsDatabase != nil {
+
+		err := updateBlobRefs(ctx, opts.BlobsDatabase, did, commit.Rev, record_writes, ts)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to update blob refs, %w", err)
+		}
+	}
+
+	if len(record_writes) > 0 {
+
+		err = opts.RecordsDatabase.ApplyWrites(ctx, record_writes)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to apply writes, %w", err)
+		}
+	}
+
+	err = AddCommit(ctx, opts.CommitsDatabase, commit)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to store commit, %w", err)
+	}
+
+	// The repository is empty so the only blocks which are no longer reachable are the nodes of the (empty) previous tree
+
+	if opts.BlocksDatabase != nil {
+
+		prev_tree, err := newTreeFromLeaves(leaves)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to derive previous tree, %w", err)
+		}
+
+		import_leaves := make(map[string]cid.Cid)
+
+		for _, e := range entries {
+			import_leaves[e.Key] = e.Value
+		}
+
+		stale := staleBlocks(tree, import_leaves, prev_tree, leaves)
+
+		if len(stale) > 0 {
+
+			err := DeleteBlocks(ctx, opts.BlocksDatabase, did, stale)
+
+			if err != nil {
+				slog.Error("Failed to remove stale blocks", "did", did, "commit", commit.CID, "error", err)
+			}
+		}
+	}
+
+	// At this point the repository has been imported so errors emitting events are logged rather than returned
+
+	if opts.Sequencer != nil {
+
+		err := emitSyncEvent(ctx, opts.Sequencer, commit, commit_block)
+
+		if err != nil {
+			slog.Error("Failed to emit sync event", "did", did, "commit", commit.CID, "error", err)
+		}
+	}
+
+	return commit, nil
+}
+
+// emitSyncEvent emits a "#sync" event for 'commit', whose encoded block is 'commit_block', to 'seq'.
+func emitSyncEvent(ctx context.Context, seq *Sequencer, commit *Commit, commit_block *repoBlock) error {
+
+	var buf bytes.Buffer
+
+	err := writeBlocks(&buf, commit_block.cid, []*repoBlock{commit_block})
+
+	if err != nil {
+		return err
+	}
+
+	e, err := newSyncEvent(commit, buf.Bytes())
+
+	if err != nil {
+		return err
+	}
+
+	return seq.Emit(ctx, e)
+}
@@ -2,6 +2,7 @@ package pds
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/did-method-plc/go-didplc"
@@ -15,6 +16,24 @@ type Operation struct {
 	LastModified int64            `json:"lastmodified"`
 }
 
+// GetDIDDocument returns the DID document for 'did' derived from the most recent PLC operation for 'did'.
+func GetDIDDocument(ctx context.Context, db OperationsDatabase, did string) (*didplc.Doc, error) {
+
+	op, err := db.GetLastOperationForDID(ctx, did)
+
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := op.Operation.Doc(did)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive DID document, %w", err)
+	}
+
+	return &doc, nil
+}
+
 func AddOperation(ctx context.Context, db OperationsDatabase, op *Operation) error {
 
 	now := time.Now()
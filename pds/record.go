@@ -3,7 +3,11 @@ package pds
 import (
 	"context"
 	"fmt"
+	"iter"
 	"time"
+
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/dagcbor"
 )
 
 type Record struct {
@@ -20,12 +24,69 @@ func (r *Record) BlockURI() string {
 	return fmt.Sprintf("repo:%s/%s/%s", r.DID, r.Collection, r.RKey)
 }
 
+// URI returns the "at://{DID}/{COLLECTION}/{RKEY}" URI for 'r'.
+func (r *Record) URI() string {
+	return fmt.Sprintf("at://%s/%s/%s", r.DID, r.Collection, r.RKey)
+}
+
+// Path returns the "{COLLECTION}/{RKEY}" path used to key 'r' in a repository's Merkle Search Tree.
+func (r *Record) Path() string {
+	return fmt.Sprintf("%s/%s", r.Collection, r.RKey)
+}
+
+// Bytes returns the canonical DAG-CBOR encoding of the record's (atproto data model JSON) value.
+func (r *Record) Bytes() ([]byte, error) {
+	return dagcbor.FromJSON([]byte(r.Value))
+}
+
+// DeriveCID returns the CIDv1 (dag-cbor, sha-256) of the canonical DAG-CBOR encoding of the record's value.
+func (r *Record) DeriveCID() (string, error) {
+
+	b, err := r.Bytes()
+
+	if err != nil {
+		return "", err
+	}
+
+	c, err := dagcbor.CID(b)
+
+	if err != nil {
+		return "", err
+	}
+
+	return c.String(), nil
+}
+
+// ensureCID derives the CID for 'r'. If 'r' already has a CID which does not match the derived value
+// an `atproto.ErrInvalidCID` error is returned, otherwise the derived value is assigned to 'r'.
+func ensureCID(r *Record) error {
+
+	derived, err := r.DeriveCID()
+
+	if err != nil {
+		return fmt.Errorf("Failed to derive CID for record, %w", err)
+	}
+
+	if r.CID != "" && r.CID != derived {
+		return fmt.Errorf("%w, %s does not match derived value %s", atproto.ErrInvalidCID, r.CID, derived)
+	}
+
+	r.CID = derived
+	return nil
+}
+
 func GetRecord(ctx context.Context, db RecordsDatabase, repo string, collection string, rkey string) (*Record, error) {
 	return db.GetRecord(ctx, repo, collection, rkey)
 }
 
 func AddRecord(ctx context.Context, db RecordsDatabase, record *Record) error {
 
+	err := ensureCID(record)
+
+	if err != nil {
+		return err
+	}
+
 	now := time.Now()
 	ts := now.Unix()
 
@@ -37,13 +98,27 @@ func AddRecord(ctx context.Context, db RecordsDatabase, record *Record) error {
 
 func UpdateRecord(ctx context.Context, db RecordsDatabase, record *Record) error {
 
+	err := ensureCID(record)
+
+	if err != nil {
+		return err
+	}
+
 	now := time.Now()
 	ts := now.Unix()
 
 	record.LastModified = ts
-	return db.AddRecord(ctx, record)
+	return db.UpdateRecord(ctx, record)
 }
 
 func DeleteRecord(ctx context.Context, db RecordsDatabase, record *Record) error {
 	return db.DeleteRecord(ctx, record)
 }
+
+func ListRecords(ctx context.Context, db RecordsDatabase, opts *ListRecordsOptions) iter.Seq2[*Record, error] {
+	return db.ListRecords(ctx, opts)
+}
+
+func ListCollections(ctx context.Context, db RecordsDatabase, repo string) iter.Seq2[string, error] {
+	return db.ListCollections(ctx, repo)
+}
@@ -14,6 +14,34 @@ import (
 type ListRecordsOptions struct {
 	Repo       string
 	Collection string
+	// The maximum number of records to return. If 0 all the matching records are returned.
+	Limit int
+	// Only return records after this value, as derived by the `ListRecordsCursor` method.
+	Cursor string
+	// Return records in ascending, rather than descending, order of record key.
+	Reverse bool
+}
+
+// ListRecordsCursor returns the cursor for 'rec' in a list of records filtered by 'opts'. If 'opts' defines
+// a collection this is the record key, otherwise it is the "{COLLECTION}/{RKEY}" path of the record.
+func ListRecordsCursor(opts *ListRecordsOptions, rec *Record) string {
+
+	if opts.Collection != "" {
+		return rec.RKey
+	}
+
+	return rec.Path()
+}
+
+// parseListRecordsCursor returns the collection and record key encoded in the cursor for 'opts'.
+func parseListRecordsCursor(opts *ListRecordsOptions) (string, string) {
+
+	if opts.Collection != "" {
+		return opts.Collection, opts.Cursor
+	}
+
+	collection, rkey, _ := strings.Cut(opts.Cursor, "/")
+	return collection, rkey
 }
 
 type RecordsDatabase interface {
@@ -22,6 +50,10 @@ type RecordsDatabase interface {
 	UpdateRecord(context.Context, *Record) error
 	DeleteRecord(context.Context, *Record) error
 	ListRecords(context.Context, *ListRecordsOptions) iter.Seq2[*Record, error]
+	// ListCollections returns the distinct (sorted) collections that a repository (DID) has records for.
+	ListCollections(context.Context, string) iter.Seq2[string, error]
+	// ApplyWrites applies a list of create, update and delete operations as a single unit of work.
+	ApplyWrites(context.Context, []*RecordWrite) error
 	Close() error
 }
 
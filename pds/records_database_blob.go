@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"iter"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/aaronland/gocloud/blob/bucket"
@@ -75,6 +77,10 @@ func NewBlobRecordsDatabase(ctx context.Context, uri string) (RecordsDatabase, e
 func (db *BlobRecordsDatabase) GetRecord(ctx context.Context, repo string, collection string, rkey string) (*Record, error) {
 
 	path := db.recordPath(repo, collection, rkey)
+	return db.readRecord(ctx, path)
+}
+
+func (db *BlobRecordsDatabase) readRecord(ctx context.Context, path string) (*Record, error) {
 
 	exists, err := db.bucket.Exists(ctx, path)
 
@@ -110,7 +116,16 @@ func (db *BlobRecordsDatabase) AddRecord(ctx context.Context, record *Record) er
 	return db.writeRecord(ctx, record)
 }
 
+// UpdateRecord replaces the stored record for 'record' preserving the creation time of the existing record.
 func (db *BlobRecordsDatabase) UpdateRecord(ctx context.Context, record *Record) error {
+
+	current, err := db.GetRecord(ctx, record.DID, record.Collection, record.RKey)
+
+	if err != nil {
+		return fmt.Errorf("Failed to retrieve existing record, %w", err)
+	}
+
+	record.Created = current.Created
 	return db.writeRecord(ctx, record)
 }
 
@@ -119,11 +134,190 @@ func (db *BlobRecordsDatabase) DeleteRecord(ctx context.Context, record *Record)
 	return db.bucket.Delete(ctx, path)
 }
 
+// ApplyWrites applies 'writes' in order. Buckets do not support transactions so if a write fails any
+// preceding writes will not be rolled back.
+func (db *BlobRecordsDatabase) ApplyWrites(ctx context.Context, writes []*RecordWrite) error {
+
+	for _, w := range writes {
+
+		var err error
+
+		switch w.Action {
+		case RECORD_WRITE_CREATE:
+			err = db.writeRecord(ctx, w.Record)
+		case RECORD_WRITE_UPDATE:
+			err = db.UpdateRecord(ctx, w.Record)
+		case RECORD_WRITE_DELETE:
+			err = db.DeleteRecord(ctx, w.Record)
+		default:
+			err = fmt.Errorf("Invalid action '%s'", w.Action)
+		}
+
+		if err != nil {
+			return fmt.Errorf("Failed to apply %s write for %s, %w", w.Action, w.Record.Path(), err)
+		}
+	}
+
+	return nil
+}
+
+// ListRecords returns the records matching 'opts'. Buckets can only be listed in ascending order of key so the keys
+// matching 'opts' are retrieved, one page at a time, and then sorted and filtered before the records themselves are
+// read. Ascending listings stop once 'opts.Limit' keys have been found and no later key can sort before them (see
+// `blobRecordKey.bound`) but descending listings need to retrieve all the keys for a repository (or collection).
 func (db *BlobRecordsDatabase) ListRecords(ctx context.Context, opts *ListRecordsOptions) iter.Seq2[*Record, error] {
 
 	return func(yield func(*Record, error) bool) {
-		yield(nil, atproto.ErrNotImplemented)
-		return
+
+		repo_prefix := filepath.Join("records", opts.Repo) + "/"
+		prefix := repo_prefix
+
+		if opts.Collection != "" {
+			prefix = filepath.Join(repo_prefix, opts.Collection) + "/"
+		}
+
+		list_opts := &blob.ListOptions{
+			Prefix: prefix,
+		}
+
+		sortKeys := func(keys []*blobRecordKey) {
+
+			sort.Slice(keys, func(i, j int) bool {
+
+				cmp := keys[i].compare(keys[j].collection, keys[j].rkey)
+
+				if opts.Reverse {
+					return cmp < 0
+				}
+
+				return cmp > 0
+			})
+		}
+
+		cursor_collection, cursor_rkey := parseListRecordsCursor(opts)
+
+		keys := make([]*blobRecordKey, 0)
+		page_token := blob.FirstPageToken
+
+		for len(page_token) > 0 {
+
+			objs, next_token, err := db.bucket.ListPage(ctx, page_token, 1000, list_opts)
+
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, obj := range objs {
+
+				if obj.IsDir || !strings.HasSuffix(obj.Key, ".json") {
+					continue
+				}
+
+				// records/{DID}/{COLLECTION}/{RKEY}.json
+
+				rel_path := strings.TrimPrefix(obj.Key, repo_prefix)
+				collection, fname, ok := strings.Cut(rel_path, "/")
+
+				if !ok {
+					continue
+				}
+
+				k := &blobRecordKey{
+					collection: collection,
+					rkey:       strings.TrimSuffix(fname, ".json"),
+					path:       obj.Key,
+				}
+
+				if opts.Cursor != "" {
+
+					cmp := k.compare(cursor_collection, cursor_rkey)
+
+					if (!opts.Reverse && cmp >= 0) || (opts.Reverse && cmp <= 0) {
+						continue
+					}
+				}
+
+				keys = append(keys, k)
+			}
+
+			page_token = next_token
+
+			if !opts.Reverse || opts.Limit <= 0 || len(keys) < opts.Limit || len(objs) == 0 {
+				continue
+			}
+
+			sortKeys(keys)
+			keys = keys[:opts.Limit]
+
+			bound := keys[opts.Limit-1].bound(repo_prefix, opts.Collection == "")
+
+			if objs[len(objs)-1].Key > bound {
+				break
+			}
+		}
+
+		sortKeys(keys)
+
+		if opts.Limit > 0 && len(keys) > opts.Limit {
+			keys = keys[:opts.Limit]
+		}
+
+		for _, k := range keys {
+
+			rec, err := db.readRecord(ctx, k.path)
+
+			if !yield(rec, err) {
+				return
+			}
+		}
+	}
+}
+
+func (db *BlobRecordsDatabase) ListCollections(ctx context.Context, repo string) iter.Seq2[string, error] {
+
+	return func(yield func(string, error) bool) {
+
+		prefix := filepath.Join("records", repo) + "/"
+
+		list_opts := &blob.ListOptions{
+			Prefix:    prefix,
+			Delimiter: "/",
+		}
+
+		collections := make([]string, 0)
+		page_token := blob.FirstPageToken
+
+		for len(page_token) > 0 {
+
+			objs, next_token, err := db.bucket.ListPage(ctx, page_token, 1000, list_opts)
+
+			if err != nil {
+				yield("", err)
+				return
+			}
+
+			for _, obj := range objs {
+
+				if !obj.IsDir {
+					continue
+				}
+
+				collection := strings.TrimSuffix(strings.TrimPrefix(obj.Key, prefix), "/")
+				collections = append(collections, collection)
+			}
+
+			page_token = next_token
+		}
+
+		sort.Strings(collections)
+
+		for _, collection := range collections {
+
+			if !yield(collection, nil) {
+				return
+			}
+		}
 	}
 }
 
@@ -160,3 +354,43 @@ func (db *BlobRecordsDatabase) recordPath(repo string, collection string, rkey s
 	path = filepath.Join(path, fname)
 	return path
 }
+
+type blobRecordKey struct {
+	collection string
+	rkey       string
+	path       string
+}
+
+// bound returns a bucket key which is greater than or equal to the keys of all the records which sort before 'k'.
+// Bucket keys are ordered byte-wise so because of the "/" separator and ".json" suffix the key for a record whose
+// collection, or record key, is a prefix of the collection, or record key, for 'k' may sort after the key for 'k'.
+// 'repo_prefix' is the "records/{DID}/" prefix for keys and 'all_collections' is true if keys for all collections
+// are being listed.
+func (k *blobRecordKey) bound(repo_prefix string, all_collections bool) string {
+
+	b := k.path
+
+	if all_collections {
+
+		// The keys for all the records in a collection which is a prefix of 'k.collection' sort before "{COLLECTION}0"
+
+		for i := 1; i < len(k.collection); i++ {
+			b = max(b, repo_prefix+k.collection[:i]+"0")
+		}
+	}
+
+	for i := 1; i < len(k.rkey); i++ {
+		b = max(b, repo_prefix+k.collection+"/"+k.rkey[:i]+".json")
+	}
+
+	return b
+}
+
+func (k *blobRecordKey) compare(collection string, rkey string) int {
+
+	if k.collection != collection {
+		return strings.Compare(k.collection, collection)
+	}
+
+	return strings.Compare(k.rkey, rkey)
+}
@@ -0,0 +1,159 @@
+package pds
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	_ "gocloud.dev/blob/memblob"
+)
+
+func TestBlobRecordsDatabaseListRecords(t *testing.T) {
+
+	ctx := context.Background()
+
+	db, err := NewRecordsDatabase(ctx, "mem://")
+
+	if err != nil {
+		t.Fatalf("Failed to create records database, %v", err)
+	}
+
+	defer db.Close()
+
+	// Collections and record keys which are prefixes of each other, followed by characters which sort before
+	// the "/" separator and ".json" suffix used in bucket keys
+
+	collections := []string{
+		"com.example.a",
+		"com.example.a-b",
+		"com.example.a.b",
+		"com.example.ab",
+	}
+
+	rkeys := []string{
+		"x",
+		"x-",
+		"x-y",
+		"x.y",
+		"x.json",
+		"xy",
+		"y",
+	}
+
+	// More than one page (1000 keys) of records in the first collection
+	for i := 0; i < 1200; i++ {
+		rkeys = append(rkeys, fmt.Sprintf("3k%04d", i))
+	}
+
+	paths := make([]string, 0)
+
+	for _, collection := range collections {
+
+		for i, rkey := range rkeys {
+
+			if collection != collections[0] && i > 20 {
+				break
+			}
+
+			rec := &Record{
+				CID:        "bafyreie5cvv4h45feadgeuwhbcutmh6t2ceseocckahdoe6uat64zmz454",
+				DID:        test_did,
+				Collection: collection,
+				RKey:       rkey,
+				Value:      "{}",
+			}
+
+			err := db.AddRecord(ctx, rec)
+
+			if err != nil {
+				t.Fatalf("Failed to add record, %v", err)
+			}
+
+			paths = append(paths, rec.Path())
+		}
+	}
+
+	slices.SortFunc(paths, func(a string, b string) int {
+
+		a_collection, a_rkey, _ := strings.Cut(a, "/")
+		b_collection, b_rkey, _ := strings.Cut(b, "/")
+
+		if a_collection != b_collection {
+			return strings.Compare(a_collection, b_collection)
+		}
+
+		return strings.Compare(a_rkey, b_rkey)
+	})
+
+	tests := []struct {
+		name       string
+		collection string
+		reverse    bool
+		limit      int
+	}{
+		{"all collections ascending", "", true, 50},
+		{"all collections ascending single record pages", "", true, 1},
+		{"collection ascending", collections[0], true, 75},
+		{"collection ascending large pages", collections[0], true, 1100},
+		{"all collections descending", "", false, 300},
+		{"collection descending", collections[1], false, 7},
+	}
+
+	for _, tt := range tests {
+
+		t.Run(tt.name, func(t *testing.T) {
+
+			expected := make([]string, 0)
+
+			for _, p := range paths {
+
+				if tt.collection == "" || strings.HasPrefix(p, tt.collection+"/") {
+					expected = append(expected, p)
+				}
+			}
+
+			if !tt.reverse {
+				slices.Reverse(expected)
+			}
+
+			list_opts := &ListRecordsOptions{
+				Repo:       test_did,
+				Collection: tt.collection,
+				Limit:      tt.limit,
+				Reverse:    tt.reverse,
+			}
+
+			found := make([]string, 0)
+
+			for {
+
+				count := 0
+
+				for rec, err := range db.ListRecords(ctx, list_opts) {
+
+					if err != nil {
+						t.Fatalf("Failed to list records, %v", err)
+					}
+
+					found = append(found, rec.Path())
+					list_opts.Cursor = ListRecordsCursor(list_opts, rec)
+					count += 1
+				}
+
+				if count > tt.limit {
+					t.Fatalf("Expected at most %d records, got %d", tt.limit, count)
+				}
+
+				if count < tt.limit {
+					break
+				}
+			}
+
+			if !slices.Equal(found, expected) {
+				t.Fatalf("Listed records do not match, expected %d records, got %d", len(expected), len(found))
+			}
+		})
+	}
+}
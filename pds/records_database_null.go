@@ -51,6 +51,17 @@ func (db *NullRecordsDatabase) ListRecords(ctx context.Context, opts *ListRecord
 	}
 }
 
+func (db *NullRecordsDatabase) ListCollections(ctx context.Context, repo string) iter.Seq2[string, error] {
+
+	return func(yield func(string, error) bool) {
+		return
+	}
+}
+
+func (db *NullRecordsDatabase) ApplyWrites(ctx context.Context, writes []*RecordWrite) error {
+	return nil
+}
+
 func (db *NullRecordsDatabase) Close() error {
 	return nil
 }
@@ -0,0 +1,335 @@
+package pds
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"net/url"
+
+	"github.com/sfomuseum/go-atproto"
+)
+
+type SQLRecordsDatabase struct {
+	RecordsDatabase
+	conn   *sql.DB
+	engine string
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterRecordsDatabase(ctx, "sql", NewSQLRecordsDatabase)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+func NewSQLRecordsDatabase(ctx context.Context, uri string) (RecordsDatabase, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	engine := u.Host
+	dsn := q.Get("dsn")
+
+	if engine == "" {
+		return nil, fmt.Errorf("Missing database engine")
+	}
+
+	if dsn == "" {
+		return nil, fmt.Errorf("Missing DSN string")
+	}
+
+	conn, err := sql.Open(engine, dsn)
+
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create database (%s) because %v", engine, err)
+	}
+
+	switch engine {
+	case "sqlite3":
+		conn.SetMaxOpenConns(1)
+	}
+
+	db := &SQLRecordsDatabase{
+		conn:   conn,
+		engine: engine,
+	}
+
+	return db, nil
+}
+
+func (db *SQLRecordsDatabase) GetRecord(ctx context.Context, repo string, collection string, rkey string) (*Record, error) {
+
+	q := "SELECT cid, did, collection, rkey, value, created, lastmodified FROM records WHERE did = ? AND collection = ? AND rkey = ?"
+
+	row := db.conn.QueryRowContext(ctx, q, repo, collection, rkey)
+
+	rec, err := db.scanRecord(row)
+
+	if err != nil {
+
+		if err == sql.ErrNoRows {
+			return nil, atproto.ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+func (db *SQLRecordsDatabase) AddRecord(ctx context.Context, record *Record) error {
+	return db.addRecord(ctx, db.conn, record)
+}
+
+func (db *SQLRecordsDatabase) addRecord(ctx context.Context, conn sqlExecer, record *Record) error {
+
+	q := "INSERT INTO records (cid, did, collection, rkey, value, created, lastmodified) VALUES (?, ?, ?, ?, ?, ?, ?)"
+
+	_, err := conn.ExecContext(ctx, q, record.CID, record.DID, record.Collection, record.RKey, record.Value, record.Created, record.LastModified)
+
+	if err != nil {
+		return fmt.Errorf("Failed to add record, %w", err)
+	}
+
+	return nil
+}
+
+func (db *SQLRecordsDatabase) UpdateRecord(ctx context.Context, record *Record) error {
+	return db.updateRecord(ctx, db.conn, record)
+}
+
+func (db *SQLRecordsDatabase) updateRecord(ctx context.Context, conn sqlExecer, record *Record) error {
+
+	q := "UPDATE records SET cid = ?, value = ?, lastmodified = ? WHERE did = ? AND collection = ? AND rkey = ?"
+
+	_, err := conn.ExecContext(ctx, q, record.CID, record.Value, record.LastModified, record.DID, record.Collection, record.RKey)
+
+	if err != nil {
+		return fmt.Errorf("Failed to update record, %w", err)
+	}
+
+	return nil
+}
+
+func (db *SQLRecordsDatabase) DeleteRecord(ctx context.Context, record *Record) error {
+	return db.deleteRecord(ctx, db.conn, record)
+}
+
+func (db *SQLRecordsDatabase) deleteRecord(ctx context.Context, conn sqlExecer, record *Record) error {
+
+	q := "DELETE FROM records WHERE did = ? AND collection = ? AND rkey = ?"
+
+	_, err := conn.ExecContext(ctx, q, record.DID, record.Collection, record.RKey)
+
+	if err != nil {
+		return fmt.Errorf("Failed to delete record, %w", err)
+	}
+
+	return nil
+}
+
+// ApplyWrites applies 'writes' inside a single database transaction. If any write fails the transaction
+// is rolled back and none of the writes are applied.
+func (db *SQLRecordsDatabase) ApplyWrites(ctx context.Context, writes []*RecordWrite) error {
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create transaction, %w", err)
+	}
+
+	for _, w := range writes {
+
+		switch w.Action {
+		case RECORD_WRITE_CREATE:
+			err = db.addRecord(ctx, tx, w.Record)
+		case RECORD_WRITE_UPDATE:
+			err = db.updateRecord(ctx, tx, w.Record)
+		case RECORD_WRITE_DELETE:
+			err = db.deleteRecord(ctx, tx, w.Record)
+		default:
+			err = fmt.Errorf("Invalid action '%s'", w.Action)
+		}
+
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("Failed to apply %s write for %s, %w", w.Action, w.Record.Path(), err)
+		}
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		return fmt.Errorf("Failed to commit transaction, %w", err)
+	}
+
+	return nil
+}
+
+func (db *SQLRecordsDatabase) ListRecords(ctx context.Context, opts *ListRecordsOptions) iter.Seq2[*Record, error] {
+
+	return func(yield func(*Record, error) bool) {
+
+		q := "SELECT cid, did, collection, rkey, value, created, lastmodified FROM records WHERE did = ?"
+		args := []any{
+			opts.Repo,
+		}
+
+		if opts.Collection != "" {
+			q = fmt.Sprintf("%s AND collection = ?", q)
+			args = append(args, opts.Collection)
+		}
+
+		order := "DESC"
+		op := "<"
+
+		if opts.Reverse {
+			order = "ASC"
+			op = ">"
+		}
+
+		if opts.Cursor != "" {
+
+			collection, rkey := parseListRecordsCursor(opts)
+
+			if opts.Collection != "" {
+				q = fmt.Sprintf("%s AND rkey %s ?", q, op)
+				args = append(args, rkey)
+			} else {
+				q = fmt.Sprintf("%s AND (collection %s ? OR (collection = ? AND rkey %s ?))", q, op, op)
+				args = append(args, collection, collection, rkey)
+			}
+		}
+
+		q = fmt.Sprintf("%s ORDER BY collection %s, rkey %s", q, order, order)
+
+		if opts.Limit > 0 {
+			q = fmt.Sprintf("%s LIMIT ?", q)
+			args = append(args, opts.Limit)
+		}
+
+		rows, err := db.conn.QueryContext(ctx, q, args...)
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+
+			rec, err := db.scanRecord(rows)
+
+			if err != nil {
+
+				if !yield(nil, err) {
+					return
+				}
+
+				continue
+			}
+
+			if !yield(rec, nil) {
+				return
+			}
+		}
+
+		err = rows.Close()
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		err = rows.Err()
+
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+	}
+}
+
+func (db *SQLRecordsDatabase) ListCollections(ctx context.Context, repo string) iter.Seq2[string, error] {
+
+	return func(yield func(string, error) bool) {
+
+		q := "SELECT DISTINCT collection FROM records WHERE did = ? ORDER BY collection ASC"
+
+		rows, err := db.conn.QueryContext(ctx, q, repo)
+
+		if err != nil {
+			yield("", err)
+			return
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+
+			var collection string
+			err := rows.Scan(&collection)
+
+			if !yield(collection, err) {
+				return
+			}
+		}
+
+		err = rows.Close()
+
+		if err != nil {
+			yield("", err)
+			return
+		}
+
+		err = rows.Err()
+
+		if err != nil {
+			yield("", err)
+			return
+		}
+	}
+}
+
+func (db *SQLRecordsDatabase) Close() error {
+	return db.conn.Close()
+}
+
+func (db *SQLRecordsDatabase) scanRecord(row sqlRowScanner) (*Record, error) {
+
+	var rec_cid string
+	var did string
+	var collection string
+	var rkey string
+	var value string
+	var created int64
+	var lastmod int64
+
+	err := row.Scan(&rec_cid, &did, &collection, &rkey, &value, &created, &lastmod)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &Record{
+		CID:          rec_cid,
+		DID:          did,
+		Collection:   collection,
+		RKey:         rkey,
+		Value:        value,
+		Created:      created,
+		LastModified: lastmod,
+	}
+
+	return rec, nil
+}
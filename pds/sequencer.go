@@ -0,0 +1,244 @@
+package pds
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"sync"
+)
+
+// The number of events that can be buffered for a subscriber before it is considered too slow and disconnected.
+const SUBSCRIBER_BUFFER_SIZE int = 1000
+
+// The number of stored events read from the events database at a time when backfilling a subscription.
+const BACKFILL_PAGE_SIZE int = 500
+
+// Sequencer stores events in an `EventsDatabase`, which assigns them monotonically increasing sequence
+// numbers, and broadcasts them to subscribers.
+type Sequencer struct {
+	mu          *sync.Mutex
+	events_db   EventsDatabase
+	last_seq    int64
+	subscribers map[*Subscription]bool
+}
+
+// Subscription is a (live) subscription to events emitted by a `Sequencer`.
+type Subscription struct {
+	// Events is the channel that new events are delivered on. It is closed if the subscriber is
+	// too slow to consume events or the subscription is cancelled.
+	Events chan *Event
+	// Outdated is true if the cursor the subscription was created with is older than the oldest stored event.
+	Outdated  bool
+	cursor    int64
+	until     int64
+	sequencer *Sequencer
+	once      *sync.Once
+}
+
+// NewSequencer returns a new `Sequencer` which stores events in 'events_db'. Only events added
+// after the sequencer has been created are broadcast to subscribers.
+func NewSequencer(ctx context.Context, events_db EventsDatabase) (*Sequencer, error) {
+
+	last_seq, err := GetLatestSequence(ctx, events_db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Sequencer{
+		mu:          new(sync.Mutex),
+		events_db:   events_db,
+		last_seq:    last_seq,
+		subscribers: make(map[*Subscription]bool),
+	}
+
+	return s, nil
+}
+
+// Emit stores 'e', which assigns its sequence number, and delivers it (along with any other events that
+// have been added to the sequencer's database since the last broadcast) to all current subscribers.
+func (s *Sequencer) Emit(ctx context.Context, e *Event) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := AddEvent(ctx, s.events_db, e)
+
+	if err != nil {
+		return err
+	}
+
+	return s.broadcast(ctx, e)
+}
+
+// Poll delivers any events that have been added to the sequencer's database, for example by another
+// process, since the last broadcast to all current subscribers.
+func (s *Sequencer) Poll(ctx context.Context) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.broadcast(ctx, nil)
+}
+
+// broadcast delivers all the stored events after the last broadcast sequence number to subscribers. If 'e'
+// is the only new event it is delivered without reading it back from the database.
+func (s *Sequencer) broadcast(ctx context.Context, e *Event) error {
+
+	if e != nil && e.Seq == s.last_seq+1 {
+		s.deliver(e)
+		return nil
+	}
+
+	list_opts := &ListEventsOptions{
+		Cursor: s.last_seq,
+	}
+
+	for stored_e, err := range ListEvents(ctx, s.events_db, list_opts) {
+
+		if err != nil {
+			return fmt.Errorf("Failed to list events, %w", err)
+		}
+
+		s.deliver(stored_e)
+	}
+
+	if e != nil && e.Seq > s.last_seq {
+		s.deliver(e)
+	}
+
+	return nil
+}
+
+func (s *Sequencer) deliver(e *Event) {
+
+	s.last_seq = e.Seq
+
+	for sub, _ := range s.subscribers {
+
+		select {
+		case sub.Events <- e:
+			// pass
+		default:
+			// The subscriber is too slow
+			delete(s.subscribers, sub)
+			sub.once.Do(func() { close(sub.Events) })
+		}
+	}
+}
+
+// LastSeq returns the sequence number of the most recently broadcast event.
+func (s *Sequencer) LastSeq() int64 {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.last_seq
+}
+
+// Subscribe returns a new `Subscription`. If 'cursor' is greater than zero then the stored events with a
+// sequence number greater than 'cursor' can be read using the subscription's `Backfill` method. If 'cursor'
+// is greater than the most recent sequence number an error is returned.
+func (s *Sequencer) Subscribe(ctx context.Context, cursor int64) (*Subscription, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cursor > s.last_seq {
+		return nil, fmt.Errorf("Cursor (%d) is greater than the most recent sequence number (%d)", cursor, s.last_seq)
+	}
+
+	sub := &Subscription{
+		Events:    make(chan *Event, SUBSCRIBER_BUFFER_SIZE),
+		cursor:    cursor,
+		until:     s.last_seq,
+		sequencer: s,
+		once:      new(sync.Once),
+	}
+
+	if cursor > 0 && cursor < s.last_seq {
+
+		// Events after the cursor have been pruned if the oldest stored event is not the next one
+
+		list_opts := &ListEventsOptions{
+			Limit: 1,
+		}
+
+		sub.Outdated = true
+
+		for e, err := range ListEvents(ctx, s.events_db, list_opts) {
+
+			if err != nil {
+				return nil, fmt.Errorf("Failed to retrieve oldest event, %w", err)
+			}
+
+			sub.Outdated = cursor < e.Seq-1
+		}
+	}
+
+	s.subscribers[sub] = true
+	return sub, nil
+}
+
+// Backfill returns the stored events after the cursor 'sub' was created with and before the first
+// event delivered by its `Events` channel. Events are read from the events database in pages of
+// `BACKFILL_PAGE_SIZE` and each page is read in full before any of its events are yielded, so that a slow
+// consumer does not hold a database connection (which for SQLite is the only connection) open, and
+// block other events from being emitted, while it writes events to the network.
+func (sub *Subscription) Backfill(ctx context.Context) iter.Seq2[*Event, error] {
+
+	return func(yield func(*Event, error) bool) {
+
+		if sub.cursor == 0 {
+			return
+		}
+
+		cursor := sub.cursor
+
+		for cursor < sub.until {
+
+			list_opts := &ListEventsOptions{
+				Cursor: cursor,
+				Until:  sub.until,
+				Limit:  BACKFILL_PAGE_SIZE,
+			}
+
+			page := make([]*Event, 0, BACKFILL_PAGE_SIZE)
+
+			for e, err := range ListEvents(ctx, sub.sequencer.events_db, list_opts) {
+
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+
+				page = append(page, e)
+			}
+
+			if len(page) == 0 {
+				return
+			}
+
+			for _, e := range page {
+
+				if !yield(e, nil) {
+					return
+				}
+			}
+
+			cursor = page[len(page)-1].Seq
+		}
+	}
+}
+
+// Cancel removes 'sub' from its sequencer and closes its events channel.
+func (sub *Subscription) Cancel() {
+
+	s := sub.sequencer
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.subscribers, sub)
+	sub.once.Do(func() { close(sub.Events) })
+}
@@ -0,0 +1,108 @@
+package pds
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func testSQLEventsDatabase(t *testing.T) EventsDatabase {
+
+	ctx := context.Background()
+
+	dsn := filepath.Join(t.TempDir(), "events.db")
+
+	db, err := NewEventsDatabase(ctx, "sql://sqlite3?dsn="+dsn)
+
+	if err != nil {
+		t.Fatalf("Failed to create events database, %v", err)
+	}
+
+	testSQLSchema(t, db.(*SQLEventsDatabase).conn, "events.sql")
+	return db
+}
+
+func TestSubscriptionBackfill(t *testing.T) {
+
+	ctx := context.Background()
+
+	events_db := testSQLEventsDatabase(t)
+	defer events_db.Close()
+
+	seq, err := NewSequencer(ctx, events_db)
+
+	if err != nil {
+		t.Fatalf("Failed to create sequencer, %v", err)
+	}
+
+	count := BACKFILL_PAGE_SIZE*2 + 10
+
+	for i := 0; i < count; i++ {
+
+		err := seq.Emit(ctx, &Event{Type: EVENT_IDENTITY, DID: test_did, Body: []byte{0xa0}})
+
+		if err != nil {
+			t.Fatalf("Failed to emit event, %v", err)
+		}
+	}
+
+	cursor := int64(5)
+
+	sub, err := seq.Subscribe(ctx, cursor)
+
+	if err != nil {
+		t.Fatalf("Failed to subscribe, %v", err)
+	}
+
+	defer sub.Cancel()
+
+	expected := cursor + 1
+
+	for e, err := range sub.Backfill(ctx) {
+
+		if err != nil {
+			t.Fatalf("Failed to read backfill, %v", err)
+		}
+
+		if e.Seq != expected {
+			t.Fatalf("Expected event %d, got %d", expected, e.Seq)
+		}
+
+		expected += 1
+
+		// The SQLite events database has a single connection so emitting an event would block if the
+		// backfill was still reading from the database while events are being consumed
+
+		if e.Seq%100 == 0 {
+
+			emit_ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+
+			err := seq.Emit(emit_ctx, &Event{Type: EVENT_IDENTITY, DID: test_did, Body: []byte{0xa0}})
+			cancel()
+
+			if err != nil {
+				t.Fatalf("Failed to emit event during backfill, %v", err)
+			}
+		}
+	}
+
+	if expected != int64(count)+1 {
+		t.Fatalf("Expected backfill to end at %d, ended at %d", count, expected-1)
+	}
+
+	// Events emitted during the backfill are delivered by the subscription's events channel
+
+	select {
+	case e := <-sub.Events:
+
+		if e.Seq != int64(count)+1 {
+			t.Fatalf("Expected event %d, got %d", count+1, e.Seq)
+		}
+
+	default:
+		t.Fatalf("Expected events emitted during backfill to be delivered")
+	}
+}
@@ -0,0 +1,68 @@
+package pds
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/sfomuseum/go-atproto"
+)
+
+var repo_locks = new(sync.Map)
+
+// LockRepo acquires an exclusive (in-process) lock for writing to the repository for 'did'. It returns a function
+// which must be called to release the lock. This is used to ensure that checking swap values, writing records and
+// creating a new commit happen without interruption from other writers.
+func LockRepo(did string) func() {
+
+	v, _ := repo_locks.LoadOrStore(did, new(sync.Mutex))
+	mu := v.(*sync.Mutex)
+
+	mu.Lock()
+	return mu.Unlock
+}
+
+// CheckSwapRecord returns an `atproto.ErrInvalidSwap` error if 'swap' is not empty and does not match the CID
+// of 'rec'. 'rec' may be nil if the record does not exist.
+func CheckSwapRecord(rec *Record, swap string) error {
+
+	if swap == "" {
+		return nil
+	}
+
+	if rec == nil {
+		return fmt.Errorf("%w, record does not exist", atproto.ErrInvalidSwap)
+	}
+
+	if rec.CID != swap {
+		return fmt.Errorf("%w, record %s does not match %s", atproto.ErrInvalidSwap, rec.CID, swap)
+	}
+
+	return nil
+}
+
+// CheckSwapCommit returns an `atproto.ErrInvalidSwap` error if 'swap' is not empty and does not match the CID
+// of the current (latest) commit for the repository for 'did'.
+func CheckSwapCommit(ctx context.Context, db CommitsDatabase, did string, swap string) error {
+
+	if swap == "" {
+		return nil
+	}
+
+	commit, err := GetLatestCommitForDID(ctx, db, did)
+
+	if err != nil {
+
+		if err == atproto.ErrNotFound {
+			return fmt.Errorf("%w, repository has no commits", atproto.ErrInvalidSwap)
+		}
+
+		return fmt.Errorf("Failed to retrieve latest commit, %w", err)
+	}
+
+	if commit.CID != swap {
+		return fmt.Errorf("%w, commit %s does not match %s", atproto.ErrInvalidSwap, commit.CID, swap)
+	}
+
+	return nil
+}
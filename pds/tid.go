@@ -0,0 +1,40 @@
+package pds
+
+import (
+	"math/rand/v2"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+// tid_clock is used to generate monotonically increasing timestamp identifiers (TIDs) for commit revisions
+// and record keys. The clock ID is chosen at random on startup to reduce the chance of collisions between processes.
+var tid_clock = syntax.NewTIDClock(uint(rand.IntN(1024)))
+
+// NewTID returns a new timestamp identifier (TID) which is guaranteed to be greater than any other TID
+// returned by this method (in this process).
+func NewTID() syntax.TID {
+	return tid_clock.Next()
+}
+
+// NewTIDAfter returns a new timestamp identifier (TID) which is guaranteed to be greater than 'prev'.
+func NewTIDAfter(prev string) (syntax.TID, error) {
+
+	tid := NewTID()
+
+	if prev == "" {
+		return tid, nil
+	}
+
+	prev_tid, err := syntax.ParseTID(prev)
+
+	if err != nil {
+		return "", err
+	}
+
+	if tid.Integer() > prev_tid.Integer() {
+		return tid, nil
+	}
+
+	clock := syntax.ClockFromTID(prev_tid)
+	return clock.Next(), nil
+}
@@ -0,0 +1,242 @@
+package pds
+
+// https://docs.bsky.app/docs/api/com-atproto-repo-upload-blob
+// https://atproto.com/specs/data-model#blob-type
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/imaging"
+)
+
+// The default maximum size (in bytes) of an uploaded blob.
+const DEFAULT_MAX_BLOB_SIZE int64 = 50 * 1024 * 1024
+
+// The number of bytes used to sniff the media type of an uploaded blob.
+const SNIFF_LENGTH int = 512
+
+// UploadBlobOptions defines the storage and limits used to upload a blob.
+type UploadBlobOptions struct {
+	BlobsDatabase BlobsDatabase
+	BlobStore     *BlobStore
+	// The maximum size (in bytes) of a blob. If zero or less then `DEFAULT_MAX_BLOB_SIZE` is used.
+	MaxSize int64
+	// The directory used to store uploads while they are being hashed. If empty then `os.TempDir` is used.
+	TempDir string
+	// If not nil JPEG, PNG and WebP images are normalized (metadata removed and optionally downsized) using
+	// these options before they are stored. See `imaging.Normalize` for details.
+	Images *imaging.Options
+}
+
+// UploadBlob reads a blob for 'did' from 'r', storing it in a temporary file while its CIDv1 (raw codec, sha-256) is
+// computed, and then adds it to the blob store and blobs database. The media type of the blob is sniffed from its
+// content and compared with 'media_type' (typically the Content-Type header of a request). If 'media_type' is empty,
+// "*/*" or "application/octet-stream" the sniffed media type is used. If the blob is larger than the maximum size an error
+// wrapping `atproto.ErrBlobTooLarge` is returned. If the media types do not match an error wrapping `atproto.ErrInvalidMediaType`
+// is returned. If 'opts.Images' is defined images are normalized, which may change their media type, and the CID of the
+// normalized image is returned.
+func UploadBlob(ctx context.Context, opts *UploadBlobOptions, did string, media_type string, r io.Reader) (*Blob, error) {
+
+	max_size := opts.MaxSize
+
+	if max_size <= 0 {
+		max_size = DEFAULT_MAX_BLOB_SIZE
+	}
+
+	tmp_wr, err := os.CreateTemp(opts.TempDir, "blob-")
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create temporary file, %w", err)
+	}
+
+	defer func() {
+		tmp_wr.Close()
+		os.Remove(tmp_wr.Name())
+	}()
+
+	h := sha256.New()
+	wr := io.MultiWriter(tmp_wr, h)
+
+	// Read one byte more than the maximum size to detect blobs which are too large without reading all of them
+
+	size, err := io.Copy(wr, io.LimitReader(r, max_size+1))
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read blob, %w", err)
+	}
+
+	if size > max_size {
+		return nil, fmt.Errorf("%w, blob exceeds maximum size of %d bytes", atproto.ErrBlobTooLarge, max_size)
+	}
+
+	mh, err := multihash.Encode(h.Sum(nil), multihash.SHA2_256)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode multihash, %w", err)
+	}
+
+	blob_cid := cid.NewCidV1(cid.Raw, mh)
+
+	head := make([]byte, SNIFF_LENGTH)
+	n, err := tmp_wr.ReadAt(head, 0)
+
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("Failed to read blob header, %w", err)
+	}
+
+	blob_type, err := resolveMediaType(media_type, http.DetectContentType(head[:n]))
+
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Blob{
+		CID:       blob_cid.String(),
+		DID:       did,
+		MediaType: blob_type,
+		Size:      size,
+	}
+
+	_, err = tmp_wr.Seek(0, io.SeekStart)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to rewind temporary file, %w", err)
+	}
+
+	var body io.Reader = tmp_wr
+
+	if opts.Images != nil && imaging.IsSupported(blob_type) {
+
+		im, err := normalizeImage(tmp_wr, blob_type, opts.Images)
+
+		if err != nil {
+			return nil, err
+		}
+
+		im_cid, err := cid.NewPrefixV1(cid.Raw, multihash.SHA2_256).Sum(im.Body)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to derive CID for image, %w", err)
+		}
+
+		b.CID = im_cid.String()
+		b.MediaType = im.MediaType
+		b.Size = int64(len(im.Body))
+		b.Width = im.Width
+		b.Height = im.Height
+
+		body = bytes.NewReader(im.Body)
+	}
+
+	// Blobs are stored while holding the lock for the repository so that they can not be garbage collected (see
+	// `GCBlobs`) between their data being stored and their last modified time being updated
+
+	unlock := LockRepo(did)
+	defer unlock()
+
+	exists, err := opts.BlobStore.Exists(ctx, did, b.CID)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to determine whether blob exists, %w", err)
+	}
+
+	if !exists {
+
+		err = opts.BlobStore.Write(ctx, b, body)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to store blob, %w", err)
+		}
+	}
+
+	err = AddBlob(ctx, opts.BlobsDatabase, b)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to add blob, %w", err)
+	}
+
+	return b, nil
+}
+
+// normalizeImage reads an image with 'media_type' from 'r' and normalizes it using 'opts'. Errors which are not
+// size related are assumed to be the result of invalid (or unsupported) image data.
+func normalizeImage(r io.Reader, media_type string, opts *imaging.Options) (*imaging.Image, error) {
+
+	body, err := io.ReadAll(r)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read image, %w", err)
+	}
+
+	im, err := imaging.Normalize(body, media_type, opts)
+
+	if err != nil {
+
+		if errors.Is(err, atproto.ErrBlobTooLarge) {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("%w, failed to normalize image, %w", atproto.ErrInvalidMediaType, err)
+	}
+
+	return im, nil
+}
+
+// resolveMediaType compares a declared media type with the media type sniffed from a blob's content (see
+// `http.DetectContentType`) and returns the media type to record for the blob, without any parameters.
+func resolveMediaType(declared string, sniffed string) (string, error) {
+
+	sniffed_type, _, err := mime.ParseMediaType(sniffed)
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to parse sniffed media type, %w", err)
+	}
+
+	declared = strings.TrimSpace(declared)
+
+	if declared == "" {
+		return sniffed_type, nil
+	}
+
+	declared_type, _, err := mime.ParseMediaType(declared)
+
+	if err != nil {
+		return "", fmt.Errorf("%w, %w", atproto.ErrInvalidMediaType, err)
+	}
+
+	switch declared_type {
+	case "*/*", "application/octet-stream":
+		return sniffed_type, nil
+	}
+
+	if declared_type == sniffed_type {
+		return declared_type, nil
+	}
+
+	// The sniffer only recognizes a limited number of formats so unrecognized binary content, or
+	// text content for structured text formats (for example application/json), is accepted as declared
+
+	switch sniffed_type {
+	case "application/octet-stream":
+		return declared_type, nil
+	case "text/plain":
+
+		if strings.HasPrefix(declared_type, "text/") || strings.HasPrefix(declared_type, "application/") {
+			return declared_type, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w, declared %s but content is %s", atproto.ErrInvalidMediaType, declared_type, sniffed_type)
+}
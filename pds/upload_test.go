@@ -0,0 +1,53 @@
+package pds
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sfomuseum/go-atproto"
+)
+
+func TestResolveMediaType(t *testing.T) {
+
+	tests := []struct {
+		declared string
+		sniffed  string
+		expected string
+		ok       bool
+	}{
+		{"", "image/png", "image/png", true},
+		{"*/*", "image/png", "image/png", true},
+		{"application/octet-stream", "image/jpeg", "image/jpeg", true},
+		{"image/png", "image/png", "image/png", true},
+		{" image/png ", "image/png", "image/png", true},
+		{"text/html; charset=utf-8", "text/html; charset=utf-8", "text/html", true},
+		{"video/mp4", "application/octet-stream", "video/mp4", true},
+		{"application/json", "text/plain; charset=utf-8", "application/json", true},
+		{"text/markdown", "text/plain; charset=utf-8", "text/markdown", true},
+		{"image/png", "image/jpeg", "", false},
+		{"image/png", "text/plain; charset=utf-8", "", false},
+		{"not a media type;", "image/png", "", false},
+	}
+
+	for _, tt := range tests {
+
+		media_type, err := resolveMediaType(tt.declared, tt.sniffed)
+
+		if !tt.ok {
+
+			if !errors.Is(err, atproto.ErrInvalidMediaType) {
+				t.Fatalf("Expected invalid media type error for '%s' (%s), got %v", tt.declared, tt.sniffed, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("Unexpected error for '%s' (%s), %v", tt.declared, tt.sniffed, err)
+		}
+
+		if media_type != tt.expected {
+			t.Fatalf("Expected %s for '%s' (%s), got %s", tt.expected, tt.declared, tt.sniffed, media_type)
+		}
+	}
+}
@@ -0,0 +1,459 @@
+package pds
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/ipfs/go-cid"
+	"github.com/sfomuseum/go-atproto"
+	"github.com/sfomuseum/go-atproto/mst"
+)
+
+const RECORD_WRITE_CREATE string = "create"
+const RECORD_WRITE_UPDATE string = "update"
+const RECORD_WRITE_DELETE string = "delete"
+
+// RECORD_WRITE_UPSERT is a `Write` action which `ApplyWrites` resolves to a create or an update depending on
+// whether the record exists. It is not a valid `RecordWrite` action.
+const RECORD_WRITE_UPSERT string = "upsert"
+
+// The maximum number of writes that can be applied, as a single commit, by `ApplyWrites`.
+const MAX_WRITES int = 200
+
+// RecordWrite is a create, update or delete operation for a record passed to `RecordsDatabase.ApplyWrites`.
+type RecordWrite struct {
+	Action string
+	Record *Record
+}
+
+// Write is a create, update, upsert or delete operation to apply to a repository using `ApplyWrites`.
+type Write struct {
+	Action     string
+	Collection string
+	// The record key. If empty for create operations a new TID will be assigned.
+	RKey string
+	// The (atproto data model JSON) value of the record. Ignored for delete operations.
+	Value string
+	// The optional CID that the current record must match for update, upsert and delete operations.
+	SwapRecord string
+}
+
+// WriteResult is the outcome of an individual write applied by `ApplyWrites`.
+type WriteResult struct {
+	Action string
+	URI    string
+	// The CID of the record. Empty for delete operations.
+	CID string
+}
+
+// ApplyWritesOptions defines the databases used to apply writes and create a new commit.
+type ApplyWritesOptions struct {
+	RecordsDatabase RecordsDatabase
+	KeysDatabase    KeysDatabase
+	CommitsDatabase CommitsDatabase
+	// The optional CID that the current commit for the repository must match.
+	SwapCommit string
+	// An optional `BlocksDatabase` to store the blocks (commit, new tree nodes and records) for the new commit.
+	BlocksDatabase BlocksDatabase
+	// An optional `Sequencer` to emit a "#commit" event to once the writes have been applied.
+	Sequencer *Sequencer
+	// An optional `BlobsDatabase` to record the blobs referenced by each record.
+	BlobsDatabase BlobsDatabase
+}
+
+// ApplyWrites applies 'writes' to the repository for 'did' as a single unit of work and creates a single new commit
+// for the result. If any write is invalid (for example creating a record which already exists) an error wrapping
+// `atproto.ErrInvalidWrite` (or `atproto.ErrInvalidSwap`) is returned and no changes are made. ApplyWrites acquires
+// the lock for the repository (see `LockRepo`) so callers must not hold it.
+//
+// The Merkle Search Tree for the new commit is not updated incrementally. Instead every record for 'did' is read
+// from the records database and the tree (and the tree for the previous commit, to determine which nodes are new)
+// is rebuilt from scratch, so the cost of each call is O(N) in the number of records in the repository, regardless
+// of the number of writes.
+func ApplyWrites(ctx context.Context, opts *ApplyWritesOptions, did string, writes []*Write) ([]*WriteResult, *Commit, error) {
+
+	if len(writes) == 0 {
+		return nil, nil, fmt.Errorf("%w, no writes", atproto.ErrInvalidWrite)
+	}
+
+	if len(writes) > MAX_WRITES {
+		return nil, nil, fmt.Errorf("%w, too many writes (%d > %d)", atproto.ErrInvalidWrite, len(writes), MAX_WRITES)
+	}
+
+	unlock := LockRepo(did)
+	defer unlock()
+
+	err := CheckSwapCommit(ctx, opts.CommitsDatabase, did, opts.SwapCommit)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The current state of the repository, updated as each write is applied, which is used to check
+	// for the existence of records and to derive the new tree
+
+	leaves, err := deriveLeaves(ctx, opts.RecordsDatabase, did)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prev_leaves := maps.Clone(leaves)
+
+	now := time.Now()
+	ts := now.Unix()
+
+	record_writes := make([]*RecordWrite, len(writes))
+	results := make([]*WriteResult, len(writes))
+	ops := make([]*RepoOp, len(writes))
+
+	for i, w := range writes {
+
+		_, err := syntax.ParseNSID(w.Collection)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w, invalid collection at offset %d, %w", atproto.ErrInvalidWrite, i, err)
+		}
+
+		rkey := w.RKey
+
+		if rkey == "" && w.Action == RECORD_WRITE_CREATE {
+
+			for {
+				rkey = NewTID().String()
+				_, exists := leaves[fmt.Sprintf("%s/%s", w.Collection, rkey)]
+
+				if !exists {
+					break
+				}
+			}
+		}
+
+		_, err = syntax.ParseRecordKey(rkey)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w, invalid record key at offset %d, %w", atproto.ErrInvalidWrite, i, err)
+		}
+
+		rec := &Record{
+			DID:          did,
+			Collection:   w.Collection,
+			RKey:         rkey,
+			Value:        w.Value,
+			LastModified: ts,
+		}
+
+		path := rec.Path()
+		current, exists := leaves[path]
+
+		// Upserts are resolved here, rather than by the caller, so that the existence of the record is
+		// determined while the lock for the repository is held
+
+		action := w.Action
+
+		if action == RECORD_WRITE_UPSERT {
+
+			action = RECORD_WRITE_CREATE
+
+			if exists {
+				action = RECORD_WRITE_UPDATE
+			}
+		}
+
+		// The creation time of existing records is preserved by the records database for updates
+
+		if action == RECORD_WRITE_CREATE {
+			rec.Created = ts
+		}
+
+		switch action {
+		case RECORD_WRITE_CREATE, RECORD_WRITE_UPDATE:
+
+			if action == RECORD_WRITE_CREATE && exists {
+				return nil, nil, fmt.Errorf("%w, record %s already exists", atproto.ErrInvalidWrite, path)
+			}
+
+			if action == RECORD_WRITE_UPDATE && !exists {
+				return nil, nil, fmt.Errorf("%w, record %s does not exist", atproto.ErrInvalidWrite, path)
+			}
+
+			err := ensureCID(rec)
+
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w, invalid record %s, %w", atproto.ErrInvalidWrite, path, err)
+			}
+
+			rec_cid, err := cid.Decode(rec.CID)
+
+			if err != nil {
+				return nil, nil, fmt.Errorf("Failed to decode CID for %s, %w", path, err)
+			}
+
+			leaves[path] = rec_cid
+
+		case RECORD_WRITE_DELETE:
+
+			if !exists {
+				return nil, nil, fmt.Errorf("%w, record %s does not exist", atproto.ErrInvalidWrite, path)
+			}
+
+			delete(leaves, path)
+
+		default:
+			return nil, nil, fmt.Errorf("%w, invalid action '%s' at offset %d", atproto.ErrInvalidWrite, w.Action, i)
+		}
+
+		if w.SwapRecord != "" && w.Action != RECORD_WRITE_CREATE {
+
+			if !exists {
+				return nil, nil, fmt.Errorf("%w, record %s does not exist", atproto.ErrInvalidSwap, path)
+			}
+
+			if current.String() != w.SwapRecord {
+				return nil, nil, fmt.Errorf("%w, record %s (%s) does not match %s", atproto.ErrInvalidSwap, path, current, w.SwapRecord)
+			}
+		}
+
+		op := &RepoOp{
+			Action: action,
+			Path:   path,
+		}
+
+		if action != RECORD_WRITE_DELETE {
+			op.CID = leaves[path]
+		}
+
+		if exists {
+			op.Prev = current
+		}
+
+		ops[i] = op
+
+		record_writes[i] = &RecordWrite{
+			Action: action,
+			Record: rec,
+		}
+
+		results[i] = &WriteResult{
+			Action: action,
+			URI:    rec.URI(),
+			CID:    rec.CID,
+		}
+	}
+
+	tree, err := newTreeFromLeaves(leaves)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to derive tree, %w", err)
+	}
+
+	prev, err := GetLatestCommitForDID(ctx, opts.CommitsDatabase, did)
+
+	if err != nil && err != atproto.ErrNotFound {
+		return nil, nil, fmt.Errorf("Failed to retrieve previous commit, %w", err)
+	}
+
+	// Create (sign) the commit before writing anything so that a missing key, or similar, does
+	// not leave records which are not reflected in a commit
+
+	commit, err := newCommit(ctx, opts.KeysDatabase, did, tree, prev)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prev_tree, err := newTreeFromLeaves(prev_leaves)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to derive previous tree, %w", err)
+	}
+
+	blocks, err := commitBlocks(commit, tree, prev_tree, record_writes)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Blocks are content-addressed so storing them before the records and the commit means that a subsequent
+	// failure only leaves unreferenced blocks rather than a commit whose blocks are missing
+
+	if opts.BlocksDatabase != nil {
+
+		err := storeBlocks(ctx, opts.BlocksDatabase, did, commit.Rev, blocks)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to store blocks, %w", err)
+		}
+	}
+
+	// Blob references are updated before the records for the same reason; a subsequent failure leaves (at worst)
+	// references which keep blobs from being garbage collected rather than records whose blobs are missing
+
+	if opts.BlobsDatabase != nil {
+
+		err := updateBlobRefs(ctx, opts.BlobsDatabase, did, commit.Rev, record_writes, ts)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to update blob refs, %w", err)
+		}
+	}
+
+	err = opts.RecordsDatabase.ApplyWrites(ctx, record_writes)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to apply writes, %w", err)
+	}
+
+	err = AddCommit(ctx, opts.CommitsDatabase, commit)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to store commit, %w", err)
+	}
+
+	// Once the commit has been stored the tree nodes and records which are no longer reachable from it are removed.
+	// Failing to remove them only leaves unreferenced blocks so errors are logged rather than returned
+
+	if opts.BlocksDatabase != nil {
+
+		stale := staleBlocks(tree, leaves, prev_tree, prev_leaves)
+
+		if len(stale) > 0 {
+
+			err := DeleteBlocks(ctx, opts.BlocksDatabase, did, stale)
+
+			if err != nil {
+				slog.Error("Failed to remove stale blocks", "did", did, "commit", commit.CID, "error", err)
+			}
+		}
+	}
+
+	// At this point the writes have been applied so errors emitting events are logged rather than returned
+
+	if opts.Sequencer != nil {
+
+		err := emitCommitEvent(ctx, opts.Sequencer, commit, prev, blocks, ops)
+
+		if err != nil {
+			slog.Error("Failed to emit commit event", "did", did, "commit", commit.CID, "error", err)
+		}
+	}
+
+	return results, commit, nil
+}
+
+// commitBlocks returns the blocks added by 'commit': the commit itself, the nodes in 'tree' which are not present
+// in 'prev_tree' and the records created or updated by 'writes'.
+func commitBlocks(commit *Commit, tree *mst.Tree, prev_tree *mst.Tree, writes []*RecordWrite) ([]*repoBlock, error) {
+
+	commit_cid, err := cid.Decode(commit.CID)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid commit CID, %w", err)
+	}
+
+	commit_body, err := commit.Bytes()
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode commit, %w", err)
+	}
+
+	blocks := []*repoBlock{
+		&repoBlock{cid: commit_cid, data: commit_body},
+	}
+
+	for node_cid, node_body := range tree.Nodes() {
+
+		_, exists := prev_tree.Node(node_cid)
+
+		if !exists {
+			blocks = append(blocks, &repoBlock{cid: node_cid, data: node_body})
+		}
+	}
+
+	for _, w := range writes {
+
+		if w.Action == RECORD_WRITE_DELETE {
+			continue
+		}
+
+		rec_cid, err := cid.Decode(w.Record.CID)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CID for %s, %w", w.Record.Path(), err)
+		}
+
+		body, err := w.Record.Bytes()
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to encode record %s, %w", w.Record.Path(), err)
+		}
+
+		blocks = append(blocks, &repoBlock{cid: rec_cid, data: body})
+	}
+
+	return blocks, nil
+}
+
+// staleBlocks returns the CIDs of the nodes in 'prev_tree' and the records in 'prev_leaves' which are not present in
+// 'tree' or 'leaves'. Commit blocks are never considered stale so that the revision history of the repository, used to
+// derive diffs, is preserved.
+func staleBlocks(tree *mst.Tree, leaves map[string]cid.Cid, prev_tree *mst.Tree, prev_leaves map[string]cid.Cid) []string {
+
+	// The same record (CID) may be stored at more than one path so reachability is determined by CID rather than path
+
+	reachable := make(map[cid.Cid]bool)
+
+	for node_cid := range tree.Nodes() {
+		reachable[node_cid] = true
+	}
+
+	for _, rec_cid := range leaves {
+		reachable[rec_cid] = true
+	}
+
+	seen := make(map[cid.Cid]bool)
+	stale := make([]string, 0)
+
+	for node_cid := range prev_tree.Nodes() {
+
+		if !reachable[node_cid] && !seen[node_cid] {
+			stale = append(stale, node_cid.String())
+			seen[node_cid] = true
+		}
+	}
+
+	for _, rec_cid := range prev_leaves {
+
+		if !reachable[rec_cid] && !seen[rec_cid] {
+			stale = append(stale, rec_cid.String())
+			seen[rec_cid] = true
+		}
+	}
+
+	return stale
+}
+
+// emitCommitEvent emits a "#commit" event for 'commit', whose first block is the commit itself, to 'seq'.
+func emitCommitEvent(ctx context.Context, seq *Sequencer, commit *Commit, prev *Commit, blocks []*repoBlock, ops []*RepoOp) error {
+
+	var buf bytes.Buffer
+
+	err := writeBlocks(&buf, blocks[0].cid, blocks)
+
+	if err != nil {
+		return err
+	}
+
+	e, err := newCommitEvent(commit, prev, ops, buf.Bytes())
+
+	if err != nil {
+		return err
+	}
+
+	return seq.Emit(ctx, e)
+}
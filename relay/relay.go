@@ -0,0 +1,206 @@
+package relay
+
+// https://atproto.com/specs/sync#relays
+// https://docs.bsky.app/docs/api/com-atproto-sync-request-crawl
+// https://docs.bsky.app/docs/api/com-atproto-sync-notify-of-update
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const REQUEST_CRAWL_PATH string = "/xrpc/com.atproto.sync.requestCrawl"
+const NOTIFY_OF_UPDATE_PATH string = "/xrpc/com.atproto.sync.notifyOfUpdate"
+
+// The default number of times a request will be attempted before giving up.
+const DEFAULT_MAX_ATTEMPTS int = 5
+
+// The default amount of time to wait before retrying a failed request. This is doubled after each attempt.
+const DEFAULT_BACKOFF time.Duration = 2 * time.Second
+
+const USER_AGENT string = "sfomuseum/go-atproto"
+
+// Client is a client for calling XRPC methods on one or more relays.
+type Client struct {
+	HTTPClient *http.Client
+	// The maximum number of times a request will be attempted.
+	MaxAttempts int
+	// The amount of time to wait before retrying a failed request. This is doubled after each attempt.
+	Backoff time.Duration
+}
+
+// hostnameRequest is the request body for both the requestCrawl and notifyOfUpdate methods.
+type hostnameRequest struct {
+	Hostname string `json:"hostname"`
+}
+
+// DefaultClient returns a new `Client` instance with default retry settings.
+func DefaultClient() *Client {
+
+	return &Client{
+		HTTPClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		MaxAttempts: DEFAULT_MAX_ATTEMPTS,
+		Backoff:     DEFAULT_BACKOFF,
+	}
+}
+
+// RequestCrawlAll asks each relay in 'relays' to crawl the PDS at 'hostname'. Relays are notified in sequence
+// and a failure to notify one relay does not prevent the others from being notified. Any errors are returned
+// as a single (joined) error.
+func (cl *Client) RequestCrawlAll(ctx context.Context, relays []string, hostname string) error {
+	return cl.callAll(ctx, relays, REQUEST_CRAWL_PATH, hostname)
+}
+
+// RequestCrawl asks the relay at 'relay_uri' to crawl the PDS at 'hostname'. 'relay_uri' may be a URL or a hostname,
+// in which case "https" is assumed. Requests which fail because of network errors, rate limits or server errors
+// are retried (with an exponential backoff) up to `Client.MaxAttempts` times.
+func (cl *Client) RequestCrawl(ctx context.Context, relay_uri string, hostname string) error {
+	return cl.call(ctx, relay_uri, REQUEST_CRAWL_PATH, hostname)
+}
+
+// NotifyOfUpdateAll tells each relay in 'relays' that the repositories hosted by the PDS at 'hostname' have been
+// updated. As with `RequestCrawlAll` a failure to notify one relay does not prevent the others from being notified.
+func (cl *Client) NotifyOfUpdateAll(ctx context.Context, relays []string, hostname string) error {
+	return cl.callAll(ctx, relays, NOTIFY_OF_UPDATE_PATH, hostname)
+}
+
+// NotifyOfUpdate tells the relay at 'relay_uri' that the repositories hosted by the PDS at 'hostname' have been
+// updated. Requests are retried in the same way as `RequestCrawl`.
+func (cl *Client) NotifyOfUpdate(ctx context.Context, relay_uri string, hostname string) error {
+	return cl.call(ctx, relay_uri, NOTIFY_OF_UPDATE_PATH, hostname)
+}
+
+func (cl *Client) callAll(ctx context.Context, relays []string, path string, hostname string) error {
+
+	errs := make([]error, 0)
+
+	for _, r := range relays {
+
+		err := cl.call(ctx, r, path, hostname)
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Failed to call %s on %s, %w", path, r, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// call performs a POST request, with a JSON-encoded `hostnameRequest` body, to 'path' on the relay at 'relay_uri'
+// retrying failed requests (with an exponential backoff) up to `Client.MaxAttempts` times.
+func (cl *Client) call(ctx context.Context, relay_uri string, path string, hostname string) error {
+
+	endpoint, err := relayURL(relay_uri, path)
+
+	if err != nil {
+		return err
+	}
+
+	host_req := hostnameRequest{
+		Hostname: hostname,
+	}
+
+	body, err := json.Marshal(host_req)
+
+	if err != nil {
+		return fmt.Errorf("Failed to encode request, %w", err)
+	}
+
+	logger := slog.Default()
+	logger = logger.With("relay", endpoint)
+	logger = logger.With("hostname", hostname)
+
+	max_attempts := max(cl.MaxAttempts, 1)
+	backoff := cl.Backoff
+
+	for attempt := 1; ; attempt++ {
+
+		retry, err := cl.post(ctx, endpoint, body)
+
+		if err == nil {
+			logger.Info("Notified relay", "attempts", attempt)
+			return nil
+		}
+
+		if !retry || attempt >= max_attempts {
+			logger.Error("Failed to notify relay", "attempts", attempt, "error", err)
+			return err
+		}
+
+		logger.Warn("Failed to notify relay, retrying", "attempt", attempt, "backoff", backoff, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+			// pass
+		}
+
+		backoff = backoff * 2
+	}
+}
+
+// post performs a single request and returns whether or not a failed request should be retried.
+func (cl *Client) post(ctx context.Context, endpoint string, body []byte) (bool, error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+
+	if err != nil {
+		return false, fmt.Errorf("Failed to create request, %w", err)
+	}
+
+	req.Header.Set("Content-type", "application/json")
+	req.Header.Set("User-Agent", USER_AGENT)
+
+	rsp, err := cl.HTTPClient.Do(req)
+
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("Failed to execute request, %w", err)
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode == http.StatusOK {
+		return false, nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(rsp.Body, 1024))
+	err = fmt.Errorf("Relay returned %d %s (%s)", rsp.StatusCode, http.StatusText(rsp.StatusCode), strings.TrimSpace(string(msg)))
+
+	retry := rsp.StatusCode == http.StatusTooManyRequests || rsp.StatusCode >= 500
+	return retry, err
+}
+
+// relayURL returns the URL for 'path' on the relay at 'relay_uri'.
+func relayURL(relay_uri string, path string) (string, error) {
+
+	if !strings.Contains(relay_uri, "://") {
+		relay_uri = fmt.Sprintf("https://%s", relay_uri)
+	}
+
+	u, err := url.Parse(relay_uri)
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to parse relay URI, %w", err)
+	}
+
+	if u.Host == "" {
+		return "", fmt.Errorf("Invalid relay URI, missing host")
+	}
+
+	u.Path = path
+	u.RawQuery = ""
+
+	return u.String(), nil
+}
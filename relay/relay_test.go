@@ -0,0 +1,261 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testRelay is a stand-in for a relay which responds to each request with the next status code in 'statuses'
+// (repeating the last one) and records the requests it receives.
+type testRelay struct {
+	mu       *sync.Mutex
+	statuses []int
+	paths    []string
+	bodies   []hostnameRequest
+	times    []time.Time
+}
+
+func newTestRelay(statuses ...int) *testRelay {
+
+	return &testRelay{
+		mu:       new(sync.Mutex),
+		statuses: statuses,
+	}
+}
+
+func (r *testRelay) ServeHTTP(rsp http.ResponseWriter, req *http.Request) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var body hostnameRequest
+	json.NewDecoder(req.Body).Decode(&body)
+
+	r.paths = append(r.paths, req.URL.Path)
+	r.bodies = append(r.bodies, body)
+	r.times = append(r.times, time.Now())
+
+	idx := min(len(r.paths)-1, len(r.statuses)-1)
+	rsp.WriteHeader(r.statuses[idx])
+}
+
+func (r *testRelay) attempts() int {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.paths)
+}
+
+func testClient(backoff time.Duration) *Client {
+
+	return &Client{
+		HTTPClient:  &http.Client{Timeout: 5 * time.Second},
+		MaxAttempts: 4,
+		Backoff:     backoff,
+	}
+}
+
+func TestRequestCrawlRetries(t *testing.T) {
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		statuses []int
+		attempts int
+		ok       bool
+	}{
+		{"success", []int{200}, 1, true},
+		{"server error then success", []int{500, 502, 200}, 3, true},
+		{"rate limited then success", []int{429, 200}, 2, true},
+		{"client error is not retried", []int{400}, 1, false},
+		{"not found is not retried", []int{404, 200}, 1, false},
+		{"max attempts", []int{503}, 4, false},
+	}
+
+	for _, tt := range tests {
+
+		t.Run(tt.name, func(t *testing.T) {
+
+			r := newTestRelay(tt.statuses...)
+
+			s := httptest.NewServer(r)
+			defer s.Close()
+
+			cl := testClient(time.Millisecond)
+			err := cl.RequestCrawl(ctx, s.URL, "pds.example.com")
+
+			if tt.ok && err != nil {
+				t.Fatalf("Unexpected error, %v", err)
+			}
+
+			if !tt.ok && err == nil {
+				t.Fatalf("Expected an error")
+			}
+
+			if r.attempts() != tt.attempts {
+				t.Fatalf("Expected %d attempts, got %d", tt.attempts, r.attempts())
+			}
+
+			for i, p := range r.paths {
+
+				if p != REQUEST_CRAWL_PATH {
+					t.Fatalf("Unexpected path for attempt %d, %s", i, p)
+				}
+
+				if r.bodies[i].Hostname != "pds.example.com" {
+					t.Fatalf("Unexpected hostname for attempt %d, %s", i, r.bodies[i].Hostname)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+
+	ctx := context.Background()
+
+	r := newTestRelay(500, 500, 500, 200)
+
+	s := httptest.NewServer(r)
+	defer s.Close()
+
+	backoff := 20 * time.Millisecond
+
+	cl := testClient(backoff)
+	err := cl.RequestCrawl(ctx, s.URL, "pds.example.com")
+
+	if err != nil {
+		t.Fatalf("Unexpected error, %v", err)
+	}
+
+	if len(r.times) != 4 {
+		t.Fatalf("Expected 4 attempts, got %d", len(r.times))
+	}
+
+	// The wait between attempts doubles each time
+
+	expected := backoff
+
+	for i := 1; i < len(r.times); i++ {
+
+		wait := r.times[i].Sub(r.times[i-1])
+
+		if wait < expected {
+			t.Fatalf("Expected a wait of at least %v before attempt %d, got %v", expected, i+1, wait)
+		}
+
+		expected = expected * 2
+	}
+}
+
+func TestBackoffCancelled(t *testing.T) {
+
+	r := newTestRelay(500)
+
+	s := httptest.NewServer(r)
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	cl := testClient(10 * time.Second)
+	err := cl.RequestCrawl(ctx, s.URL, "pds.example.com")
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context deadline error, got %v", err)
+	}
+
+	if r.attempts() != 1 {
+		t.Fatalf("Expected 1 attempt, got %d", r.attempts())
+	}
+}
+
+func TestNotifyOfUpdateAll(t *testing.T) {
+
+	ctx := context.Background()
+
+	ok := newTestRelay(200)
+
+	ok_s := httptest.NewServer(ok)
+	defer ok_s.Close()
+
+	bad := newTestRelay(400)
+
+	bad_s := httptest.NewServer(bad)
+	defer bad_s.Close()
+
+	ok_2 := newTestRelay(200)
+
+	ok_s2 := httptest.NewServer(ok_2)
+	defer ok_s2.Close()
+
+	cl := testClient(time.Millisecond)
+	err := cl.NotifyOfUpdateAll(ctx, []string{ok_s.URL, bad_s.URL, ok_s2.URL}, "pds.example.com")
+
+	if err == nil {
+		t.Fatalf("Expected an error")
+	}
+
+	if !strings.Contains(err.Error(), bad_s.URL) {
+		t.Fatalf("Expected error to reference %s, %v", bad_s.URL, err)
+	}
+
+	// A failure to notify one relay does not prevent the others from being notified
+
+	for _, r := range []*testRelay{ok, bad, ok_2} {
+
+		if r.attempts() != 1 {
+			t.Fatalf("Expected 1 attempt, got %d", r.attempts())
+		}
+
+		if r.paths[0] != NOTIFY_OF_UPDATE_PATH {
+			t.Fatalf("Unexpected path, %s", r.paths[0])
+		}
+	}
+}
+
+func TestRelayURL(t *testing.T) {
+
+	tests := []struct {
+		relay_uri string
+		expected  string
+		ok        bool
+	}{
+		{"bsky.network", "https://bsky.network" + REQUEST_CRAWL_PATH, true},
+		{"https://relay.example.com", "https://relay.example.com" + REQUEST_CRAWL_PATH, true},
+		{"http://localhost:8080/some/path?q=1", "http://localhost:8080" + REQUEST_CRAWL_PATH, true},
+		{"https://", "", false},
+		{"http://%zz", "", false},
+	}
+
+	for _, tt := range tests {
+
+		u, err := relayURL(tt.relay_uri, REQUEST_CRAWL_PATH)
+
+		if !tt.ok {
+
+			if err == nil {
+				t.Fatalf("Expected an error for %s", tt.relay_uri)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("Unexpected error for %s, %v", tt.relay_uri, err)
+		}
+
+		if u != tt.expected {
+			t.Fatalf("Expected %s for %s, got %s", tt.expected, tt.relay_uri, u)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+DROP TABLE IF exists blobs;
+
+CREATE TABLE blobs (
+       did TEXT,
+       cid TEXT,
+       media_type TEXT,
+       size INTEGER,
+       width INTEGER,
+       height INTEGER,
+       created INTEGER,
+       lastmodified INTEGER,
+       PRIMARY KEY (did, cid)
+);
+
+CREATE INDEX `blobs_by_created` ON blobs (`created`);
+
+DROP TABLE IF exists blob_refs;
+
+CREATE TABLE blob_refs (
+       did TEXT,
+       path TEXT,
+       cid TEXT,
+       rev TEXT,
+       PRIMARY KEY (did, path, cid)
+);
+
+CREATE INDEX `blob_refs_by_cid` ON blob_refs (`did`, `cid`);
+CREATE INDEX `blob_refs_by_rev` ON blob_refs (`did`, `rev`);
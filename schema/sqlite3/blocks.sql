@@ -0,0 +1,13 @@
+DROP TABLE IF exists blocks;
+
+CREATE TABLE blocks (
+       did TEXT,
+       cid TEXT,
+       rev TEXT,
+       data BLOB,
+       created INTEGER,
+       PRIMARY KEY (did, cid)
+);
+
+CREATE INDEX `blocks_by_rev` ON blocks (`did`, `rev`);
+CREATE INDEX `blocks_by_created` ON blocks (`created`);
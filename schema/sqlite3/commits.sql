@@ -0,0 +1,16 @@
+DROP TABLE IF exists commits;
+
+CREATE TABLE commits (
+       cid TEXT PRIMARY KEY,
+       did TEXT,
+       version INTEGER,
+       data TEXT,
+       rev TEXT,
+       prev TEXT,
+       sig BLOB,
+       created INTEGER,
+       lastmodified INTEGER
+);
+
+CREATE UNIQUE INDEX `commits_by_did` ON commits (`did`, `rev`);
+CREATE INDEX `commits_by_created` ON commits (`created`);
@@ -0,0 +1,12 @@
+DROP TABLE IF exists events;
+
+CREATE TABLE events (
+       seq INTEGER PRIMARY KEY AUTOINCREMENT,
+       type TEXT,
+       did TEXT,
+       body BLOB,
+       created INTEGER
+);
+
+CREATE INDEX `events_by_did` ON events (`did`);
+CREATE INDEX `events_by_created` ON events (`created`);
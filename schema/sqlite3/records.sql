@@ -0,0 +1,14 @@
+DROP TABLE IF exists records;
+
+CREATE TABLE records (
+       cid TEXT,
+       did TEXT,
+       collection TEXT,
+       rkey TEXT,
+       value TEXT,
+       created INTEGER,
+       lastmodified INTEGER
+);
+
+CREATE UNIQUE INDEX `records_by_rkey` ON records (`did`, `collection`, `rkey`);
+CREATE INDEX `records_by_created` ON records (`did`, `collection`, `created`);